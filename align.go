@@ -0,0 +1,179 @@
+package sqlfmt
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// alignedOperators are the operators aligned by alignOperators.
+var alignedOperators = wordSet(`= <> != < > <= >= ==`)
+
+// alignedClauses are the clauses whose predicates or assignments alignOperators aligns.
+var alignedClauses = wordSet(`WHERE HAVING ON SET`)
+
+// logicalOperators may precede the left operand of an aligned predicate.
+var logicalOperators = wordSet(`AND OR XOR NOT`)
+
+// startsOperand reports whether a line starting with t begins with a predicate or assignment
+// rather than with a clause, such as JOIN u ON a = b.
+func startsOperand(t token) bool {
+	return t.kind != tokenWord || !isKeyword(t.text) || logicalOperators[strings.ToUpper(t.text)]
+}
+
+// alignLine is a line of formatted SQL considered by alignOperators.
+type alignLine struct {
+	indent string
+	left   int // width in runes from the end of the indentation to the end of the left operand
+	gap    span
+}
+
+// alignOperators pads the left operands of consecutive predicates in WHERE, HAVING and ON clauses
+// and of consecutive assignments in SET clauses so that their operators line up:
+//
+//	WHERE
+//	    id          = 1
+//	    AND name   <> 'x'
+//
+// A line takes part if it is in one of those clauses, starts with an operand (possibly after AND or
+// OR) and has a comparison operator outside parentheses; consecutive such lines with the same
+// indentation are aligned together.
+func alignOperators(sql string, lang LanguageOption) string {
+	tokens := tokenize(sql, lang)
+	sig := significant(tokens)
+
+	var (
+		spans []span
+		run   []alignLine
+	)
+	flush := func() {
+		if len(run) > 1 {
+			width := 0
+			for _, l := range run {
+				width = max(width, l.left)
+			}
+			for _, l := range run {
+				spans = append(spans, span{l.gap.start, l.gap.end, strings.Repeat(" ", width-l.left+1)})
+			}
+		}
+		run = run[:0]
+	}
+
+	lineStart, first := 0, -1 // offset of the current line and index in sig of its first token
+	depth := 0                // parenthesis depth relative to the start of the line
+	found := false            // whether the current line has an operator to align
+	var line alignLine
+	endLine := func(end int) {
+		if found {
+			text := sql[lineStart:end]
+			indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+			if len(run) > 0 && run[0].indent != indent {
+				flush()
+			}
+			line.indent = indent
+			run = append(run, line)
+		} else {
+			flush()
+		}
+		lineStart, first, depth, found = end+1, -1, 0, false
+	}
+
+	i := 0 // index in sig
+	for _, t := range tokens {
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			off, rest := t.start, t.text
+			for n := strings.IndexByte(rest, '\n'); n >= 0; n = strings.IndexByte(rest, '\n') {
+				endLine(off + n)
+				off, rest = off+n+1, rest[n+1:]
+			}
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case !found && depth == 0 && i > first && t.kind == tokenOperator && alignedOperators[t.text] &&
+			alignedClauses[enclosingClause(sig, i)] && startsOperand(sig[first]):
+			prev := sig[i-1]
+			found = true
+			line.left = utf8.RuneCountInString(strings.TrimLeft(sql[lineStart:prev.end], " \t"))
+			line.gap = span{start: prev.end, end: t.start}
+		}
+		i++
+	}
+	endLine(len(sql))
+	flush()
+	return replaceSpans(sql, spans)
+}
+
+// alignAliases pads the expressions of each SELECT list so that their AS keywords and their
+// aliases line up in columns:
+//
+//	SELECT
+//	    id              AS user_id,
+//	    COUNT(*)        AS n,
+//	    MAX(created_at) AS last_seen
+//
+// Only aliased items written on a single line take part; the width is computed separately for
+// every SELECT list.
+func alignAliases(sql string, lang LanguageOption) string {
+	sig := significant(tokenize(sql, lang))
+	depths := nestingDepths(sig)
+
+	type aliased struct {
+		column int  // column in runes just past the expression
+		gap    span // whitespace between the expression and AS or the alias
+		as     bool // whether the alias follows AS
+	}
+	var spans []span
+	for i, t := range sig {
+		if t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		_, end := selectListEnd(sig, depths, i)
+		var items []aliased
+		for _, item := range selectItems(sig, depths, i, end) {
+			_, _, ok := outputName(sig, item)
+			if !ok || item[1]-item[0] < 2 {
+				continue
+			}
+			last := item[1] - 2 // last token of the expression
+			as := strings.EqualFold(sig[last].text, "AS")
+			if as {
+				last--
+			}
+			if last < item[0] || strings.Contains(sql[sig[item[0]].start:sig[item[1]-1].end], "\n") {
+				continue
+			}
+			lineStart := strings.LastIndexByte(sql[:sig[last].end], '\n') + 1
+			items = append(items, aliased{
+				column: utf8.RuneCountInString(sql[lineStart:sig[last].end]),
+				gap:    span{start: sig[last].end, end: sig[last+1].start},
+				as:     as,
+			})
+		}
+		if len(items) < 2 {
+			continue
+		}
+		width, anyAS := 0, false
+		for _, it := range items {
+			width = max(width, it.column)
+			anyAS = anyAS || it.as
+		}
+		for _, it := range items {
+			pad := width - it.column + 1
+			if anyAS && !it.as {
+				// Put the alias in the column of the aliases following AS.
+				pad += len("AS ")
+			}
+			spans = append(spans, span{it.gap.start, it.gap.end, strings.Repeat(" ", pad)})
+		}
+	}
+	// Spans of nested SELECT lists come after those of the enclosing list.
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return replaceSpans(sql, spans)
+}
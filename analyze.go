@@ -0,0 +1,141 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryInfo describes a statement, as found by Analyze.
+type QueryInfo struct {
+	// Kind is the keyword that determines the kind of statement, as in StatementResult.
+	Kind string
+	// Tables lists the tables (and views) the statement references, in order of first
+	// appearance, without duplicates. Names of common table expressions are not tables.
+	Tables []TableRef
+	// CTEs lists the names of the common table expressions the statement defines.
+	CTEs []string
+	// Joins is the number of joins, subqueries included.
+	Joins int
+	// Subqueries is the number of parenthesized queries, common table expressions included.
+	Subqueries int
+	// HasWhere, HasGroupBy, HasOrderBy and HasLimit report whether the statement has the
+	// corresponding clause at its top level, that is, outside subqueries and common table
+	// expressions. HasLimit covers LIMIT, FETCH FIRST and TOP.
+	HasWhere, HasGroupBy, HasOrderBy, HasLimit bool
+}
+
+// TableRef is a table referenced by a statement.
+type TableRef struct {
+	// Schema is the qualifier of the table, if any, such as "public" or "catalog.schema".
+	Schema string
+	// Name is the name of the table, unquoted.
+	Name string
+}
+
+// String returns the table name, qualified if Schema is set.
+func (t TableRef) String() string {
+	if t.Schema == "" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// Analyze returns the metadata of the single statement sql, found with the tokenizer the
+// formatter uses rather than a full parse, so it also works on queries sql-formatter rejects.
+// It fails with ErrEmptySQL if sql has no statement and with ErrMultipleQueries if it has several.
+func Analyze(sql string, lang LanguageOption) (QueryInfo, error) {
+	stmts := splitStatements(sql, lang)
+	switch {
+	case len(stmts) == 0:
+		return QueryInfo{}, ErrEmptySQL
+	case len(stmts) > 1:
+		return QueryInfo{}, fmt.Errorf("%w: found %d", ErrMultipleQueries, len(stmts))
+	}
+	sig := significant(tokenize(sql[stmts[0].start:stmts[0].end], lang))
+	info := QueryInfo{Kind: stmts[0].kind}
+
+	depth := 0
+	for i, t := range sig {
+		switch {
+		case t.text == "(":
+			depth++
+			if next := strings.ToUpper(tokenAt(sig, i+1).text); next == "SELECT" || next == "WITH" {
+				info.Subqueries++
+			}
+		case t.text == ")":
+			depth = max(depth-1, 0)
+		case t.kind != tokenWord:
+		case strings.EqualFold(t.text, "JOIN") || strings.EqualFold(t.text, "STRAIGHT_JOIN"):
+			info.Joins++
+		case depth == 0:
+			switch strings.ToUpper(t.text) {
+			case "WHERE":
+				info.HasWhere = true
+			case "GROUP":
+				info.HasGroupBy = info.HasGroupBy || strings.EqualFold(tokenAt(sig, i+1).text, "BY")
+			case "ORDER":
+				info.HasOrderBy = info.HasOrderBy || strings.EqualFold(tokenAt(sig, i+1).text, "BY")
+			case "LIMIT", "FETCH", "TOP":
+				info.HasLimit = true
+			}
+		}
+	}
+
+	refs := scanNames(sig)
+	for _, ref := range refs {
+		if ref.role == roleCTE {
+			info.CTEs = append(info.CTEs, ref.name)
+		}
+	}
+	seen := make(map[TableRef]bool)
+	for _, occ := range tableOccurrences(sig, refs) {
+		if !seen[occ.table] {
+			seen[occ.table] = true
+			info.Tables = append(info.Tables, occ.table)
+		}
+	}
+	return info, nil
+}
+
+// tableOccurrence is a table reference among the significant tokens of a statement.
+type tableOccurrence struct {
+	table      TableRef
+	alias      string
+	start, end int // indexes of the first and last tokens of the dotted name
+}
+
+// tableOccurrences returns the table references among refs, the names found by scanNames in sig,
+// leaving out column qualifiers and references to common table expressions.
+func tableOccurrences(sig []token, refs []nameRef) []tableOccurrence {
+	cte := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.role == roleCTE {
+			cte[strings.ToUpper(ref.name)] = true
+		}
+	}
+	var occs []tableOccurrence
+	for k, ref := range refs {
+		if ref.role != roleTable {
+			continue
+		}
+		// Walk back over the qualifiers to the start of the dotted name.
+		start := ref.index
+		for start >= 2 && sig[start-1].text == "." {
+			start -= 2
+		}
+		if !isTablePosition(sig, start) || !ref.qualified && cte[strings.ToUpper(ref.name)] {
+			continue // a column qualifier, or a reference to a common table expression
+		}
+		occ := tableOccurrence{table: TableRef{Name: ref.name}, start: start, end: ref.index}
+		var schema []string
+		for j := start; j < ref.index; j += 2 {
+			schema = append(schema, unquoteName(sig[j]))
+		}
+		occ.table.Schema = strings.Join(schema, ".")
+		if k+1 < len(refs) && refs[k+1].role == roleTableAlias {
+			occ.alias = refs[k+1].name
+		}
+		occs = append(occs, occ)
+	}
+	return occs
+}
@@ -0,0 +1,101 @@
+package sqlfmt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// anonymizedLiterals are neutral replacements for typed literals, which must stay valid for their type.
+var anonymizedLiterals = map[string]string{
+	"DATE":        "'1970-01-01'",
+	"TIME":        "'00:00:00'",
+	"TIMESTAMP":   "'1970-01-01 00:00:00'",
+	"TIMESTAMPTZ": "'1970-01-01 00:00:00+00'",
+	"DATETIME":    "'1970-01-01 00:00:00'",
+	"INTERVAL":    "'1 day'",
+}
+
+// Anonymize rewrites sql so it can be shared without exposing schema or data, while preserving
+// its structure. Schemas, tables (including aliases and CTEs) and columns (including aliases) are
+// consistently renamed to s1, t1, c1, ... so that every occurrence of a name maps to the same
+// placeholder. String literals become 'x', numbers other than ORDER BY and GROUP BY ordinals
+// become 0, and comments, which may contain anything, are removed, leaving a space where the
+// tokens around them would otherwise run together. Keywords, function names, operators and
+// placeholders are kept.
+//
+// The returned map translates each placeholder back to the original name, so that answers about
+// the anonymized query can be related to the real schema. Names are matched case-insensitively
+// unless they are quoted.
+func Anonymize(sql string, lang LanguageOption) (string, map[string]string) {
+	tokens := tokenize(sql, lang)
+	sig := significant(tokens)
+
+	names := make(map[string]string)   // role prefix + name key -> placeholder
+	reverse := make(map[string]string) // placeholder -> original name
+	counters := make(map[string]int)   // role prefix -> last number used
+	replace := make(map[int]string)    // token start offset -> replacement
+	placeholder := func(prefix string, t token, name string) string {
+		key := name
+		if t.kind != tokenIdent {
+			key = strings.ToLower(name)
+		}
+		key = prefix + "\x00" + key
+		if p, ok := names[key]; ok {
+			return p
+		}
+		counters[prefix]++
+		p := prefix + strconv.Itoa(counters[prefix])
+		names[key] = p
+		reverse[p] = name
+		return p
+	}
+
+	for _, ref := range scanNames(sig) {
+		t := sig[ref.index]
+		switch ref.role {
+		case roleSchema:
+			replace[t.start] = placeholder("s", t, ref.name)
+		case roleTable, roleTableAlias, roleCTE:
+			replace[t.start] = placeholder("t", t, ref.name)
+		case roleColumn, roleColumnAlias:
+			replace[t.start] = placeholder("c", t, ref.name)
+		}
+	}
+	for i, t := range sig {
+		switch t.kind {
+		case tokenString:
+			prev := tokenAt(sig, i-1)
+			if lit, ok := anonymizedLiterals[strings.ToUpper(prev.text)]; ok && prev.kind == tokenWord {
+				replace[t.start] = lit
+			} else {
+				replace[t.start] = "'x'"
+			}
+		case tokenNumber:
+			if !isOrdinal(sig, i, tokenAt(sig, i-1), tokenAt(sig, i+1)) {
+				replace[t.start] = "0"
+			}
+		}
+	}
+
+	var (
+		b       strings.Builder
+		dropped bool // a comment was removed since the last token written
+		spaced  bool // the last token written is whitespace
+	)
+	for _, t := range tokens {
+		if t.kind == tokenComment {
+			dropped = true
+			continue
+		}
+		if dropped && !spaced && t.kind != tokenSpace && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		dropped, spaced = false, t.kind == tokenSpace
+		if r, ok := replace[t.start]; ok && t.kind != tokenSpace {
+			b.WriteString(r)
+			continue
+		}
+		b.WriteString(t.text)
+	}
+	return b.String(), reverse
+}
@@ -0,0 +1,43 @@
+package sqlfmt
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		sql, want string
+		names     map[string]string
+	}{
+		{
+			sql:   "select u.name from users u where u.id = 42 and u.name = 'bob' order by 1",
+			want:  "select t1.c1 from t2 t1 where t1.c2 = 0 and t1.c1 = 'x' order by 1",
+			names: map[string]string{"t1": "u", "t2": "users", "c1": "name", "c2": "id"},
+		},
+		{
+			sql:   "select date '2024-01-02', Col from db.t",
+			want:  "select date '1970-01-01', c1 from s1.t1",
+			names: map[string]string{"s1": "db", "t1": "t", "c1": "Col"},
+		},
+		{
+			sql:   "SELECT/**/1, a/*x*/b FROM users",
+			want:  "SELECT 0, c1 c2 FROM t1",
+			names: map[string]string{"t1": "users", "c1": "a", "c2": "b"},
+		},
+		{
+			sql:   "/* lead */select 1/* a *//* b */+2 -- trail\n",
+			want:  "select 0 +0 \n",
+			names: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		got, names := Anonymize(tt.sql, LanguageSQL)
+		if got != tt.want {
+			t.Errorf("Anonymize(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+		if !maps.Equal(names, tt.names) {
+			t.Errorf("Anonymize(%q) names = %v, want %v", tt.sql, names, tt.names)
+		}
+	}
+}
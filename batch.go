@@ -0,0 +1,113 @@
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ItemError is the error for one item of a batch operation such as FormatMany.
+type ItemError struct {
+	// Index is the position of the item in the batch.
+	Index int
+	// Path is the file the item was read from, or "" if it did not come from a file.
+	Path string
+	// Err is the error for the item.
+	Err error
+}
+
+func (e *ItemError) Error() string {
+	if e.Path != "" {
+		return e.Path + ": " + e.Err.Error()
+	}
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the errors of a batch operation, ordered by item index. It works with
+// errors.Is and errors.As like the result of errors.Join.
+type MultiError struct {
+	Errors []*ItemError
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// FormatMany formats each query of sqls, spreading them over the formatter's JavaScript contexts.
+// It formats every query even if some fail: the result has one entry per query, empty for failed
+// ones, and the error is a *MultiError describing all failures.
+func (f *Formatter) FormatMany(sqls []string, options FormatOptions) ([]string, error) {
+	return f.formatBatch(len(sqls), func(i int) (string, string, error) {
+		return sqls[i], "", nil
+	}, options)
+}
+
+// FormatFiles reads and formats each file of paths like FormatMany. It does not modify the files.
+// Errors reading a file are reported in the *MultiError together with formatting errors.
+func (f *Formatter) FormatFiles(paths []string, options FormatOptions) ([]string, error) {
+	return f.formatBatch(len(paths), func(i int) (string, string, error) {
+		data, err := os.ReadFile(paths[i])
+		return string(data), paths[i], err
+	}, options)
+}
+
+// formatBatch formats n items returned by item, using as many goroutines as the pool has contexts.
+func (f *Formatter) formatBatch(n int, item func(i int) (sql, path string, err error), options FormatOptions) ([]string, error) {
+	if err := f.check("FormatMany"); err != nil && !errors.Is(err, ErrFormatterClosed) {
+		return nil, err // a closed formatter fails each item instead
+	}
+	results := make([]string, n)
+	errs := make([]*ItemError, n)
+
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for range min(f.pool.size, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				sql, path, err := item(i)
+				if err == nil {
+					results[i], err = f.Format(sql, options)
+				}
+				if err != nil {
+					errs[i] = &ItemError{Index: i, Path: path, Err: err}
+				}
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var multi MultiError
+	for _, err := range errs {
+		if err != nil {
+			multi.Errors = append(multi.Errors, err)
+		}
+	}
+	if len(multi.Errors) > 0 {
+		return results, &multi
+	}
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package sqlfmt
+
+import "strings"
+
+// keepBlankLines restores the blank lines that separate tokens of the input inside statements,
+// up to max consecutive ones, in the output of sql-formatter, which removes them all. Blank lines
+// are only restored where the output already breaks the line, and those between statements are
+// left to LinesBetweenQueries. Runs of blank lines inside block comments are capped to max too.
+//
+// Tokens are matched by position, so the output is returned unchanged if sql-formatter did not
+// produce the same number of tokens as the input.
+func keepBlankLines(input, output string, lang LanguageOption, max int) string {
+	in := nonSpaceTokens(input, lang)
+	out := nonSpaceTokens(output, lang)
+	if len(in) != len(out) {
+		return output
+	}
+
+	var spans []span
+	for i, t := range out {
+		if i > 0 && in[i-1].text != ";" {
+			blank := min(strings.Count(input[in[i-1].end:in[i].start], "\n")-1, max)
+			gap := output[out[i-1].end:t.start]
+			if nl := strings.LastIndexByte(gap, '\n'); blank > 0 && nl >= 0 {
+				spans = append(spans, span{out[i-1].end, t.start, strings.Repeat("\n", blank+1) + gap[nl+1:]})
+			}
+		}
+		if t.kind == tokenComment && strings.HasPrefix(t.text, "/*") {
+			if capped := capBlankLines(t.text, max); capped != t.text {
+				spans = append(spans, span{t.start, t.end, capped})
+			}
+		}
+	}
+	return replaceSpans(output, spans)
+}
+
+// nonSpaceTokens returns the tokens of sql other than whitespace.
+func nonSpaceTokens(sql string, lang LanguageOption) []token {
+	var tokens []token
+	for _, t := range tokenize(sql, lang) {
+		if t.kind != tokenSpace {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// capBlankLines limits the runs of blank lines in text to max and empties the blank lines kept.
+func capBlankLines(text string, max int) string {
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	blank := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > max {
+				continue
+			}
+			line = ""
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,36 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// loadBundle reads the sql-formatter bundle from a file or an http(s) URL.
+func loadBundle(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading sql-formatter bundle: %w", err)
+		}
+		return data, nil
+	}
+
+	resp, err := http.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("downloading sql-formatter bundle: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading sql-formatter bundle: %s: %s", path, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading sql-formatter bundle: %w", err)
+	}
+	return data, nil
+}
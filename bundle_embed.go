@@ -0,0 +1,12 @@
+//go:build !sqlfmt_noembed && !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import _ "embed"
+
+//go:embed assets/sql-formatter.min.js
+var jsCode []byte
+
+// BundleVersion is the version of the sql-formatter bundle embedded in the package, or "" if
+// there is none. It is updated together with SQL_FORMATTER_VERSION in the Makefile.
+const BundleVersion = "15.6.6"
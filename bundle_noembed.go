@@ -0,0 +1,10 @@
+//go:build sqlfmt_noembed || tinygo || sqlfmt_native
+
+package sqlfmt
+
+// jsCode is empty when built with the sqlfmt_noembed tag; the bundle must be given with WithBundlePath.
+// The native backend (tinygo or sqlfmt_native) does not use it.
+var jsCode []byte
+
+// BundleVersion is "": no sql-formatter bundle is embedded.
+const BundleVersion = ""
@@ -0,0 +1,48 @@
+//go:build !js && !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/0x6b/sqlfmt/internal/quickjs"
+)
+
+// compiledBundle holds the bytecode of the bundle last evaluated by evalBundle. Parsing the bundle
+// takes about a third of the time evaluating it does; the rest is its top-level code, which runs
+// in every context since QuickJS cannot snapshot an initialized heap.
+var compiledBundle struct {
+	mu   sync.Mutex
+	sum  [sha256.Size]byte // of the bundle
+	code []byte
+}
+
+// evalBundle evaluates the sql-formatter bundle in ctx from bytecode, compiling it first unless
+// it was compiled for an earlier context. A bundle that is an ES module is evaluated from source.
+// It must be called from the thread that created ctx.
+func evalBundle(ctx *quickjs.JsContext, bundle []byte) error {
+	if quickjs.IsModule(string(bundle)) {
+		_, err := ctx.Eval(string(bundle), nil)
+		return err
+	}
+
+	sum := sha256.Sum256(bundle)
+	compiledBundle.mu.Lock()
+	code := compiledBundle.code
+	if compiledBundle.sum != sum {
+		code = nil
+	}
+	compiledBundle.mu.Unlock()
+
+	if code == nil {
+		var err error
+		if code, err = ctx.Compile(string(bundle), "sql-formatter.min.js"); err != nil {
+			return err
+		}
+		compiledBundle.mu.Lock()
+		compiledBundle.sum, compiledBundle.code = sum, code
+		compiledBundle.mu.Unlock()
+	}
+	return ctx.EvalBytecode(code)
+}
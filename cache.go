@@ -0,0 +1,121 @@
+package sqlfmt
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// resultCache keeps the results of the latest Format calls of a formatter and its clones, up to
+// size, evicting the least recently used. Results are keyed on a hash of the input, the options,
+// the backend and the sql-formatter bundle, so that a change of any of them misses the cache.
+type resultCache struct {
+	mu         sync.Mutex
+	size       int
+	entries    map[[sha256.Size]byte]*list.Element
+	order      list.List // of *cacheEntry, most recently used first
+	generation uint64    // incremented by invalidate, so that formats started before it are not stored
+	bundle     [sha256.Size]byte
+}
+
+// cacheEntry is a result kept by resultCache.
+type cacheEntry struct {
+	key       [sha256.Size]byte
+	formatted string
+	warnings  []Warning
+}
+
+// newResultCache returns a cache of size results formatted with bundle, or nil if size is below 1.
+func newResultCache(size int, bundle []byte) *resultCache {
+	if size < 1 {
+		return nil
+	}
+	return &resultCache{size: size, entries: make(map[[sha256.Size]byte]*list.Element), bundle: sha256.Sum256(bundle)}
+}
+
+// key returns the key of the result of formatting sql with options. It reports false if the
+// result cannot be cached because options reference a Catalog or ColumnCatalog, whose content is
+// not part of the key.
+func (c *resultCache) key(sql string, options FormatOptions) ([sha256.Size]byte, bool) {
+	if options.Catalog != nil || options.ColumnCatalog != nil {
+		return [sha256.Size]byte{}, false
+	}
+	h := sha256.New()
+	h.Write(c.bundle[:])
+	h.Write([]byte(Backend))
+	h.Write([]byte{0})
+	h.Write(appendOptionsKey(nil, options))
+	h.Write([]byte{0})
+	h.Write([]byte(sql))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+// appendOptionsKey appends to b an encoding of the options that set the output, which differs
+// whenever one of them does. Strings are prefixed with their length, so that they can hold any byte.
+func appendOptionsKey(b []byte, o FormatOptions) []byte {
+	for _, s := range []string{
+		string(o.DataTypeCase), string(o.FunctionCase), string(o.IdentifierCase), string(o.IndentStyle),
+		string(o.KeywordCase), string(o.Language), string(o.LogicalOperatorNewline), o.DenseOperatorExceptions,
+		string(o.DumpFormat), string(o.DumpInserts), string(o.NumberCase), string(o.LeadingZero),
+		o.GeneratedMarker, string(o.ExecutableComments),
+	} {
+		b = strconv.AppendInt(b, int64(len(s)), 10)
+		b = append(append(b, ':'), s...)
+	}
+	for _, n := range []int{o.ExpressionWidth, o.LinesBetweenQueries, o.TabWidth, o.MaxConsecutiveBlankLines, o.PredicateChainWidth} {
+		b = append(strconv.AppendInt(b, int64(n), 10), ',')
+	}
+	for _, v := range []bool{
+		o.DenseOperators, o.NewlineBeforeSemicolon, o.UseTabs, o.QualifyTables, o.ExpandStar, o.VerifyTokens,
+		o.VerifyIdempotent, o.PreserveLineBreaks, o.AlignOperators, o.AlignAliases, o.OrderByDependencies,
+		o.SortSchema, o.GroupDigits, o.SQLXBinds, o.MapLines,
+	} {
+		b = strconv.AppendBool(b, v)
+	}
+	return b
+}
+
+// get returns the result stored under key, and the generation to pass to put if there is none.
+func (c *resultCache) get(key [sha256.Size]byte) (entry *cacheEntry, generation uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cacheEntry), c.generation, true
+	}
+	return nil, c.generation, false
+}
+
+// put stores a result under key, unless the cache was invalidated since the get that returned
+// generation.
+func (c *resultCache) put(key [sha256.Size]byte, generation uint64, formatted string, warnings []Warning) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	// Clip the warnings so that callers appending to them do not write to the stored array.
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, formatted, slices.Clip(warnings)})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate drops all results, including those of formats in progress.
+func (c *resultCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	clear(c.entries)
+	c.order.Init()
+}
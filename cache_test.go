@@ -0,0 +1,94 @@
+package sqlfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResultCacheKeyCoversOptions(t *testing.T) {
+	c := newResultCache(1, nil)
+	base, _ := c.key("select 1", FormatOptions{})
+	typ := reflect.TypeFor[FormatOptions]()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		var options FormatOptions
+		v := reflect.ValueOf(&options).Elem().Field(i)
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString("x")
+		case reflect.Int:
+			v.SetInt(1)
+		case reflect.Bool:
+			v.SetBool(true)
+		case reflect.Interface:
+			continue // catalogs, which make results uncacheable
+		default:
+			t.Fatalf("%s: no test value for a %s", field.Name, v.Kind())
+		}
+		if key, _ := c.key("select 1", options); key == base {
+			t.Errorf("%s is not part of the cache key", field.Name)
+		}
+	}
+	if _, ok := c.key("select 1", FormatOptions{Catalog: SchemaMap{}}); ok {
+		t.Error("a result formatted with a catalog can be cached")
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	f, err := NewFormatter(WithResultCache(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	hit := func(sql string, options FormatOptions) bool {
+		t.Helper()
+		res, err := f.FormatWithResult(sql, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.CacheHit
+	}
+
+	if hit("select 1", DefaultFormatOptions) {
+		t.Error("first call hit the cache")
+	}
+	if !hit("select 1", DefaultFormatOptions) {
+		t.Error("second call missed the cache")
+	}
+	if hit("select 1", DefaultFormatOptions.WithLanguage(LanguageMySQL)) {
+		t.Error("a call with other options hit the cache")
+	}
+
+	clone, err := f.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clone.Close()
+	if res, err := clone.FormatWithResult("select 1", DefaultFormatOptions); err != nil || !res.CacheHit {
+		t.Errorf("a clone does not share the cache (%v)", err)
+	}
+
+	hit("select 2", DefaultFormatOptions) // evicts the MySQL result, used least recently
+	if !hit("select 1", DefaultFormatOptions) {
+		t.Error("a recently used result was evicted")
+	}
+	if hit("select 1", DefaultFormatOptions.WithLanguage(LanguageMySQL)) {
+		t.Error("the least recently used result was not evicted")
+	}
+
+	clone.InvalidateCache()
+	if hit("select 1", DefaultFormatOptions) {
+		t.Error("InvalidateCache did not drop the results")
+	}
+}
+
+func TestResultCacheInvalidateDuringFormat(t *testing.T) {
+	c := newResultCache(4, nil)
+	key, _ := c.key("select 1", FormatOptions{})
+	_, generation, _ := c.get(key)
+	c.invalidate() // while "select 1" is being formatted
+	c.put(key, generation, "SELECT 1", nil)
+	if _, _, ok := c.get(key); ok {
+		t.Error("a result formatted before InvalidateCache was stored")
+	}
+}
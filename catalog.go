@@ -0,0 +1,103 @@
+package sqlfmt
+
+import "strings"
+
+// Catalog resolves table names to the schema that owns them.
+// It is consulted by schema-aware transforms such as FormatOptions.QualifyTables.
+type Catalog interface {
+	// SchemaOf returns the schema of the named table and whether the table is known.
+	SchemaOf(table string) (schema string, ok bool)
+}
+
+// SchemaMap is a Catalog backed by a map from table name to schema name.
+// Lookups try the exact name first and then fall back to a case-insensitive match, preferring the
+// smallest key when several match.
+type SchemaMap map[string]string
+
+// SchemaOf implements Catalog.
+func (m SchemaMap) SchemaOf(table string) (string, bool) {
+	if schema, ok := m[table]; ok {
+		return schema, true
+	}
+	if name, ok := foldedKey(m, table); ok {
+		return m[name], true
+	}
+	return "", false
+}
+
+// CatalogFunc adapts an ordinary function to the Catalog interface.
+type CatalogFunc func(table string) (schema string, ok bool)
+
+// SchemaOf implements Catalog.
+func (f CatalogFunc) SchemaOf(table string) (string, bool) {
+	return f(table)
+}
+
+// ColumnCatalog resolves table names to their columns.
+// It is consulted by FormatOptions.ExpandStar.
+type ColumnCatalog interface {
+	// ColumnsOf returns the columns of the named table in definition order and whether the table is known.
+	// The name may be schema-qualified (schema.table) when the query qualifies it.
+	ColumnsOf(table string) (columns []string, ok bool)
+}
+
+// ColumnMap is a ColumnCatalog backed by a map from table name to its columns in definition order.
+// Keys may be plain or schema-qualified table names. Lookups try the exact name first and then
+// fall back to a case-insensitive match, preferring the smallest key when several match.
+type ColumnMap map[string][]string
+
+// ColumnsOf implements ColumnCatalog.
+func (m ColumnMap) ColumnsOf(table string) ([]string, bool) {
+	if columns, ok := m[table]; ok {
+		return columns, true
+	}
+	if name, ok := foldedKey(m, table); ok {
+		return m[name], true
+	}
+	return nil, false
+}
+
+// quoteName renders name as an identifier in the given dialect, quoting it only when necessary.
+func quoteName(name string, lang LanguageOption) string {
+	plain := name != "" && !isKeyword(name)
+	for i, r := range name {
+		if !(r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (i > 0 && '0' <= r && r <= '9')) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return quoteIdent(name, lang)
+}
+
+// quoteIdent quotes name as an identifier using the preferred quoting of the given dialect.
+func quoteIdent(name string, lang LanguageOption) string {
+	lx := lexiconFor(lang)
+	switch {
+	case lx.backtickIdents && lx.doubleQuoteStrings:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case lx.bracketIdents && isTransactSQL(lang):
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// isTransactSQL reports whether dialect is one of the names of Transact-SQL.
+func isTransactSQL(dialect LanguageOption) bool {
+	return dialect == LanguageTransactSQL || dialect == LanguageTSQL
+}
+
+// foldedKey returns the smallest key of m equal to name under Unicode case folding. Taking the
+// smallest rather than the first found keeps lookups independent of map iteration order.
+func foldedKey[V any](m map[string]V, name string) (string, bool) {
+	found, ok := "", false
+	for k := range m {
+		if strings.EqualFold(k, name) && (!ok || k < found) {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
@@ -0,0 +1,128 @@
+package sqlfmt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// chainClauses are the clauses whose conditions collapsePredicateChains may put on one line.
+var chainClauses = wordSet(`WHERE HAVING ON`)
+
+// chainEnds end a condition besides the clause keywords, as in JOIN u ON a = b LEFT JOIN v.
+var chainEnds = wordSet(`LEFT RIGHT INNER OUTER FULL CROSS NATURAL`)
+
+// collapsePredicateChains puts the AND/OR chains of the WHERE, HAVING and ON clauses of sql, and
+// of the parenthesized conditions within them, on one line if that line, with its indentation, is
+// at most width runes long, so that short conditions do not take a line per predicate:
+//
+//	WHERE
+//	    active = 1 AND deleted_at IS NULL
+//
+// The clause keyword keeps its own line, or its place in the tabular indent styles. Chains are
+// considered from the outside in, so that a chain too long for one line can still hold short
+// parenthesized chains. Chains holding line comments or multi-line block comments are left alone.
+func collapsePredicateChains(sql string, lang LanguageOption, width int) string {
+	tokens := tokenize(sql, lang)
+	var (
+		index []int   // index in tokens of each significant token
+		sig   []token // the significant tokens
+	)
+	for i, t := range tokens {
+		if t.kind != tokenSpace && t.kind != tokenComment {
+			index = append(index, i)
+			sig = append(sig, t)
+		}
+	}
+
+	var spans []span
+	settled := 0 // offset in sql up to which a collapsed chain decided the layout
+	for k, t := range sig {
+		opening := t.text == "("
+		switch {
+		case t.start < settled:
+			continue
+		case opening && chainClauses[enclosingClause(sig, k)]:
+		case t.kind == tokenWord && chainClauses[strings.ToUpper(t.text)]:
+		default:
+			continue
+		}
+		end, ok := chainEnd(sig, k+1)
+		if !ok || opening && tokenAt(sig, end).text != ")" {
+			continue
+		}
+
+		// The gaps collapsed are those between the tokens of the chain, and for a parenthesized
+		// chain, those after ( and before ).
+		from, to := index[k+1], index[end-1]+1
+		if opening {
+			from, to = index[k]+1, index[end]
+		}
+		text, ok := oneLine(tokens[from:to], opening)
+		if !ok {
+			continue
+		}
+		// The line holds the text before the chain, such as its indentation, then the chain and,
+		// for a parenthesized one, the closing parenthesis.
+		indent := sql[strings.LastIndexByte(sql[:tokens[from].start], '\n')+1 : tokens[from].start]
+		length := utf8.RuneCountInString(indent) + utf8.RuneCountInString(text)
+		if opening {
+			length++
+		}
+		if length > width {
+			continue
+		}
+		spans = append(spans, span{tokens[from].start, tokens[to-1].end, text})
+		settled = tokens[to-1].end
+	}
+	return replaceSpans(sql, spans)
+}
+
+// chainEnd returns the index in sig of the token ending the condition starting at sig[start]: a
+// clause keyword, a closing parenthesis or a semicolon at its nesting level, or the end. It
+// reports false unless the condition is a chain of predicates joined by AND, OR or XOR, without
+// subqueries, which keep their clauses on lines of their own.
+func chainEnd(sig []token, start int) (int, bool) {
+	depth := 0
+	chained, subquery := false, false
+	end := start
+	for ; end < len(sig); end++ {
+		t := sig[end]
+		upper := strings.ToUpper(t.text)
+		if depth == 0 && (t.text == ")" || t.text == ";" || t.kind == tokenWord && (clauseKeywords[upper] || chainEnds[upper])) {
+			break
+		}
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case depth == 0 && t.kind == tokenWord && (upper == "AND" || upper == "OR" || upper == "XOR"):
+			chained = true
+		case t.kind == tokenWord && upper == "SELECT":
+			subquery = true
+		}
+	}
+	return end, chained && !subquery && end > start
+}
+
+// oneLine returns tokens with each run of whitespace, which may hold line breaks or the padding of
+// the tabular indent styles, replaced by a space, or dropped inside parentheses, at the ends of a
+// parenthesized chain as around the nested groups it holds. It reports false if tokens hold a
+// comment that needs its line.
+func oneLine(tokens []token, parenthesized bool) (string, bool) {
+	var b strings.Builder
+	for i, t := range tokens {
+		switch {
+		case t.kind == tokenComment && (!strings.HasPrefix(t.text, "/*") || strings.Contains(t.text, "\n")):
+			return "", false
+		case t.kind == tokenSpace:
+			first, last := i == 0, i == len(tokens)-1
+			if !(parenthesized && (first || last) || !first && tokens[i-1].text == "(" || !last && tokens[i+1].text == ")") {
+				b.WriteByte(' ')
+			}
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String(), true
+}
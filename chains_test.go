@@ -0,0 +1,43 @@
+package sqlfmt
+
+import "testing"
+
+func TestPredicateChainWidth(t *testing.T) {
+	const sql = "select a from t where x = 1 and y = 2 and (z = 3 or w = 4)"
+	tests := []struct {
+		name    string
+		options FormatOptions
+		want    string
+	}{
+		{
+			name:    "nested group broken by sql-formatter",
+			options: DefaultFormatOptions.WithExpressionWidth(10),
+			want:    "SELECT\n    a\nFROM\n    t\nWHERE\n    x = 1 AND y = 2 AND (z = 3 OR w = 4)",
+		},
+		{
+			name:    "line with its indentation at the width",
+			options: DefaultFormatOptions.WithExpressionWidth(10).WithPredicateChainWidth(40),
+			want:    "SELECT\n    a\nFROM\n    t\nWHERE\n    x = 1 AND y = 2 AND (z = 3 OR w = 4)",
+		},
+		{
+			name:    "chain fitting the width only without its indentation",
+			options: DefaultFormatOptions.WithExpressionWidth(10).WithPredicateChainWidth(39),
+			want:    "SELECT\n    a\nFROM\n    t\nWHERE\n    x = 1 AND\n    y = 2 AND\n    (z = 3 OR w = 4)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if options.PredicateChainWidth == 0 {
+				options.PredicateChainWidth = 80
+			}
+			got, err := Format(sql, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
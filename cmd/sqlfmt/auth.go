@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// tokenEnv is the environment variable holding the bearer token accepted by serve, in addition
+// to those of -token-file.
+const tokenEnv = "SQLFMT_SERVE_TOKEN"
+
+// authenticator requires requests to carry one of a set of bearer tokens, a verified client
+// certificate, or both. Only the hashes of the tokens are kept, so that they can be compared in
+// constant time whatever their length.
+type authenticator struct {
+	hashes      [][sha256.Size]byte
+	requireCert bool
+}
+
+// loadTokens returns the tokens of the file at path, one per line, ignoring blank lines and
+// lines starting with #, and the token of tokenEnv, if set.
+func loadTokens(path string) ([]string, error) {
+	var tokens []string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading tokens: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				tokens = append(tokens, line)
+			}
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("reading tokens: no token in %s", path)
+		}
+	}
+	if token := strings.TrimSpace(os.Getenv(tokenEnv)); token != "" {
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// newAuthenticator returns an authenticator requiring one of tokens, if any, and a client
+// certificate if requireCert is set, or nil if neither is required.
+func newAuthenticator(tokens []string, requireCert bool) *authenticator {
+	if len(tokens) == 0 && !requireCert {
+		return nil
+	}
+	a := &authenticator{requireCert: requireCert}
+	for _, t := range tokens {
+		a.hashes = append(a.hashes, sha256.Sum256([]byte(t)))
+	}
+	return a
+}
+
+// wrap rejects the unauthenticated requests to next with 401 Unauthorized. A nil authenticator
+// accepts every request.
+func (a *authenticator) wrap(next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.requireCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			writeProblem(w, http.StatusUnauthorized, "client certificate required", nil)
+			return
+		}
+		if len(a.hashes) > 0 {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !a.valid(strings.TrimSpace(token)) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="sqlfmt"`)
+				writeProblem(w, http.StatusUnauthorized, "missing or invalid bearer token", nil)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (a *authenticator) valid(token string) bool {
+	hash := sha256.Sum256([]byte(token))
+	found := 0
+	for _, h := range a.hashes {
+		found |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	return found == 1
+}
+
+// serverTLS returns the TLS configuration of serve: nil without certificate, and verifying client
+// certificates against the CAs of clientCA, if given (mutual TLS). Certificates are only verified
+// during the handshake, so that health probes can connect without one; the authenticator
+// requires them on the other endpoints.
+func serverTLS(cert, key, clientCA string) (*tls.Config, error) {
+	if cert == "" && key == "" {
+		if clientCA != "" {
+			return nil, errors.New("-client-ca requires -tls-cert and -tls-key")
+		}
+		return nil, nil
+	}
+	if cert == "" || key == "" {
+		return nil, errors.New("-tls-cert and -tls-key must be given together")
+	}
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("reading client CA: no certificate in %s", clientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
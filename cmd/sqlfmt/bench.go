@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// benchLanguages are the dialects measured by bench unless -language is given.
+var benchLanguages = []sqlfmt.LanguageOption{
+	sqlfmt.LanguageSQL,
+	sqlfmt.LanguagePostgreSQL,
+	sqlfmt.LanguageMySQL,
+	sqlfmt.LanguageBigQuery,
+	sqlfmt.LanguageTransactSQL,
+}
+
+// benchQuery is a query bench formats.
+type benchQuery struct {
+	size string
+	sql  string
+}
+
+// benchQueries returns the queries measured by bench, from a one-liner to a script of a few
+// dozen report queries. They only use syntax that all dialects accept.
+func benchQueries() []benchQuery {
+	const small = `SELECT id, name FROM users WHERE active = 1 ORDER BY created_at DESC`
+	const medium = `SELECT u.id, u.name, COUNT(o.id) AS orders, SUM(CASE WHEN o.status = 'paid' THEN o.total ELSE 0 END) AS revenue,
+COALESCE(MAX(o.created_at), u.created_at) AS last_activity FROM users u LEFT JOIN orders o ON o.user_id = u.id AND o.deleted_at IS NULL
+JOIN accounts a ON a.id = u.account_id WHERE a.plan IN ('pro', 'enterprise') AND u.created_at BETWEEN '2024-01-01' AND '2024-12-31'
+AND u.id NOT IN (SELECT user_id FROM blocked_users WHERE reason <> 'test') GROUP BY u.id, u.name, u.created_at
+HAVING COUNT(o.id) > 5 OR SUM(o.total) > 1000 ORDER BY revenue DESC, u.name`
+	return []benchQuery{
+		{"small", small},
+		{"medium", medium},
+		{"large", strings.Repeat(medium+";\n", 40)},
+	}
+}
+
+func (c *cli) bench(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("bench", "[flags]")
+	common.register(fs)
+	duration := fs.Duration("duration", time.Second, "time spent on each latency and throughput measurement")
+	coldRuns := fs.Int("cold", 5, "number of cold starts measured")
+	poolSizes := fs.String("pools", "", "comma-separated pool sizes whose throughput is measured (default: powers of two up to the number of CPUs)")
+	bundle := fs.String("bundle", "", "sql-formatter bundle to measure instead of the embedded one (file or http(s) URL)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	languages := benchLanguages
+	if common.language != "" {
+		languages = []sqlfmt.LanguageOption{config.Language}
+	}
+	pools, err := parsePoolSizes(*poolSizes)
+	if err != nil {
+		return c.errorf("-pools: %v", err)
+	}
+	var opts []sqlfmt.FormatterOption
+	if *bundle != "" {
+		opts = append(opts, sqlfmt.WithBundlePath(*bundle))
+	}
+
+	b := &benchmark{
+		w:        c.stdout,
+		options:  config.FormatOptions,
+		opts:     opts,
+		duration: *duration,
+	}
+	fmt.Fprintf(c.stdout, "sqlfmt bench: %s backend, %s/%s, %d CPUs, %s\n", sqlfmt.Backend, runtime.GOOS, runtime.GOARCH, runtime.GOMAXPROCS(0), runtime.Version())
+	if *bundle != "" {
+		fmt.Fprintf(c.stdout, "bundle: %s\n", *bundle)
+	}
+	for _, step := range []func() error{
+		func() error { return b.coldStart(*coldRuns) },
+		func() error { return b.latency(languages) },
+		func() error { return b.throughput(pools) },
+	} {
+		if err := step(); err != nil {
+			return c.errorf("%v", err)
+		}
+	}
+	return exitOK
+}
+
+// parsePoolSizes parses the -pools flag. An empty value selects 1, 2, 4, ... up to the number of
+// CPUs, which is always included.
+func parsePoolSizes(value string) ([]int, error) {
+	if value == "" {
+		var sizes []int
+		for n := 1; n < runtime.GOMAXPROCS(0); n *= 2 {
+			sizes = append(sizes, n)
+		}
+		return append(sizes, runtime.GOMAXPROCS(0)), nil
+	}
+	var sizes []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a pool size", field)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+// benchmark runs the measurements of the bench command and prints their results.
+type benchmark struct {
+	w        io.Writer
+	options  sqlfmt.FormatOptions
+	opts     []sqlfmt.FormatterOption // options of the formatters created
+	duration time.Duration
+}
+
+// coldStart measures the time to create a formatter and format a first query, which includes
+// loading sql-formatter into a new context.
+func (b *benchmark) coldStart(runs int) error {
+	if runs < 1 {
+		return nil
+	}
+	var times []time.Duration
+	for range runs {
+		start := time.Now()
+		f, err := sqlfmt.NewFormatter(b.opts...)
+		if err != nil {
+			return err
+		}
+		_, err = f.Format("SELECT 1", b.options)
+		times = append(times, time.Since(start))
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	slices.Sort(times)
+	fmt.Fprintf(b.w, "\nCold start (new formatter and first query, %s):\n", plural(runs, "run"))
+	fmt.Fprintf(b.w, "  min %s  median %s  max %s\n", round(times[0]), round(times[len(times)/2]), round(times[len(times)-1]))
+	return nil
+}
+
+// latency measures the time to format each query of benchQueries in each language, with a
+// single warmed-up context.
+func (b *benchmark) latency(languages []sqlfmt.LanguageOption) error {
+	f, err := sqlfmt.NewFormatter(append(slices.Clone(b.opts), sqlfmt.WithPoolSize(1), sqlfmt.WithPrewarm(1))...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fmt.Fprintf(b.w, "\nLatency by dialect and query size (one context, %s each):\n", b.duration)
+	tw := tabwriter.NewWriter(b.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  dialect\tsize\tbytes\tqueries\tp50\tp99\tmean")
+	for _, lang := range languages {
+		options := b.options
+		options.Language = lang
+		for _, q := range benchQueries() {
+			var times []time.Duration
+			var total time.Duration
+			for total < b.duration || len(times) < 5 {
+				start := time.Now()
+				if _, err := f.Format(q.sql, options); err != nil {
+					return fmt.Errorf("%s, %s query: %w", lang, q.size, err)
+				}
+				d := time.Since(start)
+				times = append(times, d)
+				total += d
+			}
+			slices.Sort(times)
+			fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%s\t%s\t%s\n", lang, q.size, len(q.sql), len(times),
+				round(percentile(times, 50)), round(percentile(times, 99)), round(total/time.Duration(len(times))))
+		}
+	}
+	return tw.Flush()
+}
+
+// throughput measures the number of medium queries formatted per second by formatters with
+// each of the given pool sizes, under a load of twice as many concurrent callers as CPUs.
+func (b *benchmark) throughput(pools []int) error {
+	workers := 2 * runtime.GOMAXPROCS(0)
+	sql := benchQueries()[1].sql
+
+	fmt.Fprintf(b.w, "\nThroughput by pool size (medium query, %d concurrent callers, %s each):\n", workers, b.duration)
+	tw := tabwriter.NewWriter(b.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  pool\tqueries/s\tspeedup\tp99")
+	var base float64
+	for _, size := range pools {
+		f, err := sqlfmt.NewFormatter(append(slices.Clone(b.opts), sqlfmt.WithPoolSize(size), sqlfmt.WithPrewarm(size))...)
+		if err != nil {
+			return err
+		}
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			times    []time.Duration
+			failure  atomic.Pointer[error]
+			deadline = time.Now().Add(b.duration)
+			start    = time.Now()
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var local []time.Duration
+				for time.Now().Before(deadline) {
+					t := time.Now()
+					if _, err := f.Format(sql, b.options); err != nil {
+						failure.Store(&err)
+						return
+					}
+					local = append(local, time.Since(t))
+				}
+				mu.Lock()
+				times = append(times, local...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		elapsed := time.Since(start)
+		_ = f.Close()
+		if err := failure.Load(); err != nil {
+			return *err
+		}
+
+		slices.Sort(times)
+		rate := float64(len(times)) / elapsed.Seconds()
+		if base == 0 {
+			base = rate
+		}
+		fmt.Fprintf(tw, "  %d\t%.0f\t%.2fx\t%s\n", size, rate, rate/base, round(percentile(times, 99)))
+	}
+	return tw.Flush()
+}
+
+// percentile returns the p-th percentile of sorted, or 0 if it is empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[min(len(sorted)*p/100, len(sorted)-1)]
+}
+
+// round drops the digits of d that are noise, for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	case d >= time.Microsecond:
+		return d.Round(10 * time.Nanosecond)
+	}
+	return d
+}
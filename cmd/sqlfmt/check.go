@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) check(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("check", "[flags] [path ...]")
+	common.register(fs)
+	reportFormat := fs.String("report", "text", "output format: text, or rdjson for reviewdog, with suggested fixes")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *reportFormat != "text" && *reportFormat != "rdjson" {
+		return c.errorf("unknown report format %q (want text or rdjson)", *reportFormat)
+	}
+	rdjson := *reportFormat == "rdjson"
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	files, err := inputs(fs.Args(), config.Embedded...)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+	if pinned, current := config.BehaviorFingerprint, formatter.BehaviorFingerprint(); pinned != "" && pinned != current {
+		return c.errorf("formatting behavior changed: the configuration pins behaviorFingerprint %q, but this sqlfmt formats as %q; "+
+			"reformat with sqlfmt -w in a change of its own and set behaviorFingerprint to %q", pinned, current, current)
+	}
+
+	var (
+		r       report
+		results rdjsonResult
+	)
+	status := exitOK
+	for _, path := range files {
+		src, err := c.readInput(path)
+		if err != nil {
+			status = c.errorf("%v", err)
+			continue
+		}
+		if generated(src, config.FormatOptions) {
+			continue
+		}
+		var diagnostics []sqlfmt.Diagnostic
+		if embedded := config.EmbeddedPatterns(path); embedded != nil {
+			diagnostics, err = checkEmbedded(formatter, src, config, embedded)
+		} else {
+			diagnostics, err = checkSource(formatter, src, config, rdjson)
+		}
+		if err != nil {
+			status = c.errorf("%s: %v", path, err)
+			continue
+		}
+		r.add(path, diagnostics)
+		if rdjson {
+			results.add(path, src, diagnostics)
+			continue
+		}
+		for _, d := range diagnostics {
+			fmt.Fprintf(c.stdout, "%s:%s\n", path, d)
+		}
+	}
+
+	if rdjson {
+		if err := results.write(c.stdout); err != nil {
+			return c.errorf("%v", err)
+		}
+	} else {
+		r.write(c.stdout)
+	}
+	if r.errors > 0 && status == exitOK {
+		status = exitProblems
+	}
+	return status
+}
+
+// checkSource returns the diagnostics for src: a FormatRule diagnostic if formatting would change it,
+// followed by the lint diagnostics. With fixes, the FormatRule diagnostics carry the formatted text
+// as fix, one per changed statement when possible (see formatFixes).
+func checkSource(formatter *sqlfmt.Formatter, src string, config sqlfmt.Config, fixes bool) ([]sqlfmt.Diagnostic, error) {
+	var diagnostics []sqlfmt.Diagnostic
+
+	severity := sqlfmt.SeverityError
+	if s, ok := config.Lint.Rules[sqlfmt.FormatRule]; ok {
+		severity = s
+	}
+	if severity != sqlfmt.SeverityOff {
+		out, err := formatter.Format(src, config.FormatOptions)
+		if err != nil {
+			return nil, err
+		}
+		switch out = formattedFile(src, out); {
+		case out == src:
+		case fixes:
+			if diagnostics, err = formatFixes(formatter, src, out, config.FormatOptions, severity); err != nil {
+				return nil, err
+			}
+		default:
+			diagnostics = append(diagnostics, sqlfmt.Diagnostic{
+				Rule:     sqlfmt.FormatRule,
+				Severity: severity,
+				Message:  "input is not formatted",
+				Line:     1,
+				Column:   1,
+			})
+		}
+	}
+
+	lint, err := sqlfmt.Lint(src, config.Language, config.Lint)
+	if err != nil {
+		return nil, err
+	}
+	return append(diagnostics, lint...), nil
+}
+
+// checkEmbedded returns the diagnostics for the SQL embedded in src: a FormatRule diagnostic, with
+// its fix, for each query that formatting would change, and the lint diagnostics of each query,
+// located in src.
+func checkEmbedded(formatter *sqlfmt.Formatter, src string, config sqlfmt.Config, embedded []sqlfmt.EmbeddedPattern) ([]sqlfmt.Diagnostic, error) {
+	queries, err := sqlfmt.ExtractEmbedded(src, embedded)
+	if err != nil {
+		return nil, err
+	}
+	out, err := formatter.FormatEmbedded(src, embedded, config.FormatOptions)
+	if err != nil {
+		return nil, err
+	}
+	// Formatting leaves the delimiters alone, so the queries of out are those of src, formatted.
+	formatted, err := sqlfmt.ExtractEmbedded(out, embedded)
+	if err != nil {
+		return nil, err
+	}
+	if len(formatted) != len(queries) {
+		return nil, fmt.Errorf("formatting changed the delimiters of the embedded SQL")
+	}
+
+	severity := sqlfmt.SeverityError
+	if s, ok := config.Lint.Rules[sqlfmt.FormatRule]; ok {
+		severity = s
+	}
+	var diagnostics []sqlfmt.Diagnostic
+	for i, q := range queries {
+		if severity != sqlfmt.SeverityOff && formatted[i].SQL != q.SQL {
+			line, column := position(src, q.Start)
+			diagnostics = append(diagnostics, sqlfmt.Diagnostic{
+				Rule:     sqlfmt.FormatRule,
+				Severity: severity,
+				Message:  "embedded SQL is not formatted",
+				Line:     line,
+				Column:   column,
+				Start:    q.Start,
+				End:      q.End,
+				Fix:      &sqlfmt.Fix{Start: q.Start, End: q.End, Text: formatted[i].SQL},
+			})
+		}
+
+		lint, err := sqlfmt.Lint(q.SQL, config.Language, config.Lint)
+		if err != nil {
+			return nil, err
+		}
+		line, column := position(src, q.Start)
+		for _, d := range lint {
+			if d.Line == 1 {
+				d.Column += column - 1
+			}
+			d.Line += line - 1
+			d.Start += q.Start
+			d.End += q.Start
+			if d.Fix != nil {
+				fix := *d.Fix
+				fix.Start += q.Start
+				fix.End += q.Start
+				d.Fix = &fix
+			}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics, nil
+}
+
+// counts tallies diagnostics by severity.
+type counts struct {
+	errors, warnings int
+}
+
+func (n *counts) add(d sqlfmt.Diagnostic) {
+	if d.Severity == sqlfmt.SeverityError {
+		n.errors++
+	} else {
+		n.warnings++
+	}
+}
+
+func (n counts) String() string {
+	return fmt.Sprintf("%s, %s", plural(n.errors, "error"), plural(n.warnings, "warning"))
+}
+
+// report aggregates diagnostics per rule and per file.
+type report struct {
+	counts
+	files   int
+	byRule  map[string]*counts
+	byFile  map[string]*counts
+	ordered []string // files with diagnostics, in the order they were checked
+}
+
+func (r *report) add(path string, diagnostics []sqlfmt.Diagnostic) {
+	r.files++
+	if len(diagnostics) == 0 {
+		return
+	}
+	if r.byRule == nil {
+		r.byRule = make(map[string]*counts)
+		r.byFile = make(map[string]*counts)
+	}
+	r.ordered = append(r.ordered, path)
+	r.byFile[path] = &counts{}
+	for _, d := range diagnostics {
+		r.counts.add(d)
+		r.byFile[path].add(d)
+		if r.byRule[d.Rule] == nil {
+			r.byRule[d.Rule] = &counts{}
+		}
+		r.byRule[d.Rule].add(d)
+	}
+}
+
+func (r *report) write(w io.Writer) {
+	if len(r.byRule) > 0 {
+		rules := make([]string, 0, len(r.byRule))
+		for rule := range r.byRule {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "By rule:")
+		for _, rule := range rules {
+			fmt.Fprintf(w, "  %-28s %s\n", rule, r.byRule[rule])
+		}
+		fmt.Fprintln(w, "By file:")
+		for _, path := range r.ordered {
+			fmt.Fprintf(w, "  %-28s %s\n", path, r.byFile[path])
+		}
+	}
+	fmt.Fprintf(w, "%s in %s\n", r.counts, plural(r.files, "file"))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
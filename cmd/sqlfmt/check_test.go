@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func TestCheckSourceEmpty(t *testing.T) {
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer formatter.Close()
+	config := sqlfmt.DefaultConfig()
+	for _, src := range []string{"", "\n", "  \n\n"} {
+		for _, fixes := range []bool{false, true} {
+			diagnostics, err := checkSource(formatter, src, config, fixes)
+			if err != nil {
+				t.Fatalf("%q: %v", src, err)
+			}
+			if len(diagnostics) > 0 {
+				t.Errorf("%q: got %v, want no diagnostic", src, diagnostics)
+			}
+		}
+	}
+
+	diagnostics, err := checkSource(formatter, "select 1", config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) == 0 || diagnostics[0].Rule != sqlfmt.FormatRule {
+		t.Errorf("got %v for unformatted input, want a %s diagnostic", diagnostics, sqlfmt.FormatRule)
+	}
+}
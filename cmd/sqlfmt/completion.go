@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) completion(args []string) int {
+	fs := c.newFlagSet("completion", "bash|zsh|fish")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	commands := describeCommands()
+	switch fs.Arg(0) {
+	case "bash":
+		writeBashCompletion(c.stdout, commands)
+	case "zsh":
+		writeZshCompletion(c.stdout, commands)
+	case "fish":
+		writeFishCompletion(c.stdout, commands)
+	default:
+		return c.errorf("unsupported shell %q (want bash, zsh or fish)", fs.Arg(0))
+	}
+	return exitOK
+}
+
+// commandInfo is a command with the flags it accepts.
+type commandInfo struct {
+	command
+	usage string
+	flags []*flag.Flag
+}
+
+// describeCommands returns the commands with their flags. Commands define their flags as they
+// parse their arguments, so each one is run with -h by a cli that inspects the flag set instead
+// of printing the usage.
+func describeCommands() []commandInfo {
+	var infos []commandInfo
+	for _, cmd := range commands {
+		info := commandInfo{command: cmd}
+		c := &cli{
+			stdin:  strings.NewReader(""),
+			stdout: io.Discard,
+			stderr: io.Discard,
+			inspect: func(usage string, fs *flag.FlagSet) {
+				info.usage = usage
+				fs.VisitAll(func(f *flag.Flag) {
+					info.flags = append(info.flags, f)
+				})
+			},
+		}
+		cmd.run(c, []string{"-h"})
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// defaultCommand returns the command run when no command is named.
+func defaultCommand(commands []commandInfo) commandInfo {
+	for _, cmd := range commands {
+		if cmd.name == "format" {
+			return cmd
+		}
+	}
+	return commandInfo{}
+}
+
+// isBoolFlag reports whether f takes no value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValues returns the values completed for the flag, if it has a fixed set of them.
+func flagValues(f *flag.Flag) []string {
+	if f.Name == "language" {
+		return configProperties()["language"].Enum
+	}
+	return nil
+}
+
+// configProperty describes a configuration key, as read from sqlfmt.ConfigSchema.
+type configProperty struct {
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Default     any      `json:"default"`
+	Enum        []string `json:"enum"`
+}
+
+// configProperties returns the formatting options of the configuration file by name.
+func configProperties() map[string]configProperty {
+	var schema struct {
+		Properties map[string]configProperty `json:"properties"`
+	}
+	if err := json.Unmarshal(sqlfmt.ConfigSchema(), &schema); err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"lint", "embedded", "behaviorFingerprint"} {
+		delete(schema.Properties, name) // not formatting options
+	}
+	return schema.Properties
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeBashCompletion(w io.Writer, commands []commandInfo) {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	flagNames := func(cmd commandInfo) string {
+		var out []string
+		for _, f := range cmd.flags {
+			out = append(out, "-"+f.Name)
+		}
+		return strings.Join(out, " ")
+	}
+
+	fmt.Fprintln(w, "# bash completion for sqlfmt; generated by sqlfmt completion bash")
+	fmt.Fprintln(w, "_sqlfmt() {")
+	fmt.Fprintln(w, `	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} flags`)
+	fmt.Fprintln(w, `	case $prev in`)
+	for _, f := range defaultCommand(commands).flags {
+		if values := flagValues(f); values != nil {
+			fmt.Fprintf(w, "\t-%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", f.Name, strings.Join(values, " "))
+		}
+	}
+	fmt.Fprintln(w, `	esac`)
+	fmt.Fprintln(w, `	case ${COMP_WORDS[1]} in`)
+	for _, cmd := range commands {
+		if cmd.name != "format" {
+			fmt.Fprintf(w, "\t%s) flags=%q ;;\n", cmd.name, flagNames(cmd))
+		}
+	}
+	fmt.Fprintf(w, "\t*) flags=%q ;;\n", flagNames(defaultCommand(commands)))
+	fmt.Fprintln(w, `	esac`)
+	fmt.Fprintln(w, `	if [[ $cur == -* ]]; then`)
+	fmt.Fprintln(w, `		COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Fprintln(w, `	elif [[ $COMP_CWORD -eq 1 ]]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, `	else`)
+	fmt.Fprintln(w, `		COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, `	fi`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _sqlfmt sqlfmt")
+}
+
+func writeZshCompletion(w io.Writer, commands []commandInfo) {
+	escape := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace
+	arguments := func(cmd commandInfo) string {
+		var specs []string
+		for _, f := range cmd.flags {
+			spec := fmt.Sprintf("-%s[%s]", f.Name, escape(f.Usage))
+			if !isBoolFlag(f) {
+				action := "_files"
+				if values := flagValues(f); values != nil {
+					action = "(" + strings.Join(values, " ") + ")"
+				}
+				spec += ":" + f.Name + ":" + action
+			}
+			specs = append(specs, "'"+spec+"'")
+		}
+		return strings.Join(append(specs, "'*:file:_files'"), " ")
+	}
+
+	fmt.Fprintln(w, "#compdef sqlfmt")
+	fmt.Fprintln(w, "# zsh completion for sqlfmt; generated by sqlfmt completion zsh")
+	fmt.Fprintln(w, "_sqlfmt() {")
+	fmt.Fprintln(w, "\tlocal -a commands")
+	fmt.Fprintln(w, "\tcommands=(")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "\t\t'%s:%s'\n", cmd.name, escape(cmd.summary))
+	}
+	fmt.Fprintln(w, "\t)")
+	fmt.Fprintln(w, "\tif (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then")
+	fmt.Fprintln(w, "\t\t_describe -t commands command commands")
+	fmt.Fprintln(w, "\t\t_files")
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "\tcase $words[2] in")
+	for _, cmd := range commands {
+		if cmd.name != "format" {
+			fmt.Fprintf(w, "\t%s) shift words; (( CURRENT-- )); _arguments %s ;;\n", cmd.name, arguments(cmd))
+		}
+	}
+	fmt.Fprintf(w, "\tformat) shift words; (( CURRENT-- )); _arguments %s ;;\n", arguments(defaultCommand(commands)))
+	fmt.Fprintf(w, "\t*) _arguments %s ;;\n", arguments(defaultCommand(commands)))
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, `_sqlfmt "$@"`)
+}
+
+func writeFishCompletion(w io.Writer, commands []commandInfo) {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	var others []string
+	for _, cmd := range commands {
+		if cmd.name != "format" {
+			others = append(others, cmd.name)
+		}
+	}
+
+	fmt.Fprintln(w, "# fish completion for sqlfmt; generated by sqlfmt completion fish")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c sqlfmt -n __fish_use_subcommand -f -a %s -d %s\n", cmd.name, quote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		if cmd.name == "format" {
+			// format is also the default command.
+			condition = "not __fish_seen_subcommand_from " + strings.Join(others, " ")
+		}
+		for _, f := range cmd.flags {
+			line := fmt.Sprintf("complete -c sqlfmt -n %s -o %s", quote(condition), f.Name)
+			if !isBoolFlag(f) {
+				line += " -r"
+				if values := flagValues(f); values != nil {
+					line += " -f -a " + quote(strings.Join(values, " "))
+				}
+			}
+			fmt.Fprintln(w, line+" -d "+quote(f.Usage))
+		}
+	}
+}
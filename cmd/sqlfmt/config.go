@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/0x6b/sqlfmt"
+)
+
+// config runs the subcommands dealing with the configuration file.
+func (c *cli) config(args []string) int {
+	fs := c.newFlagSet("config", "schema")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "schema" {
+		fs.Usage()
+		return exitError
+	}
+	if _, err := c.stdout.Write(sqlfmt.ConfigSchema()); err != nil {
+		return c.errorf("%v", err)
+	}
+	return exitOK
+}
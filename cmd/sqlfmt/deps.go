@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) deps(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("deps", "[flags] [path]")
+	common.register(fs)
+	dot := fs.Bool("dot", false, "print the graph in Graphviz DOT format")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	path := stdinPath
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	src, err := c.readInput(path)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	g := sqlfmt.Dependencies(src, config.FormatOptions.Language)
+	if *dot {
+		writeDOT(c.stdout, g)
+	} else {
+		writeDeps(c.stdout, g)
+	}
+	return exitOK
+}
+
+// writeDeps prints the objects of g that create something or depend on something, each followed
+// by its dependencies. Dependencies the script does not create are marked as external.
+func writeDeps(w io.Writer, g sqlfmt.SchemaGraph) {
+	for _, obj := range g.Objects {
+		if !obj.Creates && len(obj.DependsOn) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%d: %s\n", obj.Line, objectLabel(obj))
+		for _, d := range obj.DependsOn {
+			if d.Object < 0 {
+				fmt.Fprintf(w, "    %s (%s, external)\n", d.Name, d.Reason)
+				continue
+			}
+			fmt.Fprintf(w, "    %s (%s)\n", objectLabel(g.Objects[d.Object]), d.Reason)
+		}
+	}
+}
+
+// writeDOT prints g as a Graphviz digraph, with an edge from each statement to the objects it
+// depends on. Objects the script does not create are drawn dashed.
+func writeDOT(w io.Writer, g sqlfmt.SchemaGraph) {
+	fmt.Fprintln(w, "digraph dependencies {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	external := make(map[string]string) // node IDs of external objects by name
+	for i, obj := range g.Objects {
+		if !obj.Creates && len(obj.DependsOn) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\tn%d [label=%s];\n", i, strconv.Quote(objectLabel(obj)))
+		for _, d := range obj.DependsOn {
+			to := "n" + strconv.Itoa(d.Object)
+			if d.Object < 0 {
+				name := d.Name.String()
+				if to = external[name]; to == "" {
+					to = "x" + strconv.Itoa(len(external))
+					external[name] = to
+					fmt.Fprintf(w, "\t%s [label=%s, style=dashed];\n", to, strconv.Quote(name))
+				}
+			}
+			fmt.Fprintf(w, "\tn%d -> %s [label=%s];\n", i, to, strconv.Quote(d.Reason))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// objectLabel describes obj by kind and name, such as "VIEW public.active_users".
+func objectLabel(obj sqlfmt.SchemaObject) string {
+	if obj.Name.Name == "" {
+		return obj.Kind
+	}
+	return obj.Kind + " " + obj.Name.String()
+}
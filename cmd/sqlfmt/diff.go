@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// changedLines returns the lines added or modified in revisions, a range such as main..HEAD or
+// anything else git diff accepts, for the *.sql files under paths. Files are keyed by their path
+// relative to the current directory.
+func changedLines(revisions string, paths []string) (map[string][]sqlfmt.LineRange, error) {
+	args := append([]string{"diff", "--relative", "--no-prefix", "--no-color", "--no-ext-diff", "-U0", revisions, "--"}, paths...)
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s: %v: %s", revisions, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseDiff(out)
+}
+
+// parseDiff returns the line ranges of the new side of the hunks of a unified diff without
+// context, by file.
+func parseDiff(diff []byte) (map[string][]sqlfmt.LineRange, error) {
+	changed := make(map[string][]sqlfmt.LineRange)
+	var file string
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(line, "+++ ")
+			if file == "/dev/null" || !strings.EqualFold(filepath.Ext(file), ".sql") {
+				file = ""
+			}
+		case strings.HasPrefix(line, "@@ ") && file != "":
+			// @@ -start[,count] +start[,count] @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, count, hasCount := strings.Cut(fields[2][1:], ",")
+			first, err := strconv.Atoi(start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			n := 1
+			if hasCount {
+				if n, err = strconv.Atoi(count); err != nil {
+					return nil, fmt.Errorf("invalid hunk header %q", line)
+				}
+			}
+			if n == 0 {
+				// Lines were only removed, after line first: the statement around the removal
+				// changed.
+				changed[file] = append(changed[file], sqlfmt.LineRange{Start: first, End: first + 1})
+				continue
+			}
+			changed[file] = append(changed[file], sqlfmt.LineRange{Start: first, End: first + n - 1})
+		}
+	}
+	return changed, scanner.Err()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// selfTestQuery is formatted by doctor with sqlfmt.DefaultFormatOptions, which must give
+// selfTestOutput with every backend.
+const (
+	selfTestQuery  = "select id, name from users where active = 1 order by name"
+	selfTestOutput = "SELECT\n    id,\n    name\nFROM\n    users\nWHERE\n    active = 1\nORDER BY\n    name"
+)
+
+func (c *cli) doctor(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("doctor", "[flags]")
+	common.register(fs)
+	bundle := fs.String("bundle", "", "sql-formatter bundle to self-test instead of the embedded one (file or http(s) URL)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	status := exitOK
+	problem := func(format string, args ...any) {
+		field(c.stdout, "problem", format, args...)
+		status = exitProblems
+	}
+
+	fmt.Fprintln(c.stdout, "Build:")
+	writeBuildInfo(c.stdout)
+
+	fmt.Fprintln(c.stdout, "\nFormatter:")
+	field(c.stdout, "backend", "%s", backendDescription())
+	switch {
+	case *bundle != "":
+		field(c.stdout, "bundle", "%s", *bundle)
+	case sqlfmt.Backend == "native":
+		field(c.stdout, "bundle", "not used")
+	case sqlfmt.BundleVersion != "":
+		field(c.stdout, "bundle", "sql-formatter %s (embedded)", sqlfmt.BundleVersion)
+	default:
+		field(c.stdout, "bundle", "none embedded (sqlfmt_noembed build); pass -bundle")
+	}
+
+	fmt.Fprintln(c.stdout, "\nConfiguration:")
+	writeConfigFiles(c.stdout, common.config)
+	for _, kv := range os.Environ() {
+		if name, value, _ := strings.Cut(kv, "="); strings.HasPrefix(name, sqlfmt.EnvPrefix) {
+			if strings.Contains(name, "TOKEN") {
+				value = "(redacted)"
+			}
+			field(c.stdout, "env", "%s=%s", name, value)
+		}
+	}
+	config, err := common.load()
+	if err != nil {
+		problem("%v", err)
+		config = sqlfmt.DefaultConfig()
+	} else {
+		options, _ := json.MarshalIndent(config.FormatOptions, "  ", "  ")
+		field(c.stdout, "effective options", "%s", options)
+	}
+
+	fmt.Fprintln(c.stdout, "\nSelf-test:")
+	var opts []sqlfmt.FormatterOption
+	if *bundle != "" {
+		opts = append(opts, sqlfmt.WithBundlePath(*bundle))
+	}
+	start := time.Now()
+	formatter, err := sqlfmt.NewFormatter(opts...)
+	if err != nil {
+		problem("creating a formatter: %v", err)
+		return status
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+	field(c.stdout, "fingerprint", "%s", formatter.BehaviorFingerprint())
+	if pinned := config.BehaviorFingerprint; pinned != "" && pinned != formatter.BehaviorFingerprint() {
+		problem("the configuration pins behaviorFingerprint %q: check fails until the code base is reformatted and the pin updated", pinned)
+	}
+	out, err := formatter.Format(selfTestQuery, sqlfmt.DefaultFormatOptions)
+	switch {
+	case err != nil:
+		problem("formatting with the default options: %v", err)
+	case out != selfTestOutput:
+		problem("formatting with the default options gave %q, want %q", out, selfTestOutput)
+	default:
+		field(c.stdout, "default options", "ok (%s, with startup)", round(time.Since(start)))
+	}
+	start = time.Now()
+	_, warnings, err := formatter.FormatWithWarnings(selfTestQuery, config.FormatOptions)
+	for _, w := range warnings {
+		problem("formatting with the effective options: %s", w)
+	}
+	if err != nil {
+		problem("formatting with the effective options: %v", err)
+	} else if len(warnings) == 0 {
+		field(c.stdout, "effective options", "ok (%s)", round(time.Since(start)))
+	}
+	return status
+}
+
+// backendDescription describes sqlfmt.Backend.
+func backendDescription() string {
+	switch sqlfmt.Backend {
+	case "quickjs":
+		return "quickjs (sql-formatter in QuickJS, cgo)"
+	case "js":
+		return "js (sql-formatter in the JavaScript host, js/wasm)"
+	case "native":
+		return "native (pure Go, sql-formatter not used)"
+	}
+	return sqlfmt.Backend
+}
+
+// writeBuildInfo prints the version of sqlfmt, of Go and the build settings that select the
+// backend.
+func writeBuildInfo(w io.Writer) {
+	field(w, "go", "%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	version := info.Main.Version
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/0x6b/sqlfmt" {
+			version = dep.Version // the command was built from another module
+		}
+	}
+	field(w, "version", "%s", version)
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "CGO_ENABLED", "-tags", "vcs.revision", "vcs.modified":
+			field(w, s.Key, "%s", s.Value)
+		}
+	}
+}
+
+// writeConfigFiles prints the configuration files that apply in the current directory: the one
+// given with -config, or every ConfigFileName from the current directory up, the nearest of
+// which is used.
+func writeConfigFiles(w io.Writer, explicit string) {
+	if explicit != "" {
+		field(w, "file", "%s (-config)", explicit)
+		return
+	}
+	dir, err := filepath.Abs(".")
+	if err != nil {
+		field(w, "problem", "%v", err)
+		return
+	}
+	var found []string
+	for {
+		path := filepath.Join(dir, sqlfmt.ConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			field(w, "problem", "%v", err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if len(found) == 0 {
+		field(w, "file", "none found (no %s here or in a parent directory); defaults apply", sqlfmt.ConfigFileName)
+		return
+	}
+	for i, path := range found {
+		if i == 0 {
+			field(w, "file", "%s (used)", path)
+		} else {
+			field(w, "file", "%s (ignored: a nearer file is used)", path)
+		}
+	}
+}
+
+// field prints a labeled line of the doctor report.
+func field(w io.Writer, label, format string, args ...any) {
+	fmt.Fprintf(w, "  %-18s %s\n", label+":", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) explain(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("explain", "[flags] [path]")
+	common.register(fs)
+	showTokens := fs.Bool("tokens", true, "list the tokens of the input")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	path := stdinPath
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	src, err := c.readInput(path)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	formatter, err := sqlfmt.NewFormatter(sqlfmt.WithPoolSize(1))
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+	x, err := formatter.Explain(src, config.FormatOptions)
+	if err != nil {
+		return c.errorf("%s: %v", path, err)
+	}
+	writeExplanation(c.stdout, src, x, *showTokens)
+	return exitOK
+}
+
+// writeExplanation prints x, the explanation of formatting src, section by section.
+func writeExplanation(w io.Writer, src string, x *sqlfmt.Explanation, tokens bool) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if tokens {
+		fmt.Fprintln(tw, "TOKENS")
+		for _, t := range x.Tokens {
+			fmt.Fprintf(tw, "  %d:%d\t%s\t%s\n", t.Line, t.Column, t.Kind, strconv.Quote(t.Text))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintln(tw, "STATEMENTS")
+	for i, s := range x.Statements {
+		first, _ := strings.CutSuffix(firstLine(src[s.Start:s.End]), ";")
+		fmt.Fprintf(tw, "  %d\tbytes %d-%d\t%s\t%s\n", i+1, s.Start, s.End, s.Kind, first)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "PASSES")
+	for _, p := range x.Passes {
+		switch {
+		case p.Note != "":
+			fmt.Fprintf(tw, "  %s\t%s\n", p.Name, p.Note)
+		case p.Changed:
+			fmt.Fprintf(tw, "  %s\tchanged lines %s\n", p.Name, lineList(p.Lines))
+		default:
+			fmt.Fprintf(tw, "  %s\tno change\n", p.Name)
+		}
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "OPTIONS")
+	for _, o := range x.Options {
+		if len(o.Lines) == 0 {
+			fmt.Fprintf(tw, "  %s=%s\tno effect\n", o.Option, o.Value)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s=%s\tlines %s\n", o.Option, o.Value, lineList(o.Lines))
+	}
+	fmt.Fprintln(tw)
+
+	if len(x.Warnings) > 0 {
+		fmt.Fprintln(tw, "WARNINGS")
+		for _, warning := range x.Warnings {
+			fmt.Fprintf(tw, "  %s\n", warning)
+		}
+		fmt.Fprintln(tw)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w, "OUTPUT")
+	for i, line := range strings.Split(x.Output, "\n") {
+		fmt.Fprintf(w, "%4d  %s\n", i+1, line)
+	}
+}
+
+// lineList formats sorted line numbers compactly, with runs as ranges: 1-3, 5.
+func lineList(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(lines[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstLine returns the first non-blank line of s, trimmed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) format(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("format", "[flags] [path ...]")
+	common.register(fs)
+	write := fs.Bool("w", false, "write the result to the source file instead of standard output")
+	list := fs.Bool("l", false, "list files whose formatting differs and do not print the result")
+	diffFilter := fs.String("diff-filter", "", "only format the statements changed in this git revision range (such as main..HEAD)")
+	stream := fs.Bool("stream", false, "format statement by statement, with memory bounded by the largest statement, for huge files such as dumps")
+	skipLargerThan := fs.Int("skip-larger-than", 0, "with -stream, pass statements larger than this many bytes through unformatted")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *stream && (*list || *diffFilter != "") {
+		return c.errorf("-stream cannot be combined with -l or -diff-filter")
+	}
+	if *skipLargerThan > 0 && !*stream {
+		return c.errorf("-skip-larger-than requires -stream")
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	var (
+		files   []string
+		changed map[string][]sqlfmt.LineRange
+	)
+	if *diffFilter != "" {
+		if changed, err = changedLines(*diffFilter, fs.Args()); err != nil {
+			return c.errorf("%v", err)
+		}
+		files = sortedKeys(changed)
+	} else if files, err = inputs(fs.Args(), config.Embedded...); err != nil {
+		return c.errorf("%v", err)
+	}
+
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+
+	status := exitOK
+	for _, path := range files {
+		embedded := config.EmbeddedPatterns(path)
+		if *stream && embedded == nil {
+			status = max(status, c.formatStream(formatter, path, config.FormatOptions, *write, *skipLargerThan))
+			continue
+		}
+		src, err := c.readInput(path)
+		if err != nil {
+			status = c.errorf("%v", err)
+			continue
+		}
+		if generated(src, config.FormatOptions) {
+			if !*list && !*write {
+				fmt.Fprint(c.stdout, src)
+			}
+			continue
+		}
+		var out string
+		if embedded != nil {
+			out, err = formatter.FormatEmbedded(src, embedded, config.FormatOptions)
+		} else if changed != nil {
+			out, err = formatter.FormatLines(src, config.FormatOptions, changed[path])
+		} else if out, err = formatter.Format(src, config.FormatOptions); err == nil {
+			out = formattedFile(src, out)
+		}
+		if err != nil {
+			status = c.errorf("%s: %v", path, err)
+			continue
+		}
+
+		switch {
+		case *list:
+			if out != src {
+				fmt.Fprintln(c.stdout, path)
+			}
+		case *write && path != stdinPath:
+			if out != src {
+				if err := writeOutput(path, []byte(out)); err != nil {
+					status = c.errorf("%v", err)
+				}
+			}
+		default:
+			fmt.Fprint(c.stdout, out)
+		}
+	}
+	return status
+}
+
+// formattedFile returns the content of the file src once formatted, given out, its SQL formatted:
+// out and a final newline, or src itself if it holds no SQL, such as an empty file.
+func formattedFile(src, out string) string {
+	if out == "" && strings.TrimSpace(src) == "" {
+		return src
+	}
+	return out + "\n"
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// generatedPeekSize is how much of a file formatStream reads ahead to find the marker of generated
+// files, which must be in the comments at its top.
+const generatedPeekSize = 64 << 10
+
+// formatStream formats the file at path (or standard input) statement by statement with
+// FormatStream, writing each statement as soon as it is formatted, so that memory use is bounded
+// by the largest statement rather than the size of the file. Statements that fail to format are
+// reported and written as they are. With write, the result replaces the file.
+func (c *cli) formatStream(formatter *sqlfmt.Formatter, path string, options sqlfmt.FormatOptions, write bool, skipLargerThan int) int {
+	var in io.Reader = c.stdin
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// Leave generated files alone, as detected from their first bytes.
+	buffered := bufio.NewReaderSize(in, generatedPeekSize)
+	head, _ := buffered.Peek(generatedPeekSize)
+	if generated(string(head), options) {
+		if write {
+			return exitOK
+		}
+		if _, err := io.Copy(c.stdout, buffered); err != nil {
+			return c.errorf("%s: %v", path, err)
+		}
+		return exitOK
+	}
+	in = buffered
+
+	var (
+		out  = c.stdout
+		temp *os.File
+	)
+	if write && path != stdinPath {
+		var err error
+		if temp, err = os.CreateTemp(filepath.Dir(path), ".sqlfmt-*.sql"); err != nil {
+			return c.errorf("%v", err)
+		}
+		defer os.Remove(temp.Name()) // fails harmlessly once renamed
+		defer temp.Close()
+		out = temp
+	}
+
+	status := exitOK
+	source := &sourceRecorder{r: in, line: 1}
+	w := &streamWriter{
+		w:        bufio.NewWriter(out),
+		lines:    options.BlankLinesBetweenQueries(),
+		grouping: options.DumpFormat == sqlfmt.DumpFormatMySQLDump,
+	}
+	err := formatter.FormatStream(source, options, func(result sqlfmt.StatementResult) error {
+		text, line := source.text(result.Start, result.End)
+		if result.Err != nil {
+			status = c.errorf("%s:%d: %v", path, line, result.Err)
+			result.Formatted = text
+		}
+		w.write(result)
+		return nil
+	}, sqlfmt.SkipLargerThan(skipLargerThan))
+	if err == nil {
+		err = w.close()
+	}
+	if err != nil {
+		return c.errorf("%s: %v", path, err)
+	}
+
+	if temp != nil {
+		if info, err := os.Stat(path); err == nil {
+			_ = temp.Chmod(info.Mode())
+		}
+		if err := temp.Close(); err != nil {
+			return c.errorf("%v", err)
+		}
+		if err := os.Rename(temp.Name(), path); err != nil {
+			return c.errorf("%v", err)
+		}
+	}
+	return status
+}
+
+// streamWriter writes the results of FormatStream laid out like the output of Format: statements
+// and the verbatim parts of dumps separated by LinesBetweenQueries blank lines. Consecutive
+// verbatim parts, such as the chunks of the data of a COPY statement, are written as they are.
+// With grouping, the parts from LOCK TABLES to UNLOCK TABLES are one line apart, as in mysqldump
+// output formatted with Format.
+type streamWriter struct {
+	w        *bufio.Writer
+	lines    int    // blank lines between statements
+	grouping bool   // whether LOCK TABLES groups are kept together
+	grouped  bool   // whether the output is inside a LOCK TABLES group
+	started  bool   // whether anything was written
+	verbatim bool   // whether the last result was a verbatim part of a dump
+	held     string // whitespace ending the last verbatim part, written if another one follows
+}
+
+// write writes a result.
+func (w *streamWriter) write(result sqlfmt.StatementResult) {
+	text := result.Formatted
+	part := result.Verbatim && result.Kind == "" // a part of a dump, with the whitespace around it
+	if part && w.verbatim {
+		w.w.WriteString(w.held)
+	} else {
+		if part {
+			text = strings.TrimLeft(text, " \t\r\n")
+		}
+		switch {
+		case w.grouped:
+			w.w.WriteString("\n")
+		case w.started:
+			w.w.WriteString(strings.Repeat("\n", w.lines+1))
+		}
+	}
+	if w.grouping {
+		w.grouped = result.Kind == "LOCK" || w.grouped && result.Kind != "UNLOCK"
+	}
+	w.held = ""
+	if part {
+		body := strings.TrimRight(text, " \t\r\n")
+		text, w.held = body, text[len(body):]
+	}
+	w.w.WriteString(text)
+	w.started, w.verbatim = true, part
+}
+
+// close ends the output with a line break and flushes it.
+func (w *streamWriter) close() error {
+	if w.started {
+		w.w.WriteString("\n")
+	}
+	return w.w.Flush()
+}
+
+// sourceRecorder keeps the input read through it that has not been claimed yet, so that
+// statements can be written as they are when they fail to format.
+type sourceRecorder struct {
+	r    io.Reader
+	buf  []byte
+	base int // offset of buf in the input
+	line int // line of base (1-based)
+}
+
+func (s *sourceRecorder) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.buf = append(s.buf, p[:n]...)
+	return n, err
+}
+
+// text returns the input from start to end and the line of start, and discards the input before
+// end. Calls must claim the input in order.
+func (s *sourceRecorder) text(start, end int) (string, int) {
+	i, j := start-s.base, end-s.base
+	line := s.line + bytes.Count(s.buf[:i], []byte("\n"))
+	text := string(s.buf[i:j])
+	s.line = line + strings.Count(text, "\n")
+	s.buf = append(s.buf[:0], s.buf[j:]...)
+	s.base = end
+	return text, line
+}
@@ -0,0 +1,18 @@
+module github.com/0x6b/sqlfmt/cmd/sqlfmt
+
+go 1.24.4
+
+require (
+	github.com/0x6b/sqlfmt v0.0.0-00010101000000-000000000000
+	github.com/go-sql-driver/mysql v1.10.1
+	github.com/lib/pq v1.12.3
+	github.com/mattn/go-sqlite3 v1.14.52
+)
+
+require (
+	filippo.io/edwards25519 v1.2.0 // indirect
+	github.com/rosbit/go-embedding-utils v0.4.1 // indirect
+)
+
+// The command is developed with the package in this repository.
+replace github.com/0x6b/sqlfmt => ../..
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// stdinPath is the name used for standard input in messages.
+const stdinPath = "<stdin>"
+
+// commonFlags are the flags shared by all commands that process SQL.
+type commonFlags struct {
+	config   string
+	strict   bool
+	language string
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "path to the configuration file (default: nearest "+sqlfmt.ConfigFileName+")")
+	fs.BoolVar(&f.strict, "strict", false, "reject unknown keys and invalid values in the configuration file")
+	fs.StringVar(&f.language, "language", "", "SQL dialect, overriding the configuration")
+}
+
+// load returns the effective configuration: the configuration file, overridden by SQLFMT_*
+// environment variables, overridden by flags.
+func (f *commonFlags) load() (sqlfmt.Config, error) {
+	path := f.config
+	if path == "" {
+		found, err := sqlfmt.FindConfig(".")
+		if err != nil {
+			return sqlfmt.Config{}, err
+		}
+		path = found
+	}
+
+	config := sqlfmt.DefaultConfig()
+	if path != "" {
+		loadConfig := sqlfmt.LoadConfig
+		if f.strict {
+			loadConfig = sqlfmt.LoadConfigStrict
+		}
+		loaded, err := loadConfig(path)
+		if err != nil {
+			return sqlfmt.Config{}, err
+		}
+		config = loaded
+	}
+	if err := config.ApplyEnv(); err != nil {
+		return sqlfmt.Config{}, err
+	}
+	if f.language != "" {
+		config.Language = sqlfmt.LanguageOption(f.language)
+	}
+	return config, nil
+}
+
+// collectFiles expands paths into the list of files to process. Directories are searched
+// recursively for *.sql files, and the files holding the SQL of embedded; a trailing "/...", as in
+// Go package patterns, is accepted and means the same. An empty list means standard input.
+func collectFiles(paths []string, embedded ...sqlfmt.EmbeddedPattern) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		if dir, ok := strings.CutSuffix(p, "/..."); ok {
+			p = cmp.Or(dir, "/")
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (strings.EqualFold(filepath.Ext(path), ".sql") || slices.ContainsFunc(embedded, func(p sqlfmt.EmbeddedPattern) bool {
+				return p.MatchFile(path)
+			})) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readInput reads the named file, or standard input for stdinPath.
+func (c *cli) readInput(path string) (string, error) {
+	if path == stdinPath {
+		data, err := io.ReadAll(c.stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// writeOutput writes data to the named file, keeping the permissions of the file it replaces. New
+// files get those of os.Create, 0o666 less the umask.
+func writeOutput(path string, data []byte) error {
+	perm := os.FileMode(0o666)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// inputs returns the files named by paths, or stdinPath if there are none.
+func inputs(paths []string, embedded ...sqlfmt.EmbeddedPattern) ([]string, error) {
+	if len(paths) == 0 {
+		return []string{stdinPath}, nil
+	}
+	return collectFiles(paths, embedded...)
+}
+
+// generated reports whether src is marked as generated (see sqlfmt.IsGenerated), so that commands
+// leave it alone.
+func generated(src string, options sqlfmt.FormatOptions) bool {
+	return sqlfmt.IsGenerated(src, options.Language, options.GeneratedMarker)
+}
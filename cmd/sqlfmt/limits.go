@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// limits protects an endpoint receiving untrusted input.
+type limits struct {
+	maxBody int64         // maximum request body size in bytes, or 0 for no limit
+	slots   chan struct{} // one per request allowed to run concurrently, or nil for no limit
+	rate    *rateLimiter  // per-client rate limit, or nil for no limit
+}
+
+// wrap applies the limits to next. Requests over the rate or concurrency limits are rejected
+// with 429 Too Many Requests; bodies over the size limit make reading fail with an
+// *http.MaxBytesError, which handlers report with 413 Content Too Large (see bodyError).
+func (l *limits) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.rate != nil {
+			if ok, retry := l.rate.allow(clientAddr(r), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+				writeProblem(w, http.StatusTooManyRequests, "rate limit exceeded", nil)
+				return
+			}
+		}
+		if l.slots != nil {
+			select {
+			case l.slots <- struct{}{}:
+				defer func() { <-l.slots }()
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeProblem(w, http.StatusTooManyRequests, "too many concurrent requests", nil)
+				return
+			}
+		}
+		if l.maxBody > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, l.maxBody)
+		}
+		next(w, r)
+	}
+}
+
+// optionLimits are the largest values clients may give the numeric options, which would otherwise
+// let a request of a few bytes make the formatter spend seconds producing gigabytes of output.
+var optionLimits = []struct {
+	name  string
+	value func(sqlfmt.PartialFormatOptions) *int
+	max   int
+}{
+	{"tabWidth", func(o sqlfmt.PartialFormatOptions) *int { return o.TabWidth }, 16},
+	{"expressionWidth", func(o sqlfmt.PartialFormatOptions) *int { return o.ExpressionWidth }, 1000},
+	{"linesBetweenQueries", func(o sqlfmt.PartialFormatOptions) *int { return o.LinesBetweenQueries }, 10},
+	{"maxConsecutiveBlankLines", func(o sqlfmt.PartialFormatOptions) *int { return o.MaxConsecutiveBlankLines }, 10},
+	{"predicateChainWidth", func(o sqlfmt.PartialFormatOptions) *int { return o.PredicateChainWidth }, 1000},
+}
+
+// checkOptionLimits reports an error if options, given by a client, set a numeric option out of
+// the range of optionLimits.
+func checkOptionLimits(options sqlfmt.PartialFormatOptions) error {
+	for _, l := range optionLimits {
+		if v := l.value(options); v != nil && (*v < 0 || *v > l.max) {
+			return fmt.Errorf("%s: %d is out of range (want 0 to %d)", l.name, *v, l.max)
+		}
+	}
+	return nil
+}
+
+// bodyError reports an error reading the request body: 413 if it is over the size limit, 400
+// otherwise.
+func bodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, http.StatusRequestEntityTooLarge, "request body too large", err)
+		return
+	}
+	writeProblem(w, http.StatusBadRequest, "invalid request body", err)
+}
+
+// clientAddr identifies the client of r by the IP address of the connection.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter limits the rate of requests of each client with a token bucket.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	clients map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientIdle is how long a client must be idle before its bucket, full by then, is forgotten.
+const clientIdle = 10 * time.Minute
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(max(burst, 1)), clients: make(map[string]*bucket)}
+}
+
+// allow takes a token from the bucket of client. If there is none, it returns false and the time
+// until the next one.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > clientIdle {
+		for k, b := range l.clients {
+			if now.Sub(b.last) > clientIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b := l.clients[client]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
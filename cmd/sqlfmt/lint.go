@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) lint(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("lint", "[flags] [path ...]")
+	common.register(fs)
+	listRules := fs.Bool("rules", false, "list the available rules and exit")
+	fix := fs.Bool("fix", false, "apply automatic fixes, format the result and write it back (standard input is written to standard output)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *listRules {
+		for _, r := range sqlfmt.Rules() {
+			fmt.Fprintf(c.stdout, "%-28s %-5s %s\n", r.Name, sqlfmt.LintConfig{}.SeverityOf(r), r.Description)
+		}
+		return exitOK
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	files, err := inputs(fs.Args())
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	var formatter *sqlfmt.Formatter
+	if *fix {
+		if formatter, err = sqlfmt.NewFormatter(); err != nil {
+			return c.errorf("%v", err)
+		}
+		defer func() {
+			_ = formatter.Close()
+		}()
+	}
+
+	status := exitOK
+	for _, path := range files {
+		src, err := c.readInput(path)
+		if err != nil {
+			status = c.errorf("%v", err)
+			continue
+		}
+		if generated(src, config.FormatOptions) {
+			if *fix && path == stdinPath {
+				fmt.Fprint(c.stdout, src)
+			}
+			continue
+		}
+		if *fix {
+			if src, err = c.fixFile(formatter, path, src, config); err != nil {
+				status = c.errorf("%s: %v", path, err)
+				continue
+			}
+		}
+		diagnostics, err := sqlfmt.Lint(src, config.Language, config.Lint)
+		if err != nil {
+			return c.errorf("%v", err)
+		}
+		report := c.stdout
+		if *fix && path == stdinPath {
+			report = c.stderr
+		}
+		for _, d := range diagnostics {
+			fmt.Fprintf(report, "%s:%s\n", path, d)
+		}
+		if hasErrors(diagnostics) && status == exitOK {
+			status = exitProblems
+		}
+	}
+	return status
+}
+
+// fixFile applies the automatic fixes to src, formats the result and writes it back to path
+// (or to standard output for standard input). It returns the new content.
+func (c *cli) fixFile(formatter *sqlfmt.Formatter, path, src string, config sqlfmt.Config) (string, error) {
+	fixed, _, err := sqlfmt.LintFix(src, config.Language, config.Lint)
+	if err != nil {
+		return "", err
+	}
+	out, err := formatter.Format(fixed, config.FormatOptions)
+	if err != nil {
+		return "", err
+	}
+	out = formattedFile(fixed, out)
+
+	if path == stdinPath {
+		_, err = fmt.Fprint(c.stdout, out)
+		return out, err
+	}
+	if out != src {
+		err = writeOutput(path, []byte(out))
+	}
+	return out, err
+}
+
+// hasErrors reports whether any diagnostic has error severity.
+func hasErrors(diagnostics []sqlfmt.Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == sqlfmt.SeverityError {
+			return true
+		}
+	}
+	return false
+}
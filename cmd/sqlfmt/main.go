@@ -0,0 +1,129 @@
+// Command sqlfmt formats and lints SQL files.
+//
+// Usage:
+//
+//	sqlfmt [format] [flags] [path ...]
+//	sqlfmt check [flags] [path ...]
+//	sqlfmt lint [flags] [path ...]
+//	sqlfmt explain [flags] [path]
+//	sqlfmt deps [flags] [path]
+//	sqlfmt verify [flags] path ...
+//	sqlfmt stats [flags] path ...
+//	sqlfmt bench [flags]
+//	sqlfmt doctor [flags]
+//	sqlfmt dump -dsn DSN [flags]
+//	sqlfmt serve [-addr host:port] [flags]
+//	sqlfmt config schema
+//	sqlfmt completion bash|zsh|fish
+//	sqlfmt man
+//
+// Paths may be files or directories, which are searched recursively for *.sql files
+// (dir/... means the same as dir).
+// Without paths, sqlfmt reads from standard input. Options are read from the nearest
+// .sqlfmt.json in the current directory or its parents, unless -config is given.
+// SQLFMT_* environment variables, such as SQLFMT_KEYWORD_CASE=lower, override the file.
+// With -strict, unknown keys and invalid values in the file are errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0 // success
+	exitProblems = 1 // the command ran but found problems with error severity
+	exitError    = 2 // the command could not run (bad usage, unreadable input, invalid config)
+)
+
+// cli holds the standard streams, so commands can be run against buffers.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	// inspect, if set, receives the usage and flag set of a command run with -h instead of
+	// printing them (see describeCommands).
+	inspect func(usage string, fs *flag.FlagSet)
+}
+
+// command is a sqlfmt subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"format", "format SQL files (default)", (*cli).format},
+		{"check", "check formatting and lint rules, with a summary report", (*cli).check},
+		{"lint", "report lint rule violations", (*cli).lint},
+		{"explain", "show the tokens, statements and formatting decisions behind the output", (*cli).explain},
+		{"deps", "show the dependencies between the objects of a schema script", (*cli).deps},
+		{"verify", "check that formatting a corpus is safe and idempotent", (*cli).verify},
+		{"stats", "report how much formatting would change, to plan adopting it", (*cli).stats},
+		{"bench", "measure cold start, latency and pool throughput on this machine", (*cli).bench},
+		{"doctor", "report the backend, bundle, configuration and a self-test, for bug reports", (*cli).doctor},
+		{"dump", "write the formatted schema of a live database", (*cli).dump},
+		{"serve", "serve formatting over HTTP, with Prometheus metrics", (*cli).serve},
+		{"config", "print the JSON Schema of the configuration file (config schema)", (*cli).config},
+		{"completion", "print a shell completion script (bash, zsh or fish)", (*cli).completion},
+		{"man", "print the manual page in roff format", (*cli).man},
+		{"help", "show this help", (*cli).help},
+	}
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run dispatches args to the matching subcommand, defaulting to format.
+func (c *cli) run(args []string) int {
+	if len(args) > 0 {
+		for _, cmd := range commands {
+			if args[0] == cmd.name {
+				return cmd.run(c, args[1:])
+			}
+		}
+	}
+	return c.format(args)
+}
+
+func (c *cli) help([]string) int {
+	fmt.Fprintln(c.stdout, "Usage: sqlfmt <command> [flags] [path ...]")
+	fmt.Fprintln(c.stdout)
+	fmt.Fprintln(c.stdout, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stdout, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stdout)
+	fmt.Fprintln(c.stdout, `Run "sqlfmt <command> -h" for the flags of a command.`)
+	return exitOK
+}
+
+// newFlagSet returns a flag set for the named command that reports errors to stderr.
+func (c *cli) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("sqlfmt "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		if c.inspect != nil {
+			c.inspect(usage, fs)
+			return
+		}
+		fmt.Fprintf(c.stderr, "Usage: sqlfmt %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// errorf reports an error on stderr and returns exitError.
+func (c *cli) errorf(format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "sqlfmt: "+strings.TrimSuffix(format, "\n")+"\n", args...)
+	return exitError
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) man(args []string) int {
+	fs := c.newFlagSet("man", "")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitError
+	}
+	writeManPage(c.stdout, describeCommands())
+	return exitOK
+}
+
+// roff escapes s for use as text in a man page.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, `-`, `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// writeManPage writes the sqlfmt(1) man page, listing every command, flag and configuration key.
+func writeManPage(w io.Writer, commands []commandInfo) {
+	fmt.Fprintln(w, `.TH SQLFMT 1 "" "sqlfmt" "User Commands"`)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, `sqlfmt \- format and lint SQL files`)
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".B sqlfmt %s\n", cmd.name)
+		if cmd.usage != "" {
+			fmt.Fprintln(w, roff(cmd.usage))
+		}
+		fmt.Fprintln(w, ".br")
+	}
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, "Paths may be files or directories, which are searched recursively for *.sql files.")
+	fmt.Fprintln(w, "Without paths, sqlfmt reads from standard input.")
+	fmt.Fprintln(w, "Without a command, sqlfmt formats its input.")
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".SS %s\n", cmd.name)
+		fmt.Fprintln(w, roff(cmd.summary)+".")
+		for _, f := range cmd.flags {
+			fmt.Fprintln(w, ".TP")
+			if isBoolFlag(f) {
+				fmt.Fprintf(w, ".B \\-%s\n", f.Name)
+			} else {
+				fmt.Fprintf(w, ".BI \\-%s \" %s\"\n", f.Name, f.Name)
+			}
+			fmt.Fprintln(w, roff(f.Usage))
+		}
+	}
+
+	fmt.Fprintln(w, ".SH CONFIGURATION")
+	fmt.Fprintf(w, "Options are read from the nearest %s in the current directory or its parents, unless\n", roff(sqlfmt.ConfigFileName))
+	fmt.Fprintln(w, ".B \\-config")
+	fmt.Fprintln(w, "is given.")
+	fmt.Fprintln(w, "The file is a JSON object with the keys below; lint rules are configured under")
+	fmt.Fprintln(w, `"lint": {"rules": {...}}`)
+	fmt.Fprintln(w, `with the severities off, warn and error (see \fBsqlfmt lint \-rules\fR).`)
+	fmt.Fprintln(w, `"embedded" locates SQL in files of other languages, and "behaviorFingerprint" makes`)
+	fmt.Fprintln(w, `\fBsqlfmt check\fR fail when the formatting behavior differs (see \fBsqlfmt doctor\fR).`)
+	fmt.Fprintln(w, "Each key can also be set with the environment variable shown, which overrides the file.")
+	properties := configProperties()
+	for _, name := range sortedKeys(properties) {
+		p := properties[name]
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".BR %s \" (%s)\"\n", name, p.Type)
+		fmt.Fprintln(w, roff(p.Description))
+		if p.Enum != nil {
+			fmt.Fprintf(w, "One of %s.\n", roff(strings.Join(p.Enum, ", ")))
+		}
+		fmt.Fprintf(w, "Default: %s.\n", roff(fmt.Sprint(p.Default)))
+		fmt.Fprintf(w, "Environment: %s.\n", roff(sqlfmt.EnvVar(name)))
+	}
+
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintf(w, ".B %d\nSuccess.\n", exitOK)
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintf(w, ".B %d\nThe command ran but found problems with error severity.\n", exitProblems)
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintf(w, ".B %d\nThe command could not run (bad usage, unreadable input, invalid configuration).\n", exitError)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// metrics collects the server metrics exposed on /metrics.
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[string]*histogram // by route
+	inputs    *histogram
+	cacheHits uint64
+}
+
+// requestKey identifies a series of sqlfmt_http_requests_total.
+type requestKey struct {
+	route  string
+	status int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[string]*histogram),
+		inputs:    newHistogram(256, 1024, 4096, 16384, 65536, 262144, 1048576),
+	}
+}
+
+// request records a request served by route, the ServeMux pattern, in d.
+func (m *metrics) request(route string, status int, d time.Duration) {
+	if route == "" {
+		route = "other"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{route, status}]++
+	h := m.latencies[route]
+	if h == nil {
+		h = newHistogram(0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5)
+		m.latencies[route] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// input records the size of a query to format.
+func (m *metrics) input(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs.observe(float64(size))
+}
+
+func (m *metrics) cacheHit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits++
+}
+
+// write writes the metrics, with the memory use of the JavaScript contexts, in the Prometheus text
+// exposition format.
+func (m *metrics) write(w io.Writer, contexts []sqlfmt.ContextStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	header(w, "sqlfmt_http_requests_total", "counter", "HTTP requests by route and status code.")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "sqlfmt_http_requests_total{route=%q,code=\"%d\"} %d\n", k.route, k.status, m.requests[k])
+	}
+
+	header(w, "sqlfmt_http_request_duration_seconds", "histogram", "HTTP request latencies by route.")
+	for _, route := range sortedKeys(m.latencies) {
+		m.latencies[route].write(w, "sqlfmt_http_request_duration_seconds", fmt.Sprintf("route=%q,", route))
+	}
+
+	header(w, "sqlfmt_format_input_bytes", "histogram", "Size of the queries to format.")
+	m.inputs.write(w, "sqlfmt_format_input_bytes", "")
+
+	header(w, "sqlfmt_format_cache_hits_total", "counter", "Format requests answered from the result cache.")
+	fmt.Fprintf(w, "sqlfmt_format_cache_hits_total %d\n", m.cacheHits)
+
+	header(w, "sqlfmt_js_memory_bytes", "gauge", "Memory allocated by each JavaScript context.")
+	for i, c := range contexts {
+		fmt.Fprintf(w, "sqlfmt_js_memory_bytes{context=\"%d\"} %d\n", i, c.MemoryUsed)
+	}
+	header(w, "sqlfmt_js_objects", "gauge", "Live objects in each JavaScript context.")
+	for i, c := range contexts {
+		fmt.Fprintf(w, "sqlfmt_js_objects{context=\"%d\"} %d\n", i, c.Objects)
+	}
+}
+
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// histogram counts observations in cumulative buckets, like a Prometheus histogram.
+type histogram struct {
+	bounds []float64 // upper bounds of the buckets, in increasing order
+	counts []uint64  // observations per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.sum += v
+	h.count++
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+}
+
+// write writes the series of the histogram; labels, if not empty, ends with a comma.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, labels, strconv.FormatFloat(bound, 'f', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	if labels != "" {
+		labels = "{" + labels[:len(labels)-1] + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n%s_count%s %d\n", name, labels, h.sum, name, labels, h.count)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// formatFixes returns a FormatRule diagnostic for each statement of src that formatting changes,
+// and for each space between statements that differs from the formatted output, with the
+// replacement as fix, so that reviewers get small suggestions. When applying them does not yield
+// formatted, for instance because a directive comment changes the options of the following
+// statements, a single diagnostic replaces the whole input instead.
+func formatFixes(formatter *sqlfmt.Formatter, src, formatted string, options sqlfmt.FormatOptions, severity sqlfmt.Severity) ([]sqlfmt.Diagnostic, error) {
+	results, err := formatter.FormatStatements(src, options)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		diagnostics []sqlfmt.Diagnostic
+		fixed       strings.Builder
+		last        int
+	)
+	fix := func(start, end int, text, message string) {
+		fixed.WriteString(src[last:start])
+		fixed.WriteString(text)
+		last = end
+		if src[start:end] == text {
+			return
+		}
+		line, column := position(src, start)
+		diagnostics = append(diagnostics, sqlfmt.Diagnostic{
+			Rule:     sqlfmt.FormatRule,
+			Severity: severity,
+			Message:  message,
+			Line:     line,
+			Column:   column,
+			Start:    start,
+			End:      end,
+			Fix:      &sqlfmt.Fix{Start: start, End: end, Text: text},
+		})
+	}
+	separator := strings.Repeat("\n", options.BlankLinesBetweenQueries()+1)
+	for i, r := range results {
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		if i == 0 {
+			fix(0, r.Start, "", "unexpected space before the first statement")
+		} else {
+			fix(last, r.Start, separator, "wrong spacing between statements")
+		}
+		fix(r.Start, r.End, r.Formatted, "statement is not formatted")
+	}
+	fix(last, len(src), "\n", "wrong spacing after the last statement")
+	if fixed.String() == formatted {
+		return diagnostics, nil
+	}
+	return []sqlfmt.Diagnostic{{
+		Rule:     sqlfmt.FormatRule,
+		Severity: severity,
+		Message:  "input is not formatted",
+		Line:     1,
+		Column:   1,
+		End:      len(src),
+		Fix:      &sqlfmt.Fix{End: len(src), Text: formatted},
+	}}, nil
+}
+
+// position returns the line and column (1-based, in bytes) of the offset off in src.
+func position(src string, off int) (line, column int) {
+	return 1 + strings.Count(src[:off], "\n"), off - strings.LastIndexByte(src[:off], '\n')
+}
+
+// rdjsonResult is a report in the Reviewdog Diagnostic Format, read by reviewdog -f=rdjson.
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+}
+
+type rdjsonDiagnostic struct {
+	Message     string             `json:"message"`
+	Location    rdjsonLocation     `json:"location"`
+	Severity    string             `json:"severity"`
+	Code        rdjsonCode         `json:"code"`
+	Suggestions []rdjsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdjsonLocation struct {
+	Path  string      `json:"path"`
+	Range rdjsonRange `json:"range"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition `json:"start"`
+	End   rdjsonPosition `json:"end"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+type rdjsonSuggestion struct {
+	Range rdjsonRange `json:"range"`
+	Text  string      `json:"text"`
+}
+
+// add records the diagnostics of the file at path, whose content is src.
+func (r *rdjsonResult) add(path, src string, diagnostics []sqlfmt.Diagnostic) {
+	span := func(start, end int) rdjsonRange {
+		var rg rdjsonRange
+		rg.Start.Line, rg.Start.Column = position(src, start)
+		rg.End.Line, rg.End.Column = position(src, end)
+		return rg
+	}
+	for _, d := range diagnostics {
+		diagnostic := rdjsonDiagnostic{
+			Message:  d.Message,
+			Location: rdjsonLocation{Path: path, Range: span(d.Start, max(d.End, d.Start))},
+			Severity: strings.ToUpper(string(d.Severity)),
+			Code:     rdjsonCode{Value: d.Rule},
+		}
+		if diagnostic.Severity == "WARN" {
+			diagnostic.Severity = "WARNING"
+		}
+		if d.Fix != nil {
+			diagnostic.Suggestions = []rdjsonSuggestion{{Range: span(d.Fix.Start, d.Fix.End), Text: d.Fix.Text}}
+		}
+		r.Diagnostics = append(r.Diagnostics, diagnostic)
+	}
+}
+
+// write writes the report as JSON.
+func (r *rdjsonResult) write(w io.Writer) error {
+	r.Source.Name = "sqlfmt"
+	if r.Diagnostics == nil {
+		r.Diagnostics = []rdjsonDiagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
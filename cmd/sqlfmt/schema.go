@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The functions below read the schema of a database as a list of DDL statements. Objects are
+// ordered by kind and name so that dumps of the same schema are identical.
+
+// sqliteObjects reads the statements stored in sqlite_master. Internal objects and indexes created
+// implicitly for constraints, which have no SQL, are skipped.
+func sqliteObjects(db *sql.DB) ([]schemaObject, error) {
+	rows, err := db.Query(`
+		SELECT type, name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY CASE type WHEN 'table' THEN 1 WHEN 'view' THEN 2 WHEN 'index' THEN 3 ELSE 4 END, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []schemaObject
+	for rows.Next() {
+		var kind, name, ddl string
+		if err := rows.Scan(&kind, &name, &ddl); err != nil {
+			return nil, err
+		}
+		objects = append(objects, schemaObject{kind: pluralKind(kind), name: name, ddl: ddl})
+	}
+	return objects, rows.Err()
+}
+
+// autoIncrementRegex matches the AUTO_INCREMENT table option, whose value changes with the data.
+var autoIncrementRegex = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// mysqlObjects reads the tables and views of the current database with SHOW CREATE.
+func mysqlObjects(db *sql.DB) ([]schemaObject, error) {
+	rows, err := db.Query(`
+		SELECT table_name, table_type FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		ORDER BY table_type, table_name`)
+	if err != nil {
+		return nil, err
+	}
+	type table struct{ name, kind string }
+	var tables []table
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.name, &t.kind); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var objects []schemaObject
+	for _, t := range tables {
+		kind, show := "tables", "TABLE"
+		if t.kind == "VIEW" {
+			kind, show = "views", "VIEW"
+		}
+		ddl, err := showCreate(db, show, t.name)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, schemaObject{kind: kind, name: t.name, ddl: autoIncrementRegex.ReplaceAllString(ddl, "")})
+	}
+	return objects, nil
+}
+
+// showCreate returns the statement reported by SHOW CREATE TABLE or SHOW CREATE VIEW, which is
+// in the second column of the result.
+func showCreate(db *sql.DB, what, name string) (string, error) {
+	rows, err := db.Query(fmt.Sprintf("SHOW CREATE %s `%s`", what, strings.ReplaceAll(name, "`", "``")))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("SHOW CREATE %s %s returned no rows", what, name)
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	if len(values) < 2 {
+		return "", fmt.Errorf("SHOW CREATE %s %s returned %d columns", what, name, len(values))
+	}
+	return values[1].String, nil
+}
+
+// postgresExcludedSchemas filters out the system schemas in the queries of postgresObjects.
+const postgresExcludedSchemas = `n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_toast%'`
+
+// postgresObjects rebuilds the DDL of tables, views and indexes from the system catalogs, since
+// PostgreSQL has no SHOW CREATE. Indexes backing constraints are part of their table.
+func postgresObjects(db *sql.DB) ([]schemaObject, error) {
+	rows, err := db.Query(`
+		SELECT c.oid, quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relkind,
+			CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid, true) END
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm') AND ` + postgresExcludedSchemas + `
+		ORDER BY CASE WHEN c.relkind IN ('r', 'p') THEN 1 ELSE 2 END, 2`)
+	if err != nil {
+		return nil, err
+	}
+	type relation struct {
+		oid        int64
+		name, kind string
+		viewdef    sql.NullString
+	}
+	var relations []relation
+	for rows.Next() {
+		var r relation
+		if err := rows.Scan(&r.oid, &r.name, &r.kind, &r.viewdef); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		relations = append(relations, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var objects []schemaObject
+	for _, r := range relations {
+		switch r.kind {
+		case "v":
+			objects = append(objects, schemaObject{kind: "views", name: r.name, ddl: "CREATE VIEW " + r.name + " AS " + r.viewdef.String})
+		case "m":
+			objects = append(objects, schemaObject{kind: "views", name: r.name, ddl: "CREATE MATERIALIZED VIEW " + r.name + " AS " + r.viewdef.String})
+		default:
+			ddl, err := postgresTable(db, r.oid, r.name)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, schemaObject{kind: "tables", name: r.name, ddl: ddl})
+		}
+	}
+
+	indexes, err := db.Query(`
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), pg_get_indexdef(c.oid)
+		FROM pg_index x
+			JOIN pg_class c ON c.oid = x.indexrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE ` + postgresExcludedSchemas + `
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint k WHERE k.conindid = c.oid)
+		ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer indexes.Close()
+	for indexes.Next() {
+		var name, ddl string
+		if err := indexes.Scan(&name, &ddl); err != nil {
+			return nil, err
+		}
+		objects = append(objects, schemaObject{kind: "indexes", name: name, ddl: ddl})
+	}
+	return objects, indexes.Err()
+}
+
+// postgresTable returns the CREATE TABLE statement for the table with the given oid: its columns in
+// order, followed by its constraints ordered by name.
+func postgresTable(db *sql.DB, oid int64, name string) (string, error) {
+	var defs []string
+
+	columns, err := db.Query(`
+		SELECT quote_ident(a.attname), format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid)
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, oid)
+	if err != nil {
+		return "", err
+	}
+	for columns.Next() {
+		var (
+			column, typ string
+			notNull     bool
+			def         sql.NullString
+		)
+		if err := columns.Scan(&column, &typ, &notNull, &def); err != nil {
+			columns.Close()
+			return "", err
+		}
+		d := column + " " + typ
+		if def.Valid {
+			d += " DEFAULT " + def.String
+		}
+		if notNull {
+			d += " NOT NULL"
+		}
+		defs = append(defs, d)
+	}
+	columns.Close()
+	if err := columns.Err(); err != nil {
+		return "", err
+	}
+
+	constraints, err := db.Query(`
+		SELECT quote_ident(conname), pg_get_constraintdef(oid)
+		FROM pg_constraint
+		WHERE conrelid = $1
+		ORDER BY conname`, oid)
+	if err != nil {
+		return "", err
+	}
+	defer constraints.Close()
+	for constraints.Next() {
+		var constraint, def string
+		if err := constraints.Scan(&constraint, &def); err != nil {
+			return "", err
+		}
+		defs = append(defs, "CONSTRAINT "+constraint+" "+def)
+	}
+	if err := constraints.Err(); err != nil {
+		return "", err
+	}
+
+	return "CREATE TABLE " + name + " (" + strings.Join(defs, ", ") + ")", nil
+}
+
+// pluralKind returns the directory name for objects of the given sqlite_master type.
+func pluralKind(kind string) string {
+	if kind == "index" {
+		return "indexes"
+	}
+	return kind + "s"
+}
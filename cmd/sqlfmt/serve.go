@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) serve(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("serve", "[flags]")
+	common.register(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	poolSize := fs.Int("pool-size", 0, "maximum number of JavaScript contexts (default GOMAXPROCS)")
+	minPoolSize := fs.Int("min-pool-size", 0, "number of JavaScript contexts kept when idle")
+	idleTimeout := fs.Duration("idle-timeout", 0, "release JavaScript contexts unused for this long, down to -min-pool-size (0 to keep them)")
+	cacheSize := fs.Int("cache", 0, "number of formatted results kept in memory (0 to disable the cache)")
+	prewarm := fs.Int("prewarm", 0, "number of JavaScript contexts to warm up before reporting ready (default GOMAXPROCS)")
+	readHeaderTimeout := fs.Duration("read-header-timeout", 10*time.Second, "time allowed to read the headers of a request")
+	readTimeout := fs.Duration("read-timeout", time.Minute, "time allowed to read a request, including a /stream body")
+	keepAlive := fs.Duration("keepalive-timeout", 2*time.Minute, "time an idle keep-alive connection is kept open")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "time allowed for in-flight requests to complete on SIGTERM")
+	maxBody := fs.Int64("max-body", 1<<20, "maximum size of a request body in bytes (0 for no limit)")
+	maxConcurrent := fs.Int("max-concurrent", 64, "maximum number of format requests handled at once (0 for no limit)")
+	rate := fs.Float64("rate", 0, "maximum format requests per second per client IP address (0 for no limit)")
+	burst := fs.Int("burst", 20, "number of requests a client can make at once above -rate")
+	tokenFile := fs.String("token-file", "", "require a bearer token from this file (one per line; "+tokenEnv+" adds one)")
+	tlsCert := fs.String("tls-cert", "", "serve HTTPS with this certificate file")
+	tlsKey := fs.String("tls-key", "", "private key file of -tls-cert")
+	clientCA := fs.String("client-ca", "", "require client certificates signed by the CAs in this file (mutual TLS)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	tokens, err := loadTokens(*tokenFile)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	tlsConfig, err := serverTLS(*tlsCert, *tlsKey, *clientCA)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	formatter, err := sqlfmt.NewFormatter(
+		sqlfmt.WithPoolSize(*poolSize),
+		sqlfmt.WithMinPoolSize(*minPoolSize),
+		sqlfmt.WithIdleTimeout(*idleTimeout),
+		sqlfmt.WithResultCache(*cacheSize),
+	)
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+
+	s := &server{formatter: formatter, options: config.FormatOptions, metrics: newMetrics()}
+	s.limits.maxBody = *maxBody
+	if *maxConcurrent > 0 {
+		s.limits.slots = make(chan struct{}, *maxConcurrent)
+	}
+	if *rate > 0 {
+		s.limits.rate = newRateLimiter(*rate, *burst)
+	}
+	s.auth = newAuthenticator(tokens, *clientCA != "")
+	srv := &http.Server{
+		Addr:      *addr,
+		Handler:   s.handler(),
+		TLSConfig: tlsConfig,
+		// Without these, slow clients, and /stream bodies that never end, hold their connection and
+		// their -max-concurrent slot for as long as they like.
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		IdleTimeout:       *keepAlive,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		n := *prewarm
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		if err := formatter.Prewarm(n); err != nil {
+			c.errorf("warming up: %v", err)
+			return
+		}
+		s.ready.Store(true)
+	}()
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		// Report not ready while draining; Shutdown stops accepting connections and waits for the
+		// requests in flight to complete.
+		s.stopping.Store(true)
+		timeout, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		shutdown <- srv.Shutdown(timeout)
+	}()
+
+	fmt.Fprintf(c.stderr, "sqlfmt: listening on %s\n", *addr)
+	if tlsConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return c.errorf("%v", err)
+	}
+	if err := <-shutdown; err != nil {
+		return c.errorf("shutting down: %v", err)
+	}
+	return exitOK
+}
+
+// server serves formatting requests over HTTP:
+//
+//	POST /format   formats {"sql": "...", "options": {...}}, the options overriding the configuration
+//	POST /stream   formats the SQL of the body statement by statement as it arrives (see stream)
+//	GET  /metrics  metrics in the Prometheus text format
+//	GET  /healthz  liveness: succeeds while the process serves requests
+//	GET  /readyz   readiness: succeeds once the JavaScript contexts are warmed up, until shutdown
+//
+// With tokens or a client CA configured, all but the probes require an Authorization: Bearer
+// header or a client certificate.
+type server struct {
+	formatter *sqlfmt.Formatter
+	options   sqlfmt.FormatOptions
+	metrics   *metrics
+	limits    limits         // applied to POST /format and /stream
+	auth      *authenticator // nil if no authentication is required
+	ready     atomic.Bool    // contexts warmed up
+	stopping  atomic.Bool    // shutdown started
+}
+
+// formatRequest is the body of a POST /format request.
+type formatRequest struct {
+	SQL     string                      `json:"sql"`
+	Options sqlfmt.PartialFormatOptions `json:"options"`
+}
+
+// formatResponse is the body of a successful POST /format response.
+type formatResponse struct {
+	Formatted string   `json:"formatted"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// problem is an error response, in the format of RFC 9457 (problem details for HTTP APIs).
+type problem struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /format", s.auth.wrap(s.limits.wrap(s.format)))
+	mux.HandleFunc("POST /stream", s.auth.wrap(s.limits.wrap(s.stream)))
+	mux.HandleFunc("GET /metrics", s.auth.wrap(s.writeMetrics))
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	return s.instrument(mux)
+}
+
+// instrument records the count and latency of the requests handled by next.
+func (s *server) instrument(next *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		_, pattern := next.Handler(r)
+		s.metrics.request(pattern, rec.status, time.Since(start))
+	})
+}
+
+func (s *server) format(w http.ResponseWriter, r *http.Request) {
+	var req formatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	s.metrics.input(len(req.SQL))
+	if err := checkOptionLimits(req.Options); err != nil {
+		writeProblem(w, http.StatusBadRequest, "invalid options", err)
+		return
+	}
+
+	result, err := s.formatter.FormatWithResult(req.SQL, sqlfmt.Merge(s.options, req.Options))
+	switch {
+	case errors.Is(err, sqlfmt.ErrFormatterClosed):
+		writeProblem(w, http.StatusServiceUnavailable, "server is shutting down", nil)
+		return
+	case errors.Is(err, sqlfmt.ErrEmptySQL):
+		writeProblem(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	case err != nil:
+		writeProblem(w, http.StatusUnprocessableEntity, "cannot format SQL", err)
+		return
+	}
+	if result.CacheHit {
+		s.metrics.cacheHit()
+	}
+
+	resp := formatResponse{Formatted: result.Output}
+	for _, warning := range result.Warnings {
+		resp.Warnings = append(resp.Warnings, warning.String())
+	}
+	writeJSON(w, http.StatusOK, "application/json", resp)
+}
+
+func (s *server) healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, "application/json", map[string]string{"status": "ok"})
+}
+
+func (s *server) readyz(w http.ResponseWriter, _ *http.Request) {
+	switch {
+	case s.stopping.Load():
+		writeProblem(w, http.StatusServiceUnavailable, "server is shutting down", nil)
+	case !s.ready.Load():
+		writeProblem(w, http.StatusServiceUnavailable, "warming up", nil)
+	default:
+		writeJSON(w, http.StatusOK, "application/json", map[string]string{"status": "ready"})
+	}
+}
+
+func (s *server) writeMetrics(w http.ResponseWriter, _ *http.Request) {
+	stats, err := s.formatter.ContextStats()
+	if err != nil {
+		writeProblem(w, http.StatusServiceUnavailable, "server is shutting down", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.write(w, stats)
+}
+
+// writeProblem writes an error response. err, if not nil, is given as detail.
+func writeProblem(w http.ResponseWriter, status int, title string, err error) {
+	p := problem{Title: title, Status: status}
+	if err != nil {
+		p.Detail = err.Error()
+	}
+	writeJSON(w, status, "application/problem+json", p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
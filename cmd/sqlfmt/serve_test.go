@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func TestServeBodyLimit(t *testing.T) {
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer formatter.Close()
+	s := &server{formatter: formatter, options: sqlfmt.DefaultFormatOptions, metrics: newMetrics()}
+	s.limits.maxBody = 64
+	handler := s.handler()
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"sql": "select 1"}`, http.StatusOK},
+		{`{"sql": "select ` + strings.Repeat("a, ", 100) + `b"}`, http.StatusRequestEntityTooLarge},
+		{`{"sql": `, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/format", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%.20s...: got status %d, want %d (%s)", tt.body, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// dialectMarkers are syntax that, found in a file, suggests the dialect it is written in.
+var dialectMarkers = map[sqlfmt.LanguageOption][]*regexp.Regexp{
+	sqlfmt.LanguagePostgreSQL:  markers(`::\w`, `\$\d+`, `\$\w*\$`, `\bILIKE\b`, `\bRETURNING\b`, `\bJSONB\b`, `\bBIGSERIAL\b|\bSERIAL\b`),
+	sqlfmt.LanguageMySQL:       markers("`", `\bAUTO_INCREMENT\b`, `\bENGINE\s*=`, `/\*!\d*`, `\bUNSIGNED\b`, `\bLIMIT\s+\d+\s*,\s*\d+`),
+	sqlfmt.LanguageTransactSQL: markers(`\[\w+\]`, `\bTOP\s*\(?\d`, `(?m)^\s*GO\s*$`, `\bNVARCHAR\b`, `\bIDENTITY\s*\(`, `@@\w+`, `\bGETDATE\s*\(`),
+	sqlfmt.LanguageBigQuery:    markers(`\bSTRUCT\s*<`, `\bARRAY\s*<`, `\bSAFE_CAST\b`, "`[\\w-]+\\.\\w+\\.\\w+`", `\bSELECT\s+AS\s+STRUCT\b`),
+	sqlfmt.LanguageSnowflake:   markers(`\bVARIANT\b`, `\bFLATTEN\s*\(`, `\bCREATE\s+(OR\s+REPLACE\s+)?STAGE\b`, `\bCOPY\s+INTO\b`),
+	sqlfmt.LanguagePLSQL:       markers(`\bVARCHAR2\b`, `\bNUMBER\s*\(`, `\bNVL\s*\(`, `\bSYSDATE\b`, `\bFROM\s+DUAL\b`, `\bROWNUM\b`),
+	sqlfmt.LanguageSQLite:      markers(`\bAUTOINCREMENT\b`, `\bPRAGMA\b`, `\bWITHOUT\s+ROWID\b`),
+}
+
+func markers(patterns ...string) []*regexp.Regexp {
+	var list []*regexp.Regexp
+	for _, p := range patterns {
+		list = append(list, regexp.MustCompile(`(?i)`+p))
+	}
+	return list
+}
+
+// detectDialect returns the dialect whose markers src has the most of, or "" if none stands out.
+// It is a heuristic for reports, not a parse: comments and strings count too.
+func detectDialect(src string) sqlfmt.LanguageOption {
+	var best sqlfmt.LanguageOption
+	bestScore, tie := 0, false
+	for lang, list := range dialectMarkers {
+		score := 0
+		for _, re := range list {
+			if re.MatchString(src) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// fileStats is what stats found formatting a file.
+type fileStats struct {
+	path       string
+	changed    bool  // the formatted file differs
+	err        error // the error formatting the file, if any
+	statements int
+	rewritten  int // statements that formatting changes
+	churn      int // bytes of the input in the statements that formatting changes
+	lines      int // lines of the input in the statements that formatting changes
+	dialect    sqlfmt.LanguageOption
+}
+
+func (c *cli) stats(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("stats", "[flags] path ...")
+	common.register(fs)
+	top := fs.Int("top", 10, "number of files listed as the largest offenders")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	files, err := collectFiles(fs.Args())
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+
+	status := exitOK
+	var (
+		all     []fileStats
+		skipped int
+	)
+	for _, path := range files {
+		src, err := c.readInput(path)
+		if err != nil {
+			status = c.errorf("%v", err)
+			continue
+		}
+		if generated(src, config.FormatOptions) {
+			skipped++
+			continue
+		}
+		s, err := statsSource(formatter, src, config.FormatOptions)
+		if err != nil {
+			status = c.errorf("%s: %v", path, err)
+			continue
+		}
+		s.path = path
+		all = append(all, s)
+	}
+	writeStats(c.stdout, all, skipped, *top)
+	return status
+}
+
+// statsSource formats src as a whole, to tell whether the file would change, and statement by
+// statement, to measure how much of it would.
+func statsSource(formatter *sqlfmt.Formatter, src string, options sqlfmt.FormatOptions) (fileStats, error) {
+	s := fileStats{dialect: detectDialect(src)}
+	out, err := formatter.Format(src, options)
+	if err != nil {
+		s.err = err
+	} else {
+		s.changed = formattedFile(src, out) != src
+	}
+	results, err := formatter.FormatStatements(src, options)
+	if err != nil {
+		return s, err
+	}
+	for _, r := range results {
+		s.statements++
+		if r.Err == nil && src[r.Start:r.End] == r.Formatted {
+			continue
+		}
+		s.rewritten++
+		s.churn += r.End - r.Start
+		s.lines += strings.Count(src[r.Start:r.End], "\n") + 1
+	}
+	return s, nil
+}
+
+// writeStats prints the report of the stats command. skipped is the number of generated files.
+func writeStats(w io.Writer, all []fileStats, skipped, top int) {
+	var changed, failed, statements, rewritten, churn, lines int
+	dialects := make(map[sqlfmt.LanguageOption]int)
+	for _, s := range all {
+		switch {
+		case s.err != nil:
+			failed++
+		case s.changed:
+			changed++
+		}
+		statements += s.statements
+		rewritten += s.rewritten
+		churn += s.churn
+		lines += s.lines
+		dialects[s.dialect]++
+	}
+	unchanged := len(all) - changed - failed
+
+	fmt.Fprintf(w, "Files:       %s, %d would change (%s), %d already formatted, %d failed to format\n",
+		plural(len(all), "file"), changed, percent(changed, len(all)), unchanged, failed)
+	if skipped > 0 {
+		fmt.Fprintf(w, "             %s marked as generated, skipped\n", plural(skipped, "file"))
+	}
+	fmt.Fprintf(w, "Statements:  %s, %d would change (%s)\n", plural(statements, "statement"), rewritten, percent(rewritten, statements))
+	fmt.Fprintf(w, "Churn:       %s in %s of the statements that would change\n", plural(churn, "byte"), plural(lines, "line"))
+
+	fmt.Fprintln(w, "\nDialects detected from their syntax:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	langs := make([]sqlfmt.LanguageOption, 0, len(dialects))
+	for lang := range dialects {
+		langs = append(langs, lang)
+	}
+	slices.SortFunc(langs, func(a, b sqlfmt.LanguageOption) int {
+		return cmp.Or(dialects[b]-dialects[a], strings.Compare(string(a), string(b)))
+	})
+	for _, lang := range langs {
+		name := string(lang)
+		if lang == "" {
+			name = "undetermined"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, plural(dialects[lang], "file"))
+	}
+	tw.Flush()
+
+	offenders := slices.Clone(all)
+	offenders = slices.DeleteFunc(offenders, func(s fileStats) bool { return s.rewritten == 0 })
+	if len(offenders) == 0 || top <= 0 {
+		return
+	}
+	slices.SortStableFunc(offenders, func(a, b fileStats) int { return b.churn - a.churn })
+	fmt.Fprintln(w, "\nLargest offenders, by bytes in statements that would change:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range offenders[:min(top, len(offenders))] {
+		note := ""
+		if s.err != nil {
+			note = "\t(fails to format)"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d of %s%s\n", s.path, plural(s.churn, "byte"), s.rewritten, plural(s.statements, "statement"), note)
+	}
+	tw.Flush()
+}
+
+// percent formats n as a percentage of total.
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(total))
+}
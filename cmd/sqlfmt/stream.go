@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// streamResult is a line of the response to POST /stream.
+type streamResult struct {
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	Kind      string `json:"kind,omitempty"`
+	Formatted string `json:"formatted,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// stream formats the SQL of the request body as it arrives. Each statement is answered, as soon
+// as its terminating semicolon is received, with a line of newline-delimited JSON; the statement
+// after the last semicolon is answered when the body ends. The options override the
+// configuration and are given as JSON in the options query parameter. An error reading the body,
+// such as exceeding the size limit, ends the response with a line holding only the error.
+func (s *server) stream(w http.ResponseWriter, r *http.Request) {
+	var partial sqlfmt.PartialFormatOptions
+	if raw := r.URL.Query().Get("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &partial); err != nil {
+			writeProblem(w, http.StatusBadRequest, "invalid options", err)
+			return
+		}
+	}
+	if err := checkOptionLimits(partial); err != nil {
+		writeProblem(w, http.StatusBadRequest, "invalid options", err)
+		return
+	}
+	options := sqlfmt.Merge(s.options, partial)
+
+	// Answer statements while the client is still sending the body.
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil {
+		writeProblem(w, http.StatusInternalServerError, "streaming not supported", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	err := s.formatter.FormatStream(r.Body, options, func(result sqlfmt.StatementResult) error {
+		s.metrics.input(result.End - result.Start)
+		line := streamResult{Start: result.Start, End: result.End, Kind: result.Kind, Formatted: result.Formatted}
+		if result.Err != nil {
+			line.Error = result.Err.Error()
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err != nil {
+		_ = enc.Encode(streamResult{Error: err.Error()})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func (c *cli) verify(args []string) int {
+	var common commonFlags
+	fs := c.newFlagSet("verify", "[flags] path ...")
+	common.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	config, err := common.load()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	files, err := collectFiles(fs.Args())
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+
+	formatter, err := sqlfmt.NewFormatter()
+	if err != nil {
+		return c.errorf("%v", err)
+	}
+	defer func() {
+		_ = formatter.Close()
+	}()
+
+	options := config.FormatOptions
+	options.VerifyTokens = true
+	options.VerifyIdempotent = true
+	status := exitOK
+	verified, failed := 0, 0
+	for _, path := range files {
+		src, err := c.readInput(path)
+		if err != nil {
+			status = c.errorf("%v", err)
+			continue
+		}
+		if generated(src, options) {
+			continue
+		}
+		verified++
+		if problem := verifySource(formatter, src, options); problem != "" {
+			fmt.Fprintf(c.stdout, "%s: %s\n", path, problem)
+			failed++
+		}
+	}
+	fmt.Fprintf(c.stdout, "%s verified, %d failed\n", plural(verified, "file"), failed)
+	if failed > 0 && status == exitOK {
+		status = exitProblems
+	}
+	return status
+}
+
+// verifySource formats src and describes the problem found: an error formatting it, output that
+// does not hold the tokens of the input (options.VerifyTokens is set), or output that changes when
+// formatted again (options.VerifyIdempotent is set). It returns "" if there is none.
+func verifySource(formatter *sqlfmt.Formatter, src string, options sqlfmt.FormatOptions) string {
+	_, err := formatter.Format(src, options)
+	var unstable *sqlfmt.UnstableFormatError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &unstable) && unstable.Err != nil:
+		return "not idempotent: formatting the output fails: " + unstable.Err.Error()
+	case errors.As(err, &unstable):
+		line, a, b := firstDifference(unstable.Output, unstable.Reformatted)
+		return fmt.Sprintf("not idempotent: line %d of the output, %q, becomes %q when formatted again", line, a, b)
+	case errors.Is(err, sqlfmt.ErrUnsafeFormat):
+		return "unsafe: " + err.Error()
+	}
+	return "error: " + err.Error()
+}
+
+// firstDifference returns the number of the first line (1-based) that differs between a and b, and
+// that line in each.
+func firstDifference(a, b string) (int, string, string) {
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := range max(len(aLines), len(bLines)) {
+		var x, y string
+		if i < len(aLines) {
+			x = aLines[i]
+		}
+		if i < len(bLines) {
+			y = bLines[i]
+		}
+		if x != y || i >= len(aLines) || i >= len(bLines) {
+			return i + 1, x, y
+		}
+	}
+	return 0, "", ""
+}
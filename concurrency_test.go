@@ -0,0 +1,167 @@
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// The concurrency contract: a Formatter, and the package-level functions, can be used from any
+// number of goroutines at once, and Close may race with calls in flight, which then either
+// complete or fail with ErrFormatterClosed. Run with go test -race.
+
+const (
+	raceGoroutines = 8
+	raceCalls      = 20
+)
+
+// raceQuery returns a query and its formatted version with the default options, distinct per
+// goroutine and call so that results handed to the wrong caller are noticed.
+func raceQuery(g, i int) (string, string) {
+	return fmt.Sprintf("select c%d_%d from t", g, i), fmt.Sprintf("SELECT\n    c%d_%d\nFROM\n    t", g, i)
+}
+
+// hammer calls format and formatMany from raceGoroutines goroutines and reports the results that
+// are wrong, and the errors that closed does not allow.
+func hammer(t *testing.T, format func(string) (string, error), formatMany func([]string) ([]string, error), closed func(error) bool) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for g := range raceGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range raceCalls {
+				sql, want := raceQuery(g, i)
+				if i%2 == 0 {
+					got, err := format(sql)
+					if err != nil {
+						if !closed(err) {
+							t.Errorf("Format: %v", err)
+						}
+						continue
+					}
+					if got != want {
+						t.Errorf("Format(%q) = %q, want %q", sql, got, want)
+					}
+					continue
+				}
+				next, wantNext := raceQuery(g, i+raceCalls)
+				got, err := formatMany([]string{sql, next})
+				if err != nil {
+					if !closed(err) {
+						t.Errorf("FormatMany: %v", err)
+					}
+					continue
+				}
+				if len(got) != 2 || got[0] != want || got[1] != wantNext {
+					t.Errorf("FormatMany(%q, %q) = %q, want %q, %q", sql, next, got, want, wantNext)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFormatterConcurrentUse(t *testing.T) {
+	f, err := NewFormatter(WithPoolSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	hammer(t,
+		func(sql string) (string, error) { return f.Format(sql, DefaultFormatOptions) },
+		func(sqls []string) ([]string, error) { return f.FormatMany(sqls, DefaultFormatOptions) },
+		func(error) bool { return false })
+}
+
+func TestFormatterCloseDuringUse(t *testing.T) {
+	f, err := NewFormatter(WithPoolSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	isClosed := func(err error) bool { return errors.Is(err, ErrFormatterClosed) }
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		hammer(t,
+			func(sql string) (string, error) { return f.Format(sql, DefaultFormatOptions) },
+			func(sqls []string) ([]string, error) { return f.FormatMany(sqls, DefaultFormatOptions) },
+			isClosed)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = f.Format("select 1", DefaultFormatOptions) // let the calls get going
+		for range 3 {
+			if err := f.Close(); err != nil && !isClosed(err) {
+				t.Errorf("Close: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if _, err := f.Format("select 1", DefaultFormatOptions); !isClosed(err) {
+		t.Errorf("Format after Close: got %v, want ErrFormatterClosed", err)
+	}
+	if _, err := f.FormatMany([]string{"select 1"}, DefaultFormatOptions); !isClosed(err) {
+		t.Errorf("FormatMany after Close: got %v, want ErrFormatterClosed", err)
+	}
+}
+
+func TestCloneCloseDuringUse(t *testing.T) {
+	f, err := NewFormatter(WithPoolSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Clones closed while the original is in use must not affect it.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range raceGoroutines {
+			clone, err := f.Clone()
+			if err != nil {
+				t.Errorf("Clone: %v", err)
+				return
+			}
+			_, _ = clone.Format("select 1", DefaultFormatOptions)
+			if err := clone.Close(); err != nil {
+				t.Errorf("Close of a clone: %v", err)
+			}
+		}
+	}()
+	hammer(t,
+		func(sql string) (string, error) { return f.Format(sql, DefaultFormatOptions) },
+		func(sqls []string) ([]string, error) { return f.FormatMany(sqls, DefaultFormatOptions) },
+		func(error) bool { return false })
+	wg.Wait()
+}
+
+func TestPackageLevelConcurrentUse(t *testing.T) {
+	// Formatters created and closed alongside must not disturb the shared one.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 2 {
+			f, err := NewFormatter(WithPoolSize(1))
+			if err != nil {
+				t.Errorf("NewFormatter: %v", err)
+				return
+			}
+			_, _ = f.Format("select 1", DefaultFormatOptions)
+			if err := f.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		}
+	}()
+	hammer(t,
+		func(sql string) (string, error) { return Format(sql, DefaultFormatOptions) },
+		func(sqls []string) ([]string, error) { return FormatMany(sqls, DefaultFormatOptions) },
+		func(error) bool { return false })
+	wg.Wait()
+}
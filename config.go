@@ -0,0 +1,381 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ConfigFileName is the name of the configuration file looked up by FindConfig.
+const ConfigFileName = ".sqlfmt.json"
+
+// Config is the content of a configuration file. Formatting options are given at the top level,
+// using the same keys as FormatOptions; lint settings live under "lint":
+//
+//	{
+//	    "language": "postgresql",
+//	    "tabWidth": 2,
+//	    "lint": {"rules": {"no-select-star": false}}
+//	}
+type Config struct {
+	FormatOptions
+	// Lint selects the rules run by the lint command.
+	Lint LintConfig `json:"lint,omitempty"`
+	// Embedded locates SQL in files of other languages, for the format and check commands.
+	Embedded []EmbeddedPattern `json:"embedded,omitempty"`
+	// BehaviorFingerprint, if set, pins the formatting behavior: the check command fails unless it
+	// is the BehaviorFingerprint of the formatter, so that an upgrade changing the output is noticed
+	// and the code base reformatted in a change of its own.
+	BehaviorFingerprint string `json:"behaviorFingerprint,omitempty"`
+}
+
+// EmbeddedPatterns returns the patterns of c.Embedded whose files include the file at path.
+func (c Config) EmbeddedPatterns(path string) []EmbeddedPattern {
+	var patterns []EmbeddedPattern
+	for _, p := range c.Embedded {
+		if p.MatchFile(path) {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// DefaultConfig returns the configuration used when no configuration file is found.
+func DefaultConfig() Config {
+	return Config{FormatOptions: DefaultFormatOptions}
+}
+
+// LoadConfig reads the configuration file at path. Options missing from the file keep their
+// values from DefaultConfig.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses the content of a configuration file. Options missing from data keep their
+// values from DefaultConfig; as with Merge, a linesBetweenQueries of 0 puts statements on
+// consecutive lines.
+func ParseConfig(data []byte) (Config, error) {
+	config := DefaultConfig()
+	var options PartialFormatOptions
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return Config{}, fmt.Errorf("parsing config: %w", err)
+	}
+	config.FormatOptions = Merge(DefaultFormatOptions, options)
+	return config, nil
+}
+
+// LoadConfigStrict is like LoadConfig but decodes the file with ParseConfigStrict. The Path of a
+// returned *ConfigError is set to path.
+func LoadConfigStrict(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config: %w", err)
+	}
+	config, err := ParseConfigStrict(data)
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		configErr.Path = path
+	}
+	return config, err
+}
+
+// ParseConfigStrict is like ParseConfig but fails with a *ConfigError on the first unknown key,
+// value of the wrong type or enumerated value sql-formatter does not know, instead of ignoring
+// it. Unknown keys are reported with the closest known one, to catch typos such as "keywordcase".
+func ParseConfigStrict(data []byte) (Config, error) {
+	d := &configDecoder{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	if err := d.object("", d.option); err != nil {
+		return Config{}, err
+	}
+	if at := d.next(); at < len(data) {
+		return Config{}, d.errorAt(at, "", errors.New("unexpected data after the configuration"))
+	}
+	return ParseConfig(data)
+}
+
+// ConfigError reports an invalid entry of a configuration file.
+type ConfigError struct {
+	// Path is the configuration file, or "" if the configuration was not read from a file.
+	Path string
+	// Line and Column locate the offending key, or the syntax error (1-based).
+	Line, Column int
+	// Key is the dotted path of the offending key, such as "lint.rules.no-select-star", or "" for
+	// syntax errors.
+	Key string
+	// Err describes the problem.
+	Err error
+}
+
+// Error formats the error as path:line:column: key: message.
+func (e *ConfigError) Error() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.Path != "" {
+		pos = e.Path + ":" + pos
+	}
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %v", pos, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", pos, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// configDecoder checks a configuration file key by key, walking its JSON tokens so that problems
+// can be located.
+type configDecoder struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// object reads an object, calling entry with the name and offset of each key while the decoder is
+// positioned at its value.
+func (d *configDecoder) object(path string, entry func(key string, at int) error) error {
+	at := d.next()
+	tok, err := d.dec.Token()
+	if err != nil {
+		return d.syntaxError(err)
+	}
+	if tok != json.Delim('{') {
+		return d.errorAt(at, path, errors.New("expected an object"))
+	}
+	for d.dec.More() {
+		at := d.next()
+		tok, err := d.dec.Token()
+		if err != nil {
+			return d.syntaxError(err)
+		}
+		if err := entry(tok.(string), at); err != nil {
+			return err
+		}
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return d.syntaxError(err)
+	}
+	return nil
+}
+
+// option checks a top-level entry: a formatting option, the lint settings, the embedded SQL
+// patterns or the behavior fingerprint.
+func (d *configDecoder) option(key string, at int) error {
+	if key == "behaviorFingerprint" {
+		raw, err := d.value()
+		if err != nil {
+			return err
+		}
+		var fingerprint string
+		if err := json.Unmarshal(raw, &fingerprint); err != nil {
+			return d.errorAt(at, key, fmt.Errorf("invalid value %s (want a string)", raw))
+		}
+		return nil
+	}
+	if key == "embedded" {
+		raw, err := d.value()
+		if err != nil {
+			return err
+		}
+		var patterns []EmbeddedPattern
+		if err := json.Unmarshal(raw, &patterns); err != nil {
+			return d.errorAt(at, key, fmt.Errorf("invalid value %s (want an array of objects with files, start and end)", raw))
+		}
+		if _, err := compileEmbedded(patterns); err != nil {
+			return d.errorAt(at, key, err)
+		}
+		return nil
+	}
+	if key == "lint" {
+		return d.object(key, func(name string, at int) error {
+			if name != "rules" {
+				return d.unknown(at, key+"."+name, []string{"rules"})
+			}
+			return d.object(key+"."+name, d.rule)
+		})
+	}
+	field, ok := optionField(key)
+	if !ok {
+		return d.unknown(at, key, append(optionNames(), "lint", "embedded", "behaviorFingerprint"))
+	}
+	raw, err := d.value()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, reflect.New(field.Type).Interface()); err != nil {
+		return d.errorAt(at, key, fmt.Errorf("invalid value %s (want %s)", raw, jsonKind(field.Type)))
+	}
+	if values, ok := optionValues[key]; ok {
+		var value string
+		_ = json.Unmarshal(raw, &value)
+		if !slices.Contains(values, value) {
+			return d.errorAt(at, key, fmt.Errorf("invalid value %q (want one of %s)", value, strings.Join(values, ", ")))
+		}
+	}
+	return nil
+}
+
+// rule checks an entry of lint.rules.
+func (d *configDecoder) rule(key string, at int) error {
+	names := []string{FormatRule}
+	for _, r := range Rules() {
+		names = append(names, r.Name)
+	}
+	path := "lint.rules." + key
+	if !slices.Contains(names, key) {
+		return d.unknown(at, path, names)
+	}
+	raw, err := d.value()
+	if err != nil {
+		return err
+	}
+	var severity Severity
+	if err := json.Unmarshal(raw, &severity); err != nil {
+		return d.errorAt(at, path, err)
+	}
+	return nil
+}
+
+// value reads the next value.
+func (d *configDecoder) value() (json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return nil, d.syntaxError(err)
+	}
+	return raw, nil
+}
+
+// unknown reports the unknown key at the end of path, suggesting the closest of known.
+func (d *configDecoder) unknown(at int, path string, known []string) error {
+	key := path[strings.LastIndexByte(path, '.')+1:]
+	if suggestion := closestKey(key, known); suggestion != "" {
+		return d.errorAt(at, path, fmt.Errorf("unknown key (did you mean %q?)", suggestion))
+	}
+	return d.errorAt(at, path, errors.New("unknown key"))
+}
+
+// next returns the offset of the next token.
+func (d *configDecoder) next() int {
+	off := int(d.dec.InputOffset())
+	for off < len(d.data) && strings.IndexByte(" \t\r\n,:", d.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// syntaxError locates an error returned by the JSON decoder.
+func (d *configDecoder) syntaxError(err error) error {
+	off := len(d.data)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		off = int(syntaxErr.Offset)
+	} else if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return d.errorAt(off, "", err)
+}
+
+// errorAt returns a *ConfigError for the key at offset off.
+func (d *configDecoder) errorAt(off int, key string, err error) *ConfigError {
+	return &ConfigError{
+		Line:   1 + bytes.Count(d.data[:off], []byte("\n")),
+		Column: off - bytes.LastIndexByte(d.data[:off], '\n'),
+		Key:    key,
+		Err:    err,
+	}
+}
+
+// optionField returns the field of FormatOptions with the given JSON name.
+func optionField(name string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(FormatOptions{})
+	for i := range t.NumField() {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); tag == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonKind describes the JSON values accepted for a field of type t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	}
+	return t.String()
+}
+
+// closestKey returns the key of known that word most likely misspells, or "" if none is close.
+func closestKey(word string, known []string) string {
+	best, distance := "", 3
+	for _, k := range known {
+		if strings.EqualFold(k, word) {
+			return k
+		}
+		if d := editDistance(word, k); d < distance {
+			best, distance = k, d
+		}
+	}
+	return best
+}
+
+// FindConfig looks for ConfigFileName in dir and its parent directories and returns the path of
+// the first one found, or "" if there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// UnmarshalJSON accepts "off", "warn" and "error", as well as the booleans false (off) and true (error).
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = SeverityOff
+		if b {
+			*s = SeverityError
+		}
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("severity must be a string or a boolean: %w", err)
+	}
+	switch v := Severity(str); v {
+	case SeverityOff, SeverityWarn, SeverityError:
+		*s = v
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q (want %q, %q or %q)", str, SeverityOff, SeverityWarn, SeverityError)
+}
@@ -0,0 +1,108 @@
+package sqlfmt
+
+import (
+	"slices"
+	"strings"
+)
+
+// operatorGroups are the names accepted in DenseOperatorExceptions for sets of operators.
+var operatorGroups = map[string][]string{
+	"comparison":    {"=", "<>", "!=", "<", ">", "<=", ">=", "<=>", "=="},
+	"arithmetic":    {"+", "-", "*", "/", "%"},
+	"cast":          {"::"},
+	"concatenation": {"||"},
+	"json":          {"->", "->>", "#>", "#>>", "@>", "<@", "?|", "?&"},
+	"bitwise":       {"&", "|", "^", "<<", ">>"},
+}
+
+// unaryOperators can also be prefix operators, or for *, stand for all columns, so they are only
+// spaced after an operand that is not a keyword.
+var unaryOperators = wordSet(`+ - * ~ @`)
+
+// operatorExceptions parses options.DenseOperatorExceptions into the set of operators it names,
+// also returning the entries that are neither a group of operatorGroups nor an operator.
+func operatorExceptions(list string) (set map[string]bool, unknown []string) {
+	set = make(map[string]bool)
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if ops, ok := operatorGroups[strings.ToLower(entry)]; ok {
+			for _, op := range ops {
+				set[op] = true
+			}
+			continue
+		}
+		if kind, n := lexiconFor(LanguagePostgreSQL).next(entry, nil); kind != tokenOperator || n != len(entry) {
+			unknown = append(unknown, entry)
+			continue
+		}
+		set[entry] = true
+	}
+	return set, unknown
+}
+
+// spaceOperators puts a space on each side of the binary operators of formatted, the output of
+// DenseOperators, that options.DenseOperatorExceptions lists, unless a line break is already there.
+func spaceOperators(formatted string, options FormatOptions) string {
+	set, _ := operatorExceptions(options.DenseOperatorExceptions)
+	if len(set) == 0 {
+		return formatted
+	}
+	tokens := tokenize(formatted, options.Language)
+	var b strings.Builder
+	for i, t := range tokens {
+		if t.kind == tokenOperator && set[t.text] && isBinaryOperator(tokens, i) {
+			if prev := tokens[i-1]; prev.kind != tokenSpace {
+				b.WriteByte(' ')
+			}
+			b.WriteString(t.text)
+			if next := tokens[i+1]; next.kind != tokenSpace {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// isBinaryOperator reports whether the operator tokens[i] stands between two operands.
+func isBinaryOperator(tokens []token, i int) bool {
+	prev, next := i-1, i+1
+	for prev >= 0 && tokens[prev].kind == tokenSpace {
+		prev--
+	}
+	for next < len(tokens) && tokens[next].kind == tokenSpace {
+		next++
+	}
+	if prev < 0 || next >= len(tokens) {
+		return false
+	}
+	before, after := tokens[prev], tokens[next]
+	switch before.kind {
+	case tokenWord:
+		if unaryOperators[tokens[i].text] && isKeyword(before.text) {
+			return false // SELECT -1, SELECT *
+		}
+	case tokenIdent, tokenNumber, tokenString, tokenParam:
+	case tokenPunct:
+		if before.text != ")" && before.text != "]" {
+			return false
+		}
+	default:
+		return false
+	}
+	switch after.kind {
+	case tokenPunct:
+		return after.text == "(" || after.text == "["
+	case tokenComment:
+		return false
+	}
+	return true
+}
+
+// listOptions are the options whose values are comma-separated lists.
+var listOptions = []string{"denseOperatorExceptions"}
+
+// isListOption reports whether the option with the given JSON name holds a comma-separated list.
+func isListOption(name string) bool {
+	return slices.Contains(listOptions, name)
+}
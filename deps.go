@@ -0,0 +1,384 @@
+package sqlfmt
+
+import "strings"
+
+// SchemaGraph is the dependency graph of a schema script, as built by Dependencies.
+type SchemaGraph struct {
+	// Objects has an entry for each statement of the script, in order.
+	Objects []SchemaObject
+}
+
+// SchemaObject is a statement of a schema script and the objects it depends on.
+type SchemaObject struct {
+	// Kind is the kind of object the statement creates, such as TABLE, VIEW, MATERIALIZED VIEW,
+	// INDEX or SEQUENCE, or for other statements their kind, such as ALTER TABLE, GRANT, COMMENT
+	// or INSERT.
+	Kind string
+	// Creates reports whether the statement creates Name.
+	Creates bool
+	// Name is the object the statement creates, alters, grants on or comments on. It is the zero
+	// TableRef for statements without such an object, such as unnamed indexes or SET.
+	Name TableRef
+	// Start and End are the byte offsets of the statement, as in StatementResult.
+	Start, End int
+	// Line is the line of Start (1-based).
+	Line int
+	// DependsOn lists the objects the statement needs to exist, in order of appearance.
+	DependsOn []Dependency
+}
+
+// Dependency is an object a statement depends on.
+type Dependency struct {
+	// Name is the object depended on.
+	Name TableRef
+	// Reason tells why the statement depends on Name: query (views and CREATE TABLE ... AS),
+	// foreign key, inherits, partition, index, trigger, function, type (types and tables named in
+	// the arguments and RETURNS clause of functions), owned by, default (sequences named in
+	// nextval), alter, grant, comment or data.
+	Reason string
+	// Object is the index in SchemaGraph.Objects of the statement creating Name, or -1 if the
+	// script does not create it.
+	Object int
+}
+
+// objectKinds are the kinds of objects a CREATE statement can create.
+var objectKinds = wordSet(`TABLE VIEW INDEX SEQUENCE TYPE DOMAIN FUNCTION PROCEDURE TRIGGER SCHEMA
+	EXTENSION DATABASE ROLE USER`)
+
+// commentTargets are the words naming the kind of object in COMMENT ON and GRANT ... ON.
+var commentTargets = wordSet(`TABLE VIEW MATERIALIZED COLUMN INDEX SEQUENCE TYPE DOMAIN FUNCTION
+	PROCEDURE TRIGGER SCHEMA FOREIGN CONSTRAINT`)
+
+// Dependencies builds the dependency graph of the schema script sql: views depend on the tables
+// they query, tables on the tables their foreign keys reference, indexes and triggers on their
+// table, and so on. Like Analyze, it relies on the tokenizer rather than a full parse; function
+// bodies are not looked into.
+func Dependencies(sql string, lang LanguageOption) SchemaGraph {
+	var g SchemaGraph
+	for _, stmt := range splitStatements(sql, lang) {
+		sig := significant(tokenize(sql[stmt.start:stmt.end], lang))
+		obj := schemaObject(sig, stmt.kind)
+		obj.Start, obj.End = stmt.start, stmt.end
+		obj.Line, _ = position(sql, stmt.start)
+		g.Objects = append(g.Objects, obj)
+	}
+	for i := range g.Objects {
+		deps := g.Objects[i].DependsOn
+		for j := range deps {
+			deps[j].Object = g.creator(deps[j].Name, i)
+		}
+	}
+	return g
+}
+
+// creator returns the index of the statement creating name, or -1. A statement does not depend
+// on itself, so self is skipped. A name without schema matches objects in any schema and the
+// other way around, but an exact match wins.
+func (g SchemaGraph) creator(name TableRef, self int) int {
+	loose := -1
+	for i, obj := range g.Objects {
+		if i == self || !obj.Creates || obj.Kind == "INDEX" || obj.Kind == "TRIGGER" ||
+			!strings.EqualFold(obj.Name.Name, name.Name) {
+			continue
+		}
+		if strings.EqualFold(obj.Name.Schema, name.Schema) {
+			return i
+		}
+		if loose < 0 && (obj.Name.Schema == "" || name.Schema == "") {
+			loose = i
+		}
+	}
+	return loose
+}
+
+// schemaObject describes the statement made of sig, of the given kind.
+func schemaObject(sig []token, kind string) SchemaObject {
+	obj := SchemaObject{Kind: kind}
+	depend := func(name TableRef, reason string) {
+		if name.Name != "" && name != obj.Name {
+			obj.DependsOn = append(obj.DependsOn, Dependency{Name: name, Reason: reason})
+		}
+	}
+
+	switch kind {
+	case "CREATE":
+		i := 1
+		for i < len(sig) && sig[i].text != "(" && !(sig[i].kind == tokenWord && objectKinds[strings.ToUpper(sig[i].text)]) {
+			i++
+		}
+		if i == len(sig) || sig[i].text == "(" {
+			return obj
+		}
+		obj.Kind = strings.ToUpper(sig[i].text)
+		if strings.EqualFold(sig[i-1].text, "MATERIALIZED") {
+			obj.Kind = "MATERIALIZED " + obj.Kind
+		}
+		i = skipWords(sig, i+1, "CONCURRENTLY", "IF", "NOT", "EXISTS")
+		if !(obj.Kind == "INDEX" && strings.EqualFold(tokenAt(sig, i).text, "ON")) {
+			obj.Name, i = readName(sig, i)
+			obj.Creates = obj.Name.Name != ""
+		}
+
+		switch obj.Kind {
+		case "INDEX", "TRIGGER":
+			reason := strings.ToLower(obj.Kind)
+			if on := findWord(sig, i, "ON"); on >= 0 {
+				name, _ := readName(sig, skipWords(sig, on+1, "ONLY"))
+				depend(name, reason)
+			}
+			if exec := findWord(sig, i, "EXECUTE"); exec >= 0 {
+				name, _ := readName(sig, skipWords(sig, exec+1, "FUNCTION", "PROCEDURE"))
+				depend(name, "function")
+			}
+		case "FUNCTION", "PROCEDURE":
+			functionTypes(sig, i, depend)
+		case "SEQUENCE":
+			if owned := findWord(sig, i, "OWNED"); owned >= 0 && strings.EqualFold(tokenAt(sig, owned+1).text, "BY") {
+				column, _ := readName(sig, owned+2)
+				depend(qualifierOf(column), "owned by")
+			}
+		case "TABLE", "VIEW", "MATERIALIZED VIEW":
+			for _, occ := range tableOccurrences(sig, scanNames(sig)) {
+				if occ.start < i {
+					continue
+				}
+				reason := "query"
+				if strings.EqualFold(tokenAt(sig, occ.start-1).text, "REFERENCES") {
+					reason = "foreign key"
+				}
+				depend(occ.table, reason)
+			}
+			if inherits := findWord(sig, i, "INHERITS"); inherits >= 0 && tokenAt(sig, inherits+1).text == "(" {
+				for j := inherits + 2; j < len(sig) && sig[j].text != ")"; j++ {
+					var name TableRef
+					name, j = readName(sig, j)
+					depend(name, "inherits")
+				}
+			}
+			if of := findWord(sig, i, "OF"); of >= 0 && strings.EqualFold(tokenAt(sig, of-1).text, "PARTITION") {
+				name, _ := readName(sig, of+1)
+				depend(name, "partition")
+			}
+			sequenceDefaults(sig, depend)
+		}
+
+	case "ALTER":
+		i := 2
+		obj.Kind = "ALTER " + strings.ToUpper(tokenAt(sig, 1).text)
+		if strings.EqualFold(tokenAt(sig, 1).text, "MATERIALIZED") {
+			obj.Kind += " " + strings.ToUpper(tokenAt(sig, 2).text)
+			i++
+		}
+		obj.Name, _ = readName(sig, skipWords(sig, i, "IF", "EXISTS", "ONLY"))
+		if obj.Name.Name != "" {
+			obj.DependsOn = append(obj.DependsOn, Dependency{Name: obj.Name, Reason: "alter"})
+		}
+		for _, occ := range tableOccurrences(sig, scanNames(sig)) {
+			if strings.EqualFold(tokenAt(sig, occ.start-1).text, "REFERENCES") {
+				depend(occ.table, "foreign key")
+			}
+		}
+		if owned := findWord(sig, i, "OWNED"); owned >= 0 && strings.EqualFold(tokenAt(sig, owned+1).text, "BY") {
+			column, _ := readName(sig, owned+2)
+			depend(qualifierOf(column), "owned by")
+		}
+		sequenceDefaults(sig, depend)
+
+	case "GRANT", "REVOKE":
+		on := findWord(sig, 1, "ON")
+		if on < 0 || strings.EqualFold(tokenAt(sig, on+1).text, "ALL") {
+			return obj // role grants and ALL TABLES IN SCHEMA name no object
+		}
+		for j := skipTargets(sig, on+1); j < len(sig); j++ {
+			var name TableRef
+			name, j = readName(sig, j)
+			if obj.Name.Name == "" {
+				obj.Name = name
+			}
+			obj.DependsOn = append(obj.DependsOn, Dependency{Name: name, Reason: "grant"})
+			if tokenAt(sig, j).text == "(" { // function arguments
+				j = closingParen(sig, j) + 1
+			}
+			if tokenAt(sig, j).text != "," {
+				break
+			}
+		}
+
+	case "COMMENT":
+		if !strings.EqualFold(tokenAt(sig, 1).text, "ON") {
+			return obj
+		}
+		column := strings.EqualFold(tokenAt(sig, 2).text, "COLUMN")
+		obj.Name, _ = readName(sig, skipTargets(sig, 2))
+		if column {
+			obj.Name = qualifierOf(obj.Name)
+		}
+		if obj.Name.Name != "" {
+			obj.DependsOn = append(obj.DependsOn, Dependency{Name: obj.Name, Reason: "comment"})
+		}
+
+	case "COPY":
+		obj.Name, _ = readName(sig, 1)
+		if obj.Name.Name != "" {
+			obj.DependsOn = append(obj.DependsOn, Dependency{Name: obj.Name, Reason: "data"})
+		}
+
+	default:
+		for _, occ := range tableOccurrences(sig, scanNames(sig)) {
+			depend(occ.table, "data")
+		}
+	}
+	return obj
+}
+
+// functionTypes calls depend for each type named in the argument list of the function or
+// procedure made of sig, which starts at sig[i], and in its RETURNS clause, such as u in RETURNS
+// SETOF u, leaving out built-in types. A table named in t%ROWTYPE or t.column%TYPE counts too.
+func functionTypes(sig []token, i int, depend func(TableRef, string)) {
+	typeAt := func(j int) {
+		name, next := readName(sig, j)
+		if tokenAt(sig, next).text == "%" {
+			if strings.EqualFold(tokenAt(sig, next+1).text, "TYPE") {
+				name = qualifierOf(name)
+			}
+		} else if name.Schema == "" && isKeyword(name.Name) {
+			return
+		}
+		depend(name, "type")
+	}
+	// columns reads the arguments or columns of the list in parentheses at sig[open]: an optional
+	// mode, an optional name, then the type.
+	columns := func(open int) {
+		end := closingParen(sig, open)
+		for j := open + 1; j < end; j++ {
+			j = skipWords(sig, j, "IN", "OUT", "INOUT", "VARIADIC")
+			if _, next := readName(sig, j); next > j && next < end && (sig[next].kind == tokenWord || sig[next].kind == tokenIdent) &&
+				!strings.EqualFold(sig[next].text, "DEFAULT") {
+				j = next // the name of the argument
+			}
+			typeAt(j)
+			for depth := 0; j < end && (depth > 0 || sig[j].text != ","); j++ {
+				switch sig[j].text {
+				case "(":
+					depth++
+				case ")":
+					depth--
+				}
+			}
+		}
+	}
+
+	if tokenAt(sig, i).text == "(" {
+		columns(i)
+	}
+	if returns := findWord(sig, i, "RETURNS"); returns >= 0 {
+		j := skipWords(sig, returns+1, "SETOF")
+		if strings.EqualFold(tokenAt(sig, j).text, "TABLE") && tokenAt(sig, j+1).text == "(" {
+			columns(j + 1)
+		} else {
+			typeAt(j)
+		}
+	}
+}
+
+// sequenceDefaults calls depend for each sequence named in a nextval('name') call of sig.
+func sequenceDefaults(sig []token, depend func(TableRef, string)) {
+	for i, t := range sig {
+		if !strings.EqualFold(t.text, "nextval") || tokenAt(sig, i+1).text != "(" || tokenAt(sig, i+2).kind != tokenString {
+			continue
+		}
+		literal := sig[i+2].text
+		if len(literal) < 2 || literal[0] != '\'' {
+			continue
+		}
+		parts := strings.Split(strings.ReplaceAll(literal[1:len(literal)-1], `"`, ""), ".")
+		depend(TableRef{Schema: strings.Join(parts[:len(parts)-1], "."), Name: parts[len(parts)-1]}, "default")
+	}
+}
+
+// readName reads the possibly qualified name starting at sig[i] and returns it with the index of
+// the token after it. It returns the zero TableRef if sig[i] is not a name.
+func readName(sig []token, i int) (TableRef, int) {
+	var parts []string
+	for i < len(sig) && (sig[i].kind == tokenIdent || sig[i].kind == tokenWord) {
+		parts = append(parts, unquoteName(sig[i]))
+		if tokenAt(sig, i+1).text != "." {
+			i++
+			break
+		}
+		i += 2
+	}
+	if len(parts) == 0 {
+		return TableRef{}, i
+	}
+	return TableRef{Schema: strings.Join(parts[:len(parts)-1], "."), Name: parts[len(parts)-1]}, i
+}
+
+// qualifierOf returns the table of the qualified column name column.
+func qualifierOf(column TableRef) TableRef {
+	i := strings.LastIndex(column.Schema, ".")
+	if i < 0 {
+		return TableRef{Name: column.Schema}
+	}
+	return TableRef{Schema: column.Schema[:i], Name: column.Schema[i+1:]}
+}
+
+// skipWords returns the index of the first token of sig from i on that is not one of words.
+func skipWords(sig []token, i int, words ...string) int {
+	for i < len(sig) && sig[i].kind == tokenWord && containsFold(words, sig[i].text) {
+		i++
+	}
+	return i
+}
+
+// skipTargets skips the words naming a kind of object, as in COMMENT ON MATERIALIZED VIEW.
+func skipTargets(sig []token, i int) int {
+	for i < len(sig) && sig[i].kind == tokenWord && commentTargets[strings.ToUpper(sig[i].text)] &&
+		tokenAt(sig, i+1).text != "." && tokenAt(sig, i+1).kind != tokenPunct {
+		i++
+	}
+	return i
+}
+
+// findWord returns the index of the first keyword word of sig from i on outside parentheses,
+// or -1.
+func findWord(sig []token, i int, word string) int {
+	depth := 0
+	for ; i < len(sig); i++ {
+		switch {
+		case sig[i].text == "(":
+			depth++
+		case sig[i].text == ")":
+			depth--
+		case depth == 0 && sig[i].kind == tokenWord && strings.EqualFold(sig[i].text, word):
+			return i
+		}
+	}
+	return -1
+}
+
+// closingParen returns the index of the parenthesis closing the one at sig[i].
+func closingParen(sig []token, i int) int {
+	depth := 0
+	for ; i < len(sig); i++ {
+		switch sig[i].text {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(sig) - 1
+}
+
+// containsFold reports whether words contains s, ignoring case.
+func containsFold(words []string, s string) bool {
+	for _, w := range words {
+		if strings.EqualFold(w, s) {
+			return true
+		}
+	}
+	return false
+}
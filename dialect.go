@@ -0,0 +1,80 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Dialect describes the syntax a dialect accepts, as the formatter knows it, for tools that offer
+// choices per dialect, such as an editor completing keywords or a UI listing placeholder styles.
+// The syntax lists hold examples of each form, such as "$1" for numbered placeholders.
+type Dialect struct {
+	Language LanguageOption
+	// Placeholders lists the styles of bind parameters: positional ("?"), numbered ("$1"), named
+	// (":name") and quoted named (`@"name"`).
+	Placeholders []string
+	// StringQuotes lists the forms of string literals, with their prefixes, such as "''", "E''",
+	// "$$" or "'''...'''".
+	StringQuotes []string
+	// IdentifierQuotes lists the forms of quoted identifiers, such as `""`, "``" or "[]".
+	IdentifierQuotes []string
+	// LineComments lists the markers of comments that run to the end of the line, such as "--".
+	LineComments []string
+	// Keywords, DataTypes and Functions are the reserved words, data types and function names of
+	// the dialect, upper-cased and sorted. The native backend only knows the keywords and data
+	// types common to all dialects, and no functions.
+	Keywords, DataTypes, Functions []string
+}
+
+// DialectInfo describes lang, or the standard dialect if lang is "", using the formatter shared by
+// the package-level functions. It fails with ErrUnknownLanguage if lang is not a known dialect.
+func DialectInfo(lang LanguageOption) (Dialect, error) {
+	f, err := sharedFormatter()
+	if err != nil {
+		return Dialect{}, err
+	}
+	return f.DialectInfo(lang)
+}
+
+// DialectInfo describes lang, or the standard dialect if lang is "", as sql-formatter defines it in
+// the bundle f was created with. It fails with ErrUnknownLanguage if lang is not a known dialect.
+func (f *Formatter) DialectInfo(lang LanguageOption) (Dialect, error) {
+	if err := f.check("DialectInfo"); err != nil {
+		return Dialect{}, err
+	}
+	if lang == "" {
+		lang = LanguageSQL
+	}
+	if !slices.Contains(optionValues["language"], string(lang)) {
+		return Dialect{}, fmt.Errorf("%w: %q", ErrUnknownLanguage, lang)
+	}
+	e, err := f.pool.get()
+	if err != nil {
+		return Dialect{}, err
+	}
+	defer f.pool.put(e)
+
+	name := lang
+	if name == LanguageTSQL {
+		name = LanguageTransactSQL
+	}
+	d, err := e.dialect(name)
+	if err != nil {
+		return Dialect{}, err
+	}
+	d.Language = lang
+	for _, list := range [][]string{d.Keywords, d.DataTypes, d.Functions} {
+		slices.Sort(list)
+	}
+	return d, nil
+}
+
+// appendUnique appends the values to list that it does not hold yet.
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
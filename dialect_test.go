@@ -0,0 +1,56 @@
+package sqlfmt
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDialectInfo(t *testing.T) {
+	tests := []struct {
+		lang         LanguageOption
+		placeholder  string
+		stringQuote  string
+		identQuote   string
+		lineComment  string
+		wantLanguage LanguageOption
+	}{
+		{"", "", "''", `""`, "--", LanguageSQL},
+		{LanguagePostgreSQL, "$1", "$$", `""`, "--", LanguagePostgreSQL},
+		{LanguageMySQL, "?", "''", "``", "#", LanguageMySQL},
+		{LanguageTSQL, "@name", "''", "[]", "--", LanguageTSQL},
+	}
+	for _, tt := range tests {
+		d, err := DialectInfo(tt.lang)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.lang, err)
+		}
+		if d.Language != tt.wantLanguage {
+			t.Errorf("%q: Language = %q, want %q", tt.lang, d.Language, tt.wantLanguage)
+		}
+		for _, c := range []struct {
+			name string
+			list []string
+			want string
+		}{
+			{"Placeholders", d.Placeholders, tt.placeholder},
+			{"StringQuotes", d.StringQuotes, tt.stringQuote},
+			{"IdentifierQuotes", d.IdentifierQuotes, tt.identQuote},
+			{"LineComments", d.LineComments, tt.lineComment},
+		} {
+			if c.want != "" && !slices.Contains(c.list, c.want) {
+				t.Errorf("%q: %s = %q, want it to hold %q", tt.lang, c.name, c.list, c.want)
+			}
+		}
+		if !slices.Contains(d.Keywords, "SELECT") || !slices.IsSorted(d.Keywords) {
+			t.Errorf("%q: Keywords are not sorted or lack SELECT", tt.lang)
+		}
+		if !slices.IsSorted(d.DataTypes) || !slices.IsSorted(d.Functions) {
+			t.Errorf("%q: DataTypes or Functions are not sorted", tt.lang)
+		}
+	}
+
+	if _, err := DialectInfo("cobol"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Errorf("unknown language: got %v, want ErrUnknownLanguage", err)
+	}
+}
@@ -0,0 +1,137 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// directivePrefix starts the comments that override formatting options within a file.
+const directivePrefix = "sqlfmt:"
+
+// directiveChunk is a part of the input formatted with its own options.
+type directiveChunk struct {
+	sql     string
+	options FormatOptions
+}
+
+// splitDirectives splits sql at the directive comments placed between statements, such as
+//
+//	-- sqlfmt: keywordCase=lower tabWidth=2
+//
+// which override the options (named like their JSON keys) for the statements that follow, until
+// the next directive. "-- sqlfmt: reset" restores the options passed by the caller. It returns
+// nil if sql has no directive, or none that changes options.
+func splitDirectives(sql string, options FormatOptions) ([]directiveChunk, error) {
+	if !strings.Contains(sql, directivePrefix) {
+		return nil, nil
+	}
+
+	var (
+		chunks  []directiveChunk
+		current = options
+		start   = 0
+		depth   = 0
+		atStart = true // whether only whitespace and comments follow the last statement
+		found   = false
+	)
+	for _, t := range tokenize(sql, options.Language) {
+		switch {
+		case t.kind == tokenSpace:
+			continue
+		case t.kind == tokenComment:
+			if !atStart || isGeneratedMarker(t.text, "") || isGeneratedMarker(t.text, options.GeneratedMarker) {
+				continue
+			}
+			next, ok, err := ApplyDirective(t.text, options, current)
+			if !ok {
+				continue
+			}
+			if err != nil {
+				line, column := position(sql, t.start)
+				return nil, fmt.Errorf("%d:%d: invalid sqlfmt directive: %w", line, column, err)
+			}
+			if strings.TrimSpace(sql[start:t.start]) != "" {
+				chunks = append(chunks, directiveChunk{sql[start:t.start], current})
+			}
+			start, current, found = t.start, next, true
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		}
+		atStart = t.kind == tokenComment && atStart || t.text == ";" && depth == 0
+	}
+	if !found || len(chunks) == 0 && sameOptions(current, options) {
+		// A chunk formatted on its own starts with its directive, which changes nothing again
+		return nil, nil
+	}
+	return append(chunks, directiveChunk{sql[start:], current}), nil
+}
+
+// sameOptions reports whether a and b are equal apart from their catalogs, which directives do not
+// set and which may hold maps that cannot be compared.
+func sameOptions(a, b FormatOptions) bool {
+	a.Catalog, a.ColumnCatalog = nil, nil
+	b.Catalog, b.ColumnCatalog = nil, nil
+	return a == b
+}
+
+// ApplyDirective applies the directive comment, such as
+//
+//	-- sqlfmt: keywordCase=lower tabWidth=2
+//
+// to current, the options in effect before it, and returns the result: current with the options
+// the directive names set, or options for "-- sqlfmt: reset". It reports false if comment is not
+// a directive. Format applies the directives of its input this way; ApplyDirective is for tools
+// reading such comments themselves, such as package sqlfmttest.
+func ApplyDirective(comment string, options, current FormatOptions) (FormatOptions, bool, error) {
+	directive, args := parseDirective(comment, directivePrefix)
+	if directive == "" {
+		return current, false, nil
+	}
+	next, err := applyDirective(options, current, append([]string{directive}, args...))
+	return next, true, err
+}
+
+// applyDirective returns the options resulting from applying the key=value pairs of a directive
+// to current, or the caller's options for "reset".
+func applyDirective(options, current FormatOptions, pairs []string) (FormatOptions, error) {
+	if len(pairs) == 1 && pairs[0] == "reset" {
+		return options, nil
+	}
+	values := make(map[string]string)
+	var last string
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if isListOption(last) && (!ok || !strings.ContainsFunc(key, unicode.IsLetter)) {
+			values[last] += "," + pair // the list was split on its commas, and may hold "=" itself
+			continue
+		}
+		last = key
+		if !ok || key == "" {
+			return current, fmt.Errorf("%q is not key=value", pair)
+		}
+		values[key] = value
+	}
+	return setOptions(current, values)
+}
+
+// formatChunks formats each chunk with its options and joins the results, separated like
+// statements.
+func (f *Formatter) formatChunks(e *engine, chunks []directiveChunk, options FormatOptions, trace *passTrace) (string, []Warning, error) {
+	lines := options.BlankLinesBetweenQueries()
+	var (
+		parts    []string
+		warnings []Warning
+	)
+	for _, chunk := range chunks {
+		formatted, w, err := f.formatWith(e, chunk.sql, chunk.options, trace)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, formatted)
+		warnings = append(warnings, w...)
+	}
+	return strings.Join(parts, strings.Repeat("\n", lines+1)), warnings, nil
+}
@@ -0,0 +1,36 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// setOptions returns options with the options named by the keys of values (their JSON names) set
+// from the textual values. Booleans and numbers are parsed; anything else, such as NaN or 0x10,
+// which are not JSON numbers, is taken as a string.
+func setOptions(options FormatOptions, values map[string]string) (FormatOptions, error) {
+	raw := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		if value == "true" || value == "false" {
+			raw[key] = json.RawMessage(value)
+		} else if _, err := strconv.ParseFloat(value, 64); err == nil && json.Valid([]byte(value)) {
+			raw[key] = json.RawMessage(value)
+		} else {
+			raw[key], _ = json.Marshal(value)
+		}
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return options, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var partial PartialFormatOptions
+	if err := dec.Decode(&partial); err != nil {
+		return options, err
+	}
+	return Merge(options, partial), nil
+}
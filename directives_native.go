@@ -0,0 +1,11 @@
+//go:build tinygo || sqlfmt_native
+
+package sqlfmt
+
+import "errors"
+
+// setOptions fails: the native backend leaves out the JSON decoding that sets options by name, so
+// directive comments cannot be applied.
+func setOptions(options FormatOptions, values map[string]string) (FormatOptions, error) {
+	return options, errors.New("directives are not supported by the native backend")
+}
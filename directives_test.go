@@ -0,0 +1,96 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyDirective(t *testing.T) {
+	if Backend == "native" {
+		t.Skip("the native backend does not apply directives")
+	}
+	current := DefaultFormatOptions
+	current.TabWidth = 8
+	tests := []struct {
+		comment string
+		ok      bool
+		want    func(*FormatOptions)
+		err     string
+	}{
+		{comment: "-- a comment"},
+		{comment: "-- sqlfmt: keywordCase=lower tabWidth=2", ok: true, want: func(o *FormatOptions) { o.KeywordCase, o.TabWidth = CaseOptionLower, 2 }},
+		{comment: "/* sqlfmt: useTabs=true, linesBetweenQueries=0 */", ok: true, want: func(o *FormatOptions) {
+			o.UseTabs, o.LinesBetweenQueries = true, NoLinesBetweenQueries
+		}},
+		{comment: "-- sqlfmt: generatedMarker=NaN", ok: true, want: func(o *FormatOptions) { o.GeneratedMarker = "NaN" }},
+		{comment: "-- sqlfmt: reset", ok: true, want: func(o *FormatOptions) { o.TabWidth = DefaultFormatOptions.TabWidth }},
+		{comment: "-- sqlfmt: tabWidth=Inf", ok: true, err: "tabWidth"},
+		{comment: "-- sqlfmt: tabWidth=0x10", ok: true, err: "tabWidth"},
+		{comment: "-- sqlfmt: tabWidth", ok: true, err: "not key=value"},
+		{comment: "-- sqlfmt: noSuchOption=1", ok: true, err: "noSuchOption"},
+	}
+	for _, tt := range tests {
+		got, ok, err := ApplyDirective(tt.comment, DefaultFormatOptions, current)
+		if ok != tt.ok {
+			t.Errorf("%s: got ok %v, want %v", tt.comment, ok, tt.ok)
+			continue
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one mentioning %q", tt.comment, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.comment, err)
+			continue
+		}
+		want := current
+		if tt.want != nil {
+			tt.want(&want)
+		}
+		if got != want {
+			t.Errorf("%s: got %+v, want %+v", tt.comment, got, want)
+		}
+	}
+}
+
+func TestFormatDirectives(t *testing.T) {
+	if Backend == "native" {
+		t.Skip("the native backend does not apply directives")
+	}
+	tests := []struct {
+		name, sql, want string
+		catalog         Catalog
+	}{
+		{
+			name: "first line",
+			sql:  "-- sqlfmt: keywordCase=lower tabWidth=2\nselect a from t",
+			want: "-- sqlfmt: keywordCase=lower tabWidth=2\nselect\n  a\nfrom\n  t",
+		},
+		{
+			name: "after a statement",
+			sql:  "select 1;\n-- sqlfmt: keywordCase=lower tabWidth=2\nselect a from t",
+			want: "-- sqlfmt: keywordCase=lower tabWidth=2\nselect\n  a\nfrom\n  t",
+		},
+		{
+			name:    "with a map catalog",
+			sql:     "-- sqlfmt: keywordCase=lower tabWidth=2\nselect a from t",
+			want:    "-- sqlfmt: keywordCase=lower tabWidth=2\nselect\n  a\nfrom\n  t",
+			catalog: SchemaMap{"t": "app"},
+		},
+	}
+	for _, tt := range tests {
+		options := DefaultFormatOptions
+		options.KeywordCase = CaseOptionUpper
+		options.Catalog = tt.catalog
+		got, err := Format(tt.sql, options)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%s: got\n%s\nwant it to end with\n%s", tt.name, got, tt.want)
+		}
+	}
+}
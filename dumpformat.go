@@ -0,0 +1,373 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dumpPartKind tells how formatDump handles a part of a dump.
+type dumpPartKind int
+
+const (
+	dumpSQL      dumpPartKind = iota // SQL to format
+	dumpVerbatim                     // text passed through as is
+	dumpInserts                      // INSERT statements laid out by compactInserts
+)
+
+// dumpPart is a part of a dump.
+type dumpPart struct {
+	sql  string
+	kind dumpPartKind
+	// grouped marks the parts of a LOCK TABLES ... UNLOCK TABLES group after LOCK TABLES, which
+	// are separated from the previous part by a single line break.
+	grouped bool
+}
+
+// splitDump splits the output of the dump tool named by options.DumpFormat into parts. It
+// returns nil for other inputs.
+func splitDump(sql string, options FormatOptions) []dumpPart {
+	if !isDumpFormat(options.DumpFormat) {
+		return nil
+	}
+	var parts []dumpPart
+	s := &dumpSplitter{options: options, emit: func(part dumpPart) {
+		// Merge the parts handled the same way.
+		if n := len(parts); n > 0 && parts[n-1].kind == part.kind && parts[n-1].grouped == part.grouped {
+			parts[n-1].sql += part.sql
+		} else {
+			parts = append(parts, part)
+		}
+	}}
+	for _, line := range strings.SplitAfter(sql, "\n") {
+		s.line(line)
+	}
+	s.close()
+	return parts
+}
+
+// dumpOptions returns options with the Language set to the dialect of the dump tool named by
+// options.DumpFormat if it is left to the standard one, in which dumps do not parse: the $$
+// function bodies of pg_dump would be split at their semicolons, for example.
+func dumpOptions(options FormatOptions) FormatOptions {
+	if options.Language == "" || options.Language == LanguageSQL {
+		switch options.DumpFormat {
+		case DumpFormatPgDump:
+			options.Language = LanguagePostgreSQL
+		case DumpFormatMySQLDump:
+			options.Language = LanguageMySQL
+		}
+	}
+	return options
+}
+
+// isDumpFormat reports whether format names a dump tool known to splitDump.
+func isDumpFormat(format DumpFormatOption) bool {
+	return format == DumpFormatPgDump || format == DumpFormatMySQLDump
+}
+
+// dumpChunkSize is the size above which the data of a COPY statement is emitted in several parts,
+// so that a dump can be streamed with bounded memory.
+const dumpChunkSize = 64 * 1024
+
+// dumpSplitter splits a dump into parts, which it passes to emit in order. Dumps hold text that
+// is not SQL, such as the data of COPY, so they are fed line by line: only the pending statement
+// is tokenized, once, as its lines arrive.
+type dumpSplitter struct {
+	options     FormatOptions
+	emit        func(dumpPart)
+	pending     strings.Builder // text not emitted yet, the comments before the next statement included
+	inStatement bool            // whether the pending text has started a statement
+	grouped     bool            // whether the pending text is inside a LOCK TABLES group
+	copying     bool            // pg_dump: whether the pending text is a COPY with its data
+	delimited   bool            // mysqldump: whether the pending text is a DELIMITER block
+
+	// The state of the tokenization of the pending text, up to scanned.
+	scanned int     // offset of the first token not scanned yet
+	closer  string  // text closing the token at scanned, a string or comment left open so far
+	sig     []token // significant tokens (see statement)
+	last    string  // text of the last significant token
+	depth   int     // depth of parentheses
+}
+
+// flush emits the pending text as kind.
+func (s *dumpSplitter) flush(kind dumpPartKind) {
+	s.emit(dumpPart{sql: s.pending.String(), kind: kind, grouped: s.grouped})
+	s.pending.Reset()
+	s.scanned, s.closer, s.sig, s.last, s.depth = 0, "", nil, "", 0
+}
+
+// statement reports whether the pending text, which ends with line, is a complete statement,
+// and returns its significant tokens if so. Only the first two are returned for statements other
+// than COPY and SELECT, whose tokens are looked at further, so that the tokens of a large INSERT
+// statement are not held.
+//
+// The text is tokenized from where the previous call stopped, so that a long statement, such as a
+// function whose body holds many semicolons, is tokenized once rather than once per line.
+func (s *dumpSplitter) statement(line string) ([]token, bool) {
+	if s.closer != "" && strings.Contains(line, s.closer) {
+		s.closer = "" // the token left open may end in line
+	}
+	if s.closer != "" || s.inStatement && !strings.Contains(line, ";") {
+		return nil, false
+	}
+	text := s.pending.String()
+	offset := s.scanned
+	scanTokens(text[offset:], s.options.Language, func(t token) bool {
+		t.start, t.end = t.start+offset, t.end+offset
+		if t.end == len(text) && t.kind != tokenSpace {
+			// The last token may go on in the next line: scan it again then.
+			s.closer = closerOf(t)
+			return false
+		}
+		s.scanned, s.closer = t.end, ""
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			return true
+		}
+		switch t.text {
+		case "(":
+			s.depth++
+		case ")":
+			s.depth--
+		}
+		if len(s.sig) < 2 || strings.EqualFold(s.sig[0].text, "COPY") || strings.EqualFold(s.sig[0].text, "SELECT") {
+			s.sig = append(s.sig, t)
+		}
+		s.last = t.text
+		return true
+	})
+	s.inStatement = len(s.sig) > 0
+	if !s.inStatement || s.last != ";" || s.depth != 0 {
+		return nil, false
+	}
+	s.inStatement = false
+	return s.sig, true
+}
+
+// closerOf returns the text that ends the token t if it is a string, quoted identifier or block
+// comment, which may be left open by the end of the text, or "" otherwise.
+func closerOf(t token) string {
+	switch {
+	case t.kind == tokenComment && strings.HasPrefix(t.text, "/*"):
+		return "*/"
+	case t.kind == tokenString && strings.HasPrefix(t.text, "$"):
+		return t.text[:strings.IndexByte(t.text[1:], '$')+2] // the $tag$
+	case t.kind == tokenString || t.kind == tokenIdent:
+		i := strings.IndexAny(t.text, "'\"`[")
+		if i < 0 {
+			return ""
+		}
+		if t.text[i] == '[' {
+			return "]"
+		}
+		return t.text[i : i+1]
+	}
+	return ""
+}
+
+// statementKind returns how the complete statement sig is handled: INSERT statements as set by
+// DumpInserts, others as SQL.
+func (s *dumpSplitter) statementKind(sig []token) dumpPartKind {
+	if first := strings.ToUpper(sig[0].text); first == "INSERT" || first == "REPLACE" {
+		switch s.options.DumpInserts {
+		case DumpInsertsVerbatim:
+			return dumpVerbatim
+		case DumpInsertsCompact:
+			return dumpInserts
+		}
+	}
+	return dumpSQL
+}
+
+// line feeds the next line of the dump, with its line break.
+func (s *dumpSplitter) line(line string) {
+	s.pending.WriteString(line)
+	switch s.options.DumpFormat {
+	case DumpFormatPgDump:
+		s.pgDumpLine(line)
+	case DumpFormatMySQLDump:
+		s.mysqlDumpLine(line)
+	}
+}
+
+// close emits the text after the last statement.
+func (s *dumpSplitter) close() {
+	if strings.TrimSpace(s.pending.String()) == "" {
+		return
+	}
+	if s.copying || s.delimited || !s.inStatement {
+		s.flush(dumpVerbatim)
+	} else {
+		s.flush(dumpSQL)
+	}
+}
+
+// pgDumpLine splits the output of pg_dump. psql meta-commands such as \connect, the SET
+// statements of the preamble and COPY ... FROM stdin statements with their data, up to the \.
+// line, are passed through; the comments before them go with them.
+func (s *dumpSplitter) pgDumpLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if s.copying {
+		if trimmed == `\.` {
+			s.copying = false
+			s.flush(dumpVerbatim)
+		} else if s.pending.Len() >= dumpChunkSize {
+			s.flush(dumpVerbatim)
+		}
+		return
+	}
+	if !s.inStatement && strings.HasPrefix(trimmed, `\`) {
+		s.flush(dumpVerbatim)
+		return
+	}
+	sig, ok := s.statement(line)
+	switch {
+	case !ok:
+	case strings.EqualFold(sig[0].text, "COPY") && findWord(sig, 1, "STDIN") >= 0:
+		s.copying = true
+	case strings.EqualFold(sig[0].text, "SET") || isSetConfig(sig):
+		s.flush(dumpVerbatim)
+	default:
+		s.flush(s.statementKind(sig))
+	}
+}
+
+// mysqlDumpLine splits the output of mysqldump. Statements made of a conditional comment alone,
+// such as /*!40101 SET NAMES utf8mb4 */; and DELIMITER blocks, in which mysqldump writes triggers
+// and routines, are passed through. The statements between LOCK TABLES and UNLOCK TABLES are
+// kept together.
+func (s *dumpSplitter) mysqlDumpLine(line string) {
+	fields := strings.Fields(line)
+	isDelimiter := len(fields) > 0 && strings.EqualFold(fields[0], "DELIMITER")
+	if s.delimited {
+		if isDelimiter && len(fields) == 2 && fields[1] == ";" {
+			s.delimited = false
+			s.flush(dumpVerbatim)
+		}
+		return
+	}
+	if !s.inStatement && isDelimiter {
+		s.delimited = true
+		return
+	}
+	sig, ok := s.statement(line)
+	switch {
+	case !ok:
+	case len(sig) == 1:
+		s.flush(dumpVerbatim) // a conditional comment
+	case strings.EqualFold(sig[0].text, "LOCK"):
+		s.flush(dumpSQL)
+		s.grouped = true
+	case strings.EqualFold(sig[0].text, "UNLOCK"):
+		s.flush(dumpSQL)
+		s.grouped = false
+	default:
+		s.flush(s.statementKind(sig))
+	}
+}
+
+// isSetConfig reports whether sig is a call of set_config, which pg_dump uses in its preamble.
+func isSetConfig(sig []token) bool {
+	if !strings.EqualFold(tokenAt(sig, 0).text, "SELECT") {
+		return false
+	}
+	name, next := readName(sig, 1)
+	return strings.EqualFold(name.Name, "set_config") && tokenAt(sig, next).text == "("
+}
+
+// formatDump formats the SQL parts of a dump, passes the verbatim ones through and lays out
+// INSERT statements with compactInserts, separating the parts like statements. Parse errors are
+// located in the dump rather than in the part.
+func (f *Formatter) formatDump(e *engine, parts []dumpPart, options FormatOptions, trace *passTrace) (string, []Warning, error) {
+	inner := options
+	inner.DumpFormat = ""
+	lines := options.BlankLinesBetweenQueries()
+	var (
+		b        strings.Builder
+		warnings []Warning
+		verbatim int
+		line     = 1 // line of the dump where the part starts
+	)
+	for i, part := range parts {
+		partLine := line
+		line += strings.Count(part.sql, "\n")
+		if i > 0 {
+			if part.grouped {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(strings.Repeat("\n", lines+1))
+			}
+		}
+		partOptions := inner
+		if part.grouped {
+			partOptions.LinesBetweenQueries = NoLinesBetweenQueries
+		}
+		switch part.kind {
+		case dumpVerbatim:
+			verbatim++
+			b.WriteString(strings.TrimSpace(part.sql))
+		case dumpInserts:
+			b.WriteString(compactInserts(part.sql, partOptions))
+		default:
+			out, w, err := f.formatWith(e, part.sql, partOptions, trace)
+			if err != nil {
+				return "", nil, shiftError(err, partLine)
+			}
+			b.WriteString(out)
+			warnings = append(warnings, w...)
+		}
+	}
+	trace.note("dumpFormat", fmt.Sprintf("%d parts passed through, %d formatted", verbatim, len(parts)-verbatim))
+	return b.String(), warnings, nil
+}
+
+// compactInserts lays out the INSERT statements of sql without sql-formatter, which is slow on
+// the multi-megabyte statements of dumps: each statement keeps its text up to VALUES on one line,
+// followed by its rows, one per line, indented and otherwise as written. Comments before a
+// statement are kept; statements without VALUES are kept as written.
+func compactInserts(sql string, options FormatOptions) string {
+	unit := strings.Repeat(" ", indentWidth(options))
+	if options.UseTabs {
+		unit = "\t"
+	}
+	semicolon := ";"
+	if options.NewlineBeforeSemicolon {
+		semicolon = "\n;"
+	}
+
+	var stmts []string
+	for _, stmt := range splitStatements(sql, options.Language) {
+		text := sql[stmt.start:stmt.end]
+		sig := significant(tokenize(text, options.Language))
+		values := findWord(sig, 0, "VALUES")
+		if values < 0 {
+			stmts = append(stmts, strings.TrimSpace(text))
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(text[:sig[values].end])
+		last := sig[values].end // end of the last row
+		for i := values + 1; i < len(sig) && sig[i].text == "("; {
+			end := closingParen(sig, i)
+			if i > values+1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n" + unit + text[sig[i].start:sig[end].end])
+			last = sig[end].end
+			if i = end + 1; tokenAt(sig, i).text == "," {
+				i++
+			}
+		}
+		rest := text[last:]
+		if stmt.terminated {
+			rest = strings.TrimSuffix(rest, ";")
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			b.WriteString("\n" + rest) // ON DUPLICATE KEY UPDATE ...
+		}
+		if stmt.terminated {
+			b.WriteString(semicolon)
+		}
+		stmts = append(stmts, b.String())
+	}
+	return strings.Join(stmts, strings.Repeat("\n", options.BlankLinesBetweenQueries()+1))
+}
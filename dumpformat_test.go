@@ -0,0 +1,88 @@
+package sqlfmt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const pgDump = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE FUNCTION public.f() RETURNS integer
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  PERFORM 1;
+  RETURN 2;
+END;
+$$;
+
+CREATE TABLE public.t (a integer);
+
+COPY public.t (a) FROM stdin;
+1
+2
+\.
+`
+
+func TestPgDumpDefaultLanguage(t *testing.T) {
+	options := DefaultFormatOptions
+	options.DumpFormat = DumpFormatPgDump
+	got, err := Format(pgDump, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"AS $$\nBEGIN\n  PERFORM 1;\n  RETURN 2;\nEND;\n$$\n;",
+		"COPY public.t (a) FROM stdin;\n1\n2\n\\.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDumpErrorLocation(t *testing.T) {
+	if Backend == "native" {
+		t.Skip("the native backend does not report parse errors")
+	}
+	options := DefaultFormatOptions
+	options.DumpFormat = DumpFormatPgDump
+	dump := pgDump[:strings.Index(pgDump, "COPY")]
+	_, err := Format(strings.Replace(dump, "(a integer);", "(a integer;", 1), options)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got error %v, want a *ParseError", err)
+	}
+	if pe.Line != 17 || pe.Column != 33 || !strings.HasSuffix(pe.Message, "at line 17 column 33") {
+		t.Errorf("got error at line %d column %d (%s), want line 17 column 33", pe.Line, pe.Column, pe.Message)
+	}
+}
+
+func TestSplitDumpStatements(t *testing.T) {
+	options := DefaultFormatOptions.WithLanguage(LanguagePostgreSQL)
+	options.DumpFormat = DumpFormatPgDump
+	var kinds []dumpPartKind
+	var texts []string
+	for _, part := range splitDump(pgDump+"CREATE VIEW v AS SELECT '\n;' AS a, /* ;\n */ 1 AS b;\n", options) {
+		kinds = append(kinds, part.kind)
+		texts = append(texts, strings.TrimSpace(part.sql))
+	}
+	want := []dumpPartKind{dumpVerbatim, dumpSQL, dumpVerbatim, dumpSQL}
+	if len(kinds) != len(want) {
+		t.Fatalf("got parts %q, want %d parts", texts, len(want))
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("part %d %q: got kind %d, want %d", i, texts[i], kinds[i], want[i])
+		}
+	}
+	if !strings.HasSuffix(texts[3], "1 AS b;") {
+		t.Errorf("last part %q does not end with the view", texts[3])
+	}
+}
@@ -0,0 +1,147 @@
+package sqlfmt
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// EmbeddedPattern locates SQL embedded in files of other languages, such as the query strings of
+// Python or Ruby code: the SQL is the text between a match of Start and the next match of End. For
+// example, the Start sql\s*=\s*""" and the End """ find the triple-quoted strings assigned to
+// variables named sql in Python, and <<~SQL and (?m)^\s*SQL$ the SQL heredocs of Ruby.
+type EmbeddedPattern struct {
+	// Files is the pattern, in the syntax of path.Match, that the base name of the files holding
+	// the SQL matches, such as "*.py".
+	Files string `json:"files"`
+	// Start and End are the regular expressions, in the syntax of the regexp package, matching the
+	// text before and after the SQL.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// EmbeddedSQL is a piece of SQL found in a text by ExtractEmbedded.
+type EmbeddedSQL struct {
+	// Start and End are the byte offsets of the SQL in the text.
+	Start, End int
+	// SQL is the text between the delimiters, with the whitespace around the query.
+	SQL string
+}
+
+// MatchFile reports whether the files of p include the file at name, by its base name.
+func (p EmbeddedPattern) MatchFile(name string) bool {
+	ok, _ := path.Match(p.Files, path.Base(strings.ReplaceAll(name, `\`, "/")))
+	return ok
+}
+
+// compileEmbedded compiles the delimiters of patterns, in pairs.
+func compileEmbedded(patterns []EmbeddedPattern) ([][2]*regexp.Regexp, error) {
+	compiled := make([][2]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		if p.Start == "" || p.End == "" {
+			return nil, fmt.Errorf("embedded pattern %d: start and end are required", i+1)
+		}
+		if _, err := path.Match(p.Files, ""); err != nil {
+			return nil, fmt.Errorf("embedded pattern %d: files %q: %w", i+1, p.Files, err)
+		}
+		for j, expr := range []string{p.Start, p.End} {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("embedded pattern %d: %w", i+1, err)
+			}
+			compiled[i][j] = re
+		}
+	}
+	return compiled, nil
+}
+
+// ExtractEmbedded returns the SQL that patterns find in text, in order. Whether the files of a
+// pattern include text is left to the caller (see EmbeddedPattern.MatchFile). Where the matches of
+// several patterns overlap, the one starting first wins; a Start without an End is ignored.
+func ExtractEmbedded(text string, patterns []EmbeddedPattern) ([]EmbeddedSQL, error) {
+	compiled, err := compileEmbedded(patterns)
+	if err != nil {
+		return nil, err
+	}
+	var found []EmbeddedSQL
+	for _, delims := range compiled {
+		for pos := 0; pos <= len(text); {
+			start := delims[0].FindStringIndex(text[pos:])
+			if start == nil {
+				break
+			}
+			sqlStart := pos + start[1]
+			end := delims[1].FindStringIndex(text[sqlStart:])
+			if end == nil {
+				break
+			}
+			sqlEnd := sqlStart + end[0]
+			found = append(found, EmbeddedSQL{sqlStart, sqlEnd, text[sqlStart:sqlEnd]})
+			pos = max(sqlStart+end[1], pos+1) // empty delimiters must not match forever
+		}
+	}
+
+	slices.SortStableFunc(found, func(a, b EmbeddedSQL) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	var kept []EmbeddedSQL
+	for _, e := range found {
+		if len(kept) == 0 || e.Start >= kept[len(kept)-1].End {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
+// FormatEmbedded formats the SQL that patterns find in text (see ExtractEmbedded) and leaves the
+// rest of text unchanged. Each query keeps the whitespace around it, such as the line breaks after
+// and before its delimiters, and its lines are indented like the first one, or if it starts on the
+// line of its opening delimiter, like that line. Blank pieces are left alone.
+func (f *Formatter) FormatEmbedded(text string, patterns []EmbeddedPattern, options FormatOptions) (string, error) {
+	if err := f.check("FormatEmbedded"); err != nil {
+		return "", err
+	}
+	embedded, err := ExtractEmbedded(text, patterns)
+	if err != nil {
+		return "", err
+	}
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, e := range embedded {
+		query := strings.TrimSpace(e.SQL)
+		if query == "" {
+			continue
+		}
+		formatted, err := f.Format(query, options)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", 1+strings.Count(text[:e.Start], "\n"), err)
+		}
+		leading := e.SQL[:strings.Index(e.SQL, query)]
+		trailing := e.SQL[len(leading)+len(query):]
+		indent := lineIndent(text, e.Start)
+		if nl := strings.LastIndexByte(leading, '\n'); nl >= 0 {
+			indent = leading[nl+1:]
+		}
+
+		b.WriteString(text[last:e.Start])
+		b.WriteString(leading)
+		for i, line := range strings.Split(formatted, "\n") {
+			if i > 0 {
+				b.WriteByte('\n')
+				if line != "" {
+					b.WriteString(indent)
+				}
+			}
+			b.WriteString(line)
+		}
+		b.WriteString(trailing)
+		last = e.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,101 @@
+package sqlfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+var pythonSQL = EmbeddedPattern{Files: "*.py", Start: `sql\s*=\s*"""`, End: `"""`}
+
+func TestEmbeddedPatternMatchFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app.py":          true,
+		"src/db/app.py":   true,
+		`src\db\app.py`:   true,
+		"app.rb":          false,
+		"src/app.py/main": false,
+	} {
+		if got := pythonSQL.MatchFile(name); got != want {
+			t.Errorf("MatchFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExtractEmbedded(t *testing.T) {
+	text := `sql = """select 1"""
+x = 1
+sql="""select 2"""
+sql = """unterminated`
+	got, err := ExtractEmbedded(text, []EmbeddedPattern{
+		pythonSQL,
+		{Start: `"""`, End: `"""`}, // overlaps the first, which starts earlier
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EmbeddedSQL{
+		{9, 17, "select 1"},
+		{34, 42, "select 2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, p := range []EmbeddedPattern{
+		{Files: "*.py", Start: `"""`},
+		{Files: "[", Start: `"""`, End: `"""`},
+		{Files: "*.py", Start: `(`, End: `"""`},
+	} {
+		if _, err := ExtractEmbedded(text, []EmbeddedPattern{p}); err == nil {
+			t.Errorf("%+v: no error", p)
+		}
+	}
+}
+
+func TestFormatEmbedded(t *testing.T) {
+	text := `def users():
+    sql = """
+        select id, name from users where active
+    """
+    return sql
+
+def empty():
+    sql = """ """
+`
+	want := `def users():
+    sql = """
+        SELECT
+            id,
+            name
+        FROM
+            users
+        WHERE
+            active
+    """
+    return sql
+
+def empty():
+    sql = """ """
+`
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := f.FormatEmbedded(text, []EmbeddedPattern{pythonSQL}, DefaultFormatOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	ruby := "rows = db.query(<<~SQL)\n  select 1\nSQL\n"
+	got, err = f.FormatEmbedded(ruby, []EmbeddedPattern{{Files: "*.rb", Start: `<<~SQL\)`, End: `(?m)^\s*SQL$`}}, DefaultFormatOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "rows = db.query(<<~SQL)\n  SELECT\n      1\nSQL\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
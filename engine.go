@@ -0,0 +1,21 @@
+package sqlfmt
+
+import "fmt"
+
+// engineConfig holds what is needed to create an engine.
+type engineConfig struct {
+	bundle       []byte // sql-formatter bundle
+	maxStackSize int    // QuickJS stack limit in bytes, or 0 for the QuickJS default
+}
+
+// warmupSQL is formatted by warm to exercise the formatter's code paths.
+const warmupSQL = "SELECT a, COUNT(*) FROM t WHERE b = 1 GROUP BY a"
+
+// warm runs a trivial format so that the first real call does not pay for lazy initialization
+// inside the backend.
+func (e *engine) warm() error {
+	if _, err := e.formatSQL(warmupSQL, FormatOptions{}); err != nil {
+		return fmt.Errorf("warming up: %w", err)
+	}
+	return nil
+}
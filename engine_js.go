@@ -0,0 +1,103 @@
+//go:build js && wasm && !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// Backend names the engine the package was built with (see the QuickJS build).
+const Backend = "js"
+
+// setupOnce evaluates the sql-formatter bundle and setupCode in the host's global scope. The host
+// has a single JavaScript realm, so every engine shares it.
+var (
+	setupOnce sync.Once
+	setupErr  error
+)
+
+// engine calls sql-formatter through the JavaScript runtime hosting the WebAssembly module
+// (a browser or Node.js) instead of QuickJS, which needs cgo. The host runs JavaScript on a single
+// thread that all goroutines share, so the engines of a pool only bound the number of formats in
+// flight; they are not separate contexts.
+type engine struct {
+	stats  atomic.Pointer[ContextStats] // set by measure
+	broken atomic.Bool                  // a format panicked; the pool discards the engine
+	guard  engineGuard
+}
+
+// needsBundle reports whether the sql-formatter bundle must be loaded: it is not needed if the host
+// already defines the global sqlFormatter.
+func needsBundle() bool {
+	return js.Global().Get("sqlFormatter").IsUndefined()
+}
+
+// newEngine evaluates the sql-formatter bundle in the host, unless the host already defines the
+// global sqlFormatter, for example because the page loaded sql-formatter itself. maxStackSize is
+// ignored: the host sets its own stack limit.
+func newEngine(config engineConfig) (*engine, error) {
+	setupOnce.Do(func() {
+		if needsBundle() {
+			if err := eval(string(config.bundle)); err != nil {
+				setupErr = fmt.Errorf("evaluating sql-formatter.min.js: %w", err)
+				return
+			}
+		}
+		if err := eval(setupCode); err != nil {
+			setupErr = fmt.Errorf("setting up formatSql function: %w", err)
+		}
+	})
+	if setupErr != nil {
+		return nil, setupErr
+	}
+	return &engine{}, nil
+}
+
+// eval evaluates code in the global scope: eval called indirectly, through the global object,
+// declares its functions and variables as globals.
+func eval(code string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = jsError(r)
+		}
+	}()
+	js.Global().Call("eval", code)
+	return nil
+}
+
+// call calls the global JavaScript function fn with args. A string result is returned as a Go
+// string, like go-quickjs does.
+func (e *engine) call(fn string, args ...any) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = nil, jsError(r)
+		}
+	}()
+	v := js.Global().Call(fn, args...)
+	if v.Type() == js.TypeString {
+		return v.String(), nil
+	}
+	return v, nil
+}
+
+// jsError converts the value of a panic raised by syscall/js into an error. syscall/js panics with
+// a js.Error when the JavaScript code throws; any other panic is not ours to handle.
+func jsError(r any) error {
+	if err, ok := r.(js.Error); ok {
+		return errors.New(err.Value.Call("toString").String())
+	}
+	panic(r)
+}
+
+// measure records zero statistics: the host does not report the memory use of its JavaScript
+// heap.
+func (e *engine) measure() {
+	e.stats.Store(&ContextStats{})
+}
+
+// close does nothing: the functions defined in the host stay for the other engines.
+func (e *engine) close() {}
@@ -0,0 +1,100 @@
+//go:build tinygo || sqlfmt_native
+
+package sqlfmt
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Backend names the engine the package was built with (see the QuickJS build).
+const Backend = "native"
+
+// engine formats SQL in Go with nativeFormat instead of sql-formatter, for builds that cannot use
+// cgo or a JavaScript runtime, such as TinyGo. It holds no state, so the engines of a pool only
+// bound the number of formats in flight.
+type engine struct {
+	stats  atomic.Pointer[ContextStats] // set by measure
+	broken atomic.Bool                  // a format panicked; the pool discards the engine
+	guard  engineGuard
+}
+
+// needsBundle reports whether the sql-formatter bundle must be loaded, which the native backend
+// never needs.
+func needsBundle() bool {
+	return false
+}
+
+// loadBundle ignores the bundle given with WithBundlePath: the native backend does not use
+// sql-formatter.
+func loadBundle(string) ([]byte, error) {
+	return nil, nil
+}
+
+// newEngine returns an engine; config does not apply to the native backend.
+func newEngine(engineConfig) (*engine, error) {
+	return &engine{}, nil
+}
+
+// formatSQL formats sql with nativeFormat, which never fails.
+func (e *engine) formatSQL(sql string, options FormatOptions) (string, error) {
+	return nativeFormat(sql, options), nil
+}
+
+// keywordList returns the reserved words known to the package, separated by spaces, for every
+// language.
+func (e *engine) keywordList(LanguageOption) (string, error) {
+	words := make([]string, 0, len(keywords))
+	for w := range keywords {
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// dialect describes language from its lexicon, with the keywords and data types known to the
+// package.
+func (e *engine) dialect(language LanguageOption) (Dialect, error) {
+	lx := lexiconFor(language)
+	d := Dialect{StringQuotes: []string{"''"}, LineComments: []string{"--"}}
+	if lx.positionalParams {
+		d.Placeholders = append(d.Placeholders, "?")
+	}
+	for _, p := range lx.numberedParams {
+		d.Placeholders = append(d.Placeholders, string(p)+"1")
+	}
+	for _, p := range lx.namedParams {
+		d.Placeholders = append(d.Placeholders, string(p)+"name")
+	}
+	if lx.doubleQuoteStrings {
+		d.StringQuotes = append(d.StringQuotes, `""`)
+	} else {
+		d.IdentifierQuotes = append(d.IdentifierQuotes, `""`)
+	}
+	if lx.dollarQuotes {
+		d.StringQuotes = append(d.StringQuotes, "$$")
+	}
+	if lx.backtickIdents {
+		d.IdentifierQuotes = append(d.IdentifierQuotes, "``")
+	}
+	if lx.bracketIdents {
+		d.IdentifierQuotes = append(d.IdentifierQuotes, "[]")
+	}
+	if lx.hashComments {
+		d.LineComments = append(d.LineComments, "#")
+	}
+	for w := range keywords {
+		d.Keywords = append(d.Keywords, w)
+	}
+	for w := range dataTypes {
+		d.DataTypes = append(d.DataTypes, w)
+	}
+	return d, nil
+}
+
+// measure records zero statistics: the native backend has no JavaScript heap.
+func (e *engine) measure() {
+	e.stats.Store(&ContextStats{})
+}
+
+// close does nothing: the engine holds no resources.
+func (e *engine) close() {}
@@ -0,0 +1,130 @@
+//go:build !js && !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/0x6b/sqlfmt/internal/quickjs"
+)
+
+// Backend names the engine the package was built with: "quickjs" runs sql-formatter in QuickJS
+// contexts, "js" in the JavaScript host of a js/wasm build, and "native" formats in Go.
+const Backend = "quickjs"
+
+// needsBundle reports whether the sql-formatter bundle must be loaded, which QuickJS always needs.
+func needsBundle() bool {
+	return true
+}
+
+// engine is a JavaScript context with sql-formatter loaded. QuickJS records the stack of the thread
+// that creates a context and checks it for overflow on every call, so the context is owned by a
+// goroutine locked to its OS thread and all calls are dispatched to that goroutine.
+// An engine must not be used by more than one goroutine at a time.
+type engine struct {
+	jobs   chan func(*quickjs.JsContext)
+	stats  atomic.Pointer[ContextStats] // last measured by measure, or nil
+	broken atomic.Bool                  // a format panicked; the pool discards the engine
+	guard  engineGuard
+}
+
+// newEngine starts the goroutine owning a new JavaScript context and evaluates the sql-formatter
+// bundle in it.
+//
+// Evaluating the bundle dominates the cost of NewFormatter. QuickJS cannot snapshot an initialized
+// heap, so the bundle's top-level code runs in every context, but its bytecode is compiled once and
+// reused (see evalBundle). Long-running programs should still share a Formatter (or Clone one)
+// rather than create many.
+func newEngine(config engineConfig) (*engine, error) {
+	e := &engine{jobs: make(chan func(*quickjs.JsContext))}
+	ready := make(chan error)
+	go e.run(config, ready)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// run creates the context and executes jobs until the engine is closed, then frees the context.
+// The OS thread is never unlocked, so it exits together with the goroutine. The context is freed
+// on that thread rather than left to the finalizer set by quickjs.NewContext, which would run on
+// an arbitrary thread at an arbitrary time, if ever.
+func (e *engine) run(config engineConfig, ready chan<- error) {
+	runtime.LockOSThread()
+
+	ctx, err := quickjs.NewContext()
+	if err != nil {
+		ready <- fmt.Errorf("creating QuickJS context: %w", err)
+		return
+	}
+	if config.maxStackSize > 0 {
+		ctx.SetMaxStackSize(config.maxStackSize)
+	}
+
+	// Evaluate the sql-formatter bundle
+	if err := evalBundle(ctx, config.bundle); err != nil {
+		ctx.Free()
+		ready <- fmt.Errorf("evaluating sql-formatter.min.js: %w", err)
+		return
+	}
+
+	// Set up the functions called from Go
+	if _, err := ctx.Eval(setupCode, nil); err != nil {
+		ctx.Free()
+		ready <- fmt.Errorf("setting up formatSql function: %w", err)
+		return
+	}
+
+	ready <- nil
+	for job := range e.jobs {
+		job(ctx)
+	}
+	ctx.Free()
+}
+
+// call calls the global JavaScript function fn with args.
+func (e *engine) call(fn string, args ...any) (res any, err error) {
+	e.do(func(ctx *quickjs.JsContext) {
+		res, err = ctx.CallFunc(fn, args...)
+	})
+	return res, err
+}
+
+// measure records the memory use of the context, as returned by ContextStats. Computing it walks
+// the whole heap, so it is only done on demand.
+func (e *engine) measure() {
+	e.do(func(ctx *quickjs.JsContext) {
+		var stats ContextStats
+		stats.MemoryUsed, stats.Objects = ctx.MemoryUsage()
+		e.stats.Store(&stats)
+	})
+}
+
+// do runs job on the goroutine owning the context and waits for it to complete. A panic in job
+// would crash the program from that goroutine, so it is recovered there and raised again, as a
+// *PanicError, in the caller.
+func (e *engine) do(job func(*quickjs.JsContext)) {
+	done := make(chan *PanicError)
+	e.jobs <- func(ctx *quickjs.JsContext) {
+		var pe *PanicError
+		defer func() {
+			if r := recover(); r != nil {
+				pe = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+			done <- pe
+		}()
+		job(ctx)
+	}
+	if pe := <-done; pe != nil {
+		panic(pe)
+	}
+}
+
+// close stops the goroutine owning the context, which frees the context and its runtime.
+// The engine must not be used afterwards.
+func (e *engine) close() {
+	close(e.jobs)
+}
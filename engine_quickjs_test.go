@@ -0,0 +1,42 @@
+//go:build !js && !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleBytecode(t *testing.T) {
+	for range 2 {
+		f, err := NewFormatter(WithPoolSize(2), WithPrewarm(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Format("select a from t", DefaultFormatOptions)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "SELECT\n    a\nFROM\n    t"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	compiledBundle.mu.Lock()
+	sum, code := compiledBundle.sum, compiledBundle.code
+	compiledBundle.mu.Unlock()
+	if sum != sha256.Sum256(jsCode) || len(code) == 0 {
+		t.Error("the bytecode of the bundle was not kept")
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.js")
+	if err := os.WriteFile(path, []byte("var sqlFormatter = {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFormatter(WithBundlePath(path)); err == nil || !strings.Contains(err.Error(), "SyntaxError") {
+		t.Errorf("got error %v for a broken bundle, want a SyntaxError", err)
+	}
+}
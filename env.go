@@ -0,0 +1,62 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the names of the environment variables read by ApplyEnv.
+const EnvPrefix = "SQLFMT_"
+
+// EnvVar returns the environment variable that sets the option with the given JSON name,
+// for example SQLFMT_KEYWORD_CASE for keywordCase.
+func EnvVar(option string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range option {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// ApplyEnv overrides the formatting options of c with the SQLFMT_* environment variables that
+// are set, such as SQLFMT_LANGUAGE=postgresql or SQLFMT_TAB_WIDTH=2 (see EnvVar). It is meant to be
+// applied after loading the configuration file, so that the environment takes precedence.
+func (c *Config) ApplyEnv() error {
+	values := make(map[string]string)
+	for _, option := range optionNames() {
+		if value, ok := os.LookupEnv(EnvVar(option)); ok {
+			values[option] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	options, err := setOptions(c.FormatOptions, values)
+	if err != nil {
+		return fmt.Errorf("applying %s environment variables: %w", EnvPrefix+"*", err)
+	}
+	c.FormatOptions = options
+	return nil
+}
+
+// optionNames returns the JSON names of the options that can be set from text.
+func optionNames() []string {
+	var names []string
+	t := reflect.TypeOf(FormatOptions{})
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
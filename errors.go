@@ -0,0 +1,138 @@
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError is returned when sql-formatter fails to parse the input.
+// Line and Column are 1-based and are zero when sql-formatter does not report a location.
+type ParseError struct {
+	// Message is the first line of the message reported by sql-formatter.
+	Message string
+	// Line and Column locate the offending input.
+	Line, Column int
+	// Near is the offending input text as reported by sql-formatter.
+	Near string
+	// Suggestions lists likely misspelled keywords in the failing statement.
+	Suggestions []Suggestion
+}
+
+// Suggestion is a did-you-mean hint for a word in keyword position that is not a keyword of the dialect.
+type Suggestion struct {
+	// Word is the unknown word as written in the input.
+	Word string
+	// Line and Column locate Word in the input (1-based).
+	Line, Column int
+	// Keywords lists the closest keywords, best match first.
+	Keywords []string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, s := range e.Suggestions {
+		fmt.Fprintf(&b, "; did you mean %s instead of %s at line %d column %d?",
+			strings.Join(s.Keywords, " or "), s.Word, s.Line, s.Column)
+	}
+	return b.String()
+}
+
+// PanicError is returned when formatting panics, in the JavaScript bridge or in the package
+// itself, instead of letting the panic crash the program. It is a bug; Value and Stack tell where.
+// The JavaScript context that was in use is discarded rather than reused.
+type PanicError struct {
+	// Value is the value the code panicked with.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal error: panic: %v", e.Value)
+}
+
+// panicError converts the value r recovered while e was formatting into a *PanicError, and marks e
+// as broken so that the pool discards it.
+func panicError(e *engine, r any) *PanicError {
+	e.broken.Store(true)
+	if pe, ok := r.(*PanicError); ok {
+		return pe
+	}
+	return &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+var (
+	// tokenizerErrorRegex matches: Parse error: Unexpected "..." at line 1 column 8.
+	tokenizerErrorRegex = regexp.MustCompile(`Parse error: Unexpected "((?s:.*?))" at line (\d+) column (\d+)`)
+	// parserErrorRegex matches: Parse error at token: FROM at line 1 column 30
+	parserErrorRegex = regexp.MustCompile(`Parse error at token: (.*?) at line (\d+) column (\d+)`)
+	// errorLocationRegex matches the location at the end of both: at line 1 column 8.
+	errorLocationRegex = regexp.MustCompile(`at line (\d+) column \d+$`)
+)
+
+// formatError converts an error raised by the JavaScript formatSql function into a *ParseError
+// when it describes a syntax error in sql, and wraps it otherwise. e is the engine that raised it.
+func (f *Formatter) formatError(e *engine, err error, sql string, lang LanguageOption) error {
+	msg := err.Error()
+	m := tokenizerErrorRegex.FindStringSubmatch(msg)
+	if m == nil {
+		m = parserErrorRegex.FindStringSubmatch(msg)
+	}
+	if m == nil {
+		// QuickJS, then the messages of V8 and SpiderMonkey, the hosts of js/wasm builds.
+		if strings.Contains(msg, "InternalError: stack overflow") ||
+			strings.Contains(msg, "Maximum call stack size exceeded") ||
+			strings.Contains(msg, "InternalError: too much recursion") {
+			return fmt.Errorf("calling formatSql: %w", ErrStackOverflow)
+		}
+		return fmt.Errorf("calling formatSql: %w", err)
+	}
+
+	pe := &ParseError{Message: m[0], Near: m[1]}
+	pe.Line, _ = strconv.Atoi(m[2])
+	pe.Column, _ = strconv.Atoi(m[3])
+	pe.Suggestions = suggestKeywords(sql, lang, f.dialectKeywords(e, lang), offsetOf(sql, pe.Line, pe.Column))
+	return pe
+}
+
+// shiftError moves the location of a *ParseError in err from a part of a text, starting at the
+// beginning of the given line of the text, to the text. Other errors are returned as they are.
+func shiftError(err error, line int) error {
+	var pe *ParseError
+	if line <= 1 || !errors.As(err, &pe) || pe.Line == 0 {
+		return err
+	}
+	if m := errorLocationRegex.FindStringSubmatchIndex(pe.Message); m != nil {
+		pe.Message = pe.Message[:m[2]] + strconv.Itoa(pe.Line+line-1) + pe.Message[m[3]:]
+	}
+	pe.Line += line - 1
+	for i := range pe.Suggestions {
+		pe.Suggestions[i].Line += line - 1
+	}
+	return err
+}
+
+// offsetOf converts a 1-based line and column (counted in runes) to a byte offset in s.
+func offsetOf(s string, line, column int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := strings.IndexByte(s[offset:], '\n')
+		if i < 0 {
+			return len(s)
+		}
+		offset += i + 1
+	}
+	for c := 1; c < column && offset < len(s); c++ {
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+	return offset
+}
@@ -0,0 +1,91 @@
+package sqlfmt
+
+import "strings"
+
+// isExecutableComment reports whether the comment text is a MySQL executable comment: /*! ... */,
+// optionally with the minimum server version that runs it (/*!50100 ... */), or its MariaDB form
+// /*M!100100 ... */.
+func isExecutableComment(text string) bool {
+	return strings.HasPrefix(text, "/*!") || strings.HasPrefix(text, "/*M!")
+}
+
+// executablePrefix returns the length of the marker and version starting the executable comment
+// text, such as "/*!50100".
+func executablePrefix(text string) int {
+	marker := len("/*!")
+	if strings.HasPrefix(text, "/*M!") {
+		marker = len("/*M!")
+	}
+	return marker + countDigits(text[marker:])
+}
+
+// executableTokens returns the tokens of the executable comment t, whose SQL MySQL runs: the marker
+// with the version, the significant tokens of the SQL, and "*/". An unterminated comment is
+// returned whole.
+func executableTokens(t token, lang LanguageOption) []token {
+	body, ok := strings.CutSuffix(t.text, "*/")
+	if !ok {
+		return []token{t}
+	}
+	version := executablePrefix(body)
+	tokens := []token{{kind: tokenComment, text: body[:version], start: t.start}}
+	for _, inner := range significant(tokenize(body[version:], lang)) {
+		inner.start += t.start + version
+		tokens = append(tokens, inner)
+	}
+	return append(tokens, token{kind: tokenComment, text: "*/", start: t.start + len(body)})
+}
+
+// formatExecutableComments formats the SQL inside the executable comments of formatted with
+// formatExecutableComment.
+func formatExecutableComments(formatted string, options FormatOptions) string {
+	var b strings.Builder
+	b.Grow(len(formatted))
+	scanTokens(formatted, options.Language, func(t token) bool {
+		if t.kind == tokenComment && isExecutableComment(t.text) {
+			b.WriteString(formatExecutableComment(t.text, options))
+		} else {
+			b.WriteString(t.text)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// formatExecutableComment applies options.KeywordCase to the keywords of the executable comment
+// text and collapses the spaces between its tokens, keeping the line breaks, which may end line
+// comments. Strings, identifiers and the version are left alone.
+func formatExecutableComment(text string, options FormatOptions) string {
+	body, ok := strings.CutSuffix(text, "*/")
+	if !ok {
+		return text // unterminated
+	}
+	version := executablePrefix(body)
+
+	var b strings.Builder
+	b.WriteString(body[:version])
+	space := true // between the marker or version and the SQL
+	scanTokens(strings.TrimSpace(body[version:]), options.Language, func(t token) bool {
+		switch {
+		case t.kind == tokenSpace && strings.Contains(t.text, "\n"):
+			b.WriteString(t.text)
+			space = false
+			return true
+		case t.kind == tokenSpace:
+			space = true
+			return true
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if t.kind == tokenWord && isKeyword(t.text) {
+			b.WriteString(applyCase(t.text, options.KeywordCase))
+		} else {
+			b.WriteString(t.text)
+		}
+		return true
+	})
+	b.WriteString(" */")
+	return b.String()
+}
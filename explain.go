@@ -0,0 +1,168 @@
+package sqlfmt
+
+import "strings"
+
+// Explanation describes how Explain formatted a query, to find out why the output looks the way
+// it does.
+type Explanation struct {
+	// Output is the formatted query, as returned by Format.
+	Output string
+	// Tokens is the token stream of the input, whitespace excluded.
+	Tokens []ExplainedToken
+	// Statements are the statements found in the input.
+	Statements []StatementSpan
+	// Passes lists the steps of the formatting pipeline that ran, in order.
+	Passes []Pass
+	// Options lists, for every option that differs from its zero value, the lines of Output that
+	// change when the option is left unset. The native backend does not report them.
+	Options []OptionEffect
+	// Warnings are the warnings returned by FormatWithWarnings.
+	Warnings []Warning
+}
+
+// ExplainedToken is a token of the input.
+type ExplainedToken struct {
+	// Kind is the kind of token: comment, string, identifier, word, number, parameter, operator
+	// or punctuation.
+	Kind string
+	// Text is the token as written in the input.
+	Text string
+	// Line and Column locate the token in the input (1-based).
+	Line, Column int
+}
+
+// StatementSpan locates a statement of the input.
+type StatementSpan struct {
+	// Start and End are the byte offsets of the statement, as in StatementResult.
+	Start, End int
+	// Kind is the keyword that determines the kind of statement, as in StatementResult.
+	Kind string
+}
+
+// Pass is a step of the formatting pipeline.
+type Pass struct {
+	// Name identifies the step: dumpFormat, directives, checkOptions, transforms, sql-formatter,
+	// spaceBeforeParen, preserveLineBreaks, maxConsecutiveBlankLines, alignOperators,
+	// alignAliases or verifyTokens.
+	Name string
+	// Changed reports whether the step modified the query.
+	Changed bool
+	// Lines are the lines of the text produced by the step that differ from its input (1-based).
+	Lines []int
+	// Note describes what the step did, for steps that do not rewrite the query.
+	Note string
+}
+
+// OptionEffect describes the lines affected by an option.
+type OptionEffect struct {
+	// Option is the JSON name of the option and Value its value.
+	Option, Value string
+	// Lines are the lines of the output that differ when the option is left unset (1-based).
+	Lines []int
+}
+
+// tokenKindNames are the names of the token kinds in an Explanation and a LintToken.
+var tokenKindNames = map[tokenKind]string{
+	tokenSpace:    "whitespace",
+	tokenComment:  "comment",
+	tokenString:   "string",
+	tokenIdent:    "identifier",
+	tokenWord:     "word",
+	tokenNumber:   "number",
+	tokenParam:    "parameter",
+	tokenOperator: "operator",
+	tokenPunct:    "punctuation",
+}
+
+// Explain formats sql like FormatWithWarnings and reports the decisions behind the output. Finding
+// the lines affected by each option formats sql once more per option set, so Explain is meant
+// for debugging rather than for production use.
+func (f *Formatter) Explain(sql string, options FormatOptions) (*Explanation, error) {
+	if err := f.check("Explain"); err != nil {
+		return nil, err
+	}
+	e, err := f.pool.get()
+	if err != nil {
+		return nil, err
+	}
+	trace := &passTrace{}
+	output, warnings, err := f.formatWith(e, sql, options, trace)
+	f.pool.put(e)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &Explanation{Output: output, Passes: trace.passes, Warnings: warnings}
+	for _, t := range tokenize(sql, options.Language) {
+		if t.kind == tokenSpace {
+			continue
+		}
+		line, column := position(sql, t.start)
+		x.Tokens = append(x.Tokens, ExplainedToken{Kind: tokenKindNames[t.kind], Text: t.text, Line: line, Column: column})
+	}
+	for _, stmt := range splitStatements(sql, options.Language) {
+		x.Statements = append(x.Statements, StatementSpan{Start: stmt.start, End: stmt.end, Kind: stmt.kind})
+	}
+
+	x.Options = f.optionEffects(sql, options, output)
+	return x, nil
+}
+
+// passTrace records the passes of formatWith for Explain. A nil *passTrace records nothing.
+type passTrace struct {
+	passes []Pass
+}
+
+// record records a pass that turned before into after.
+func (t *passTrace) record(name, before, after string) {
+	if t == nil {
+		return
+	}
+	t.passes = append(t.passes, Pass{Name: name, Changed: before != after, Lines: changedLines(before, after)})
+}
+
+// note records a pass that does not rewrite the query.
+func (t *passTrace) note(name, note string) {
+	if t == nil {
+		return
+	}
+	t.passes = append(t.passes, Pass{Name: name, Note: note})
+}
+
+// changedLines returns the lines of after (1-based) that are not part of a longest common
+// subsequence of the lines of before and after, that is, the lines a diff would mark as added.
+func changedLines(before, after string) []int {
+	if before == after {
+		return nil
+	}
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var lines []int
+	i, j := 0, 0
+	for j < len(b) {
+		switch {
+		case i < len(a) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			lines = append(lines, j+1)
+			j++
+		}
+	}
+	return lines
+}
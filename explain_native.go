@@ -0,0 +1,9 @@
+//go:build tinygo || sqlfmt_native
+
+package sqlfmt
+
+// optionEffects returns nil: finding the options set needs the reflection left out of the native
+// backend.
+func (f *Formatter) optionEffects(sql string, options FormatOptions, output string) []OptionEffect {
+	return nil
+}
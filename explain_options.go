@@ -0,0 +1,48 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// optionEffects returns the effect on output, the formatted sql, of each option set in options.
+func (f *Formatter) optionEffects(sql string, options FormatOptions, output string) []OptionEffect {
+	var effects []OptionEffect
+	v := reflect.ValueOf(options)
+	t := v.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || name == "verifyTokens" || v.Field(i).IsZero() {
+			continue
+		}
+		unset := options
+		reflect.ValueOf(&unset).Elem().Field(i).SetZero()
+		alternative, err := f.Format(sql, unset)
+		if err != nil {
+			// The option is needed for the query to format at all, as a language can be.
+			continue
+		}
+		effects = append(effects, OptionEffect{
+			Option: name,
+			Value:  optionString(v.Field(i)),
+			Lines:  changedLines(alternative, output),
+		})
+	}
+	return effects
+}
+
+// optionString returns the value of an option as written in a directive.
+func optionString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int:
+		return strconv.Itoa(int(v.Int()))
+	}
+	return ""
+}
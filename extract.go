@@ -0,0 +1,115 @@
+package sqlfmt
+
+import "strings"
+
+// TableReference is an occurrence of a table (or view) in a query, as found by ExtractTables.
+type TableReference struct {
+	TableRef
+	// Alias is the alias the query gives the table, if any.
+	Alias string
+	// Start and End are the byte offsets of the possibly qualified name in the input.
+	Start, End int
+	// Line and Column locate Start (1-based).
+	Line, Column int
+}
+
+// ColumnReference is an occurrence of a column in a query, as found by ExtractColumns.
+type ColumnReference struct {
+	// Qualifier is the table name or alias qualifying the column, as written, if any.
+	Qualifier string
+	// Name is the name of the column, unquoted.
+	Name string
+	// Table is the table the column belongs to, when it can be told from the query: through the
+	// qualifier, resolving table aliases, or as the only table of the statement. It is the zero
+	// TableRef otherwise.
+	Table TableRef
+	// Start and End are the byte offsets of the possibly qualified name in the input.
+	Start, End int
+	// Line and Column locate Start (1-based).
+	Line, Column int
+}
+
+// ExtractTables returns every reference to a table or view in the statements of sql, in order,
+// with its position. Names of common table expressions are not tables. Like Analyze, it relies on
+// the tokenizer rather than a full parse.
+func ExtractTables(sql string, lang LanguageOption) []TableReference {
+	var tables []TableReference
+	for _, stmt := range extractStatements(sql, lang) {
+		tables = append(tables, stmt.tables...)
+	}
+	return tables
+}
+
+// ExtractColumns returns every reference to a column in the statements of sql, in order, with its
+// position and, when it can be determined, its table. Aliases of select expressions are not
+// columns.
+func ExtractColumns(sql string, lang LanguageOption) []ColumnReference {
+	var columns []ColumnReference
+	for _, stmt := range extractStatements(sql, lang) {
+		columns = append(columns, stmt.columns...)
+	}
+	return columns
+}
+
+// extracted holds the references found in a statement.
+type extracted struct {
+	tables  []TableReference
+	columns []ColumnReference
+}
+
+// extractStatements finds the table and column references of each statement of sql.
+func extractStatements(sql string, lang LanguageOption) []extracted {
+	var stmts []extracted
+	for _, stmt := range splitStatements(sql, lang) {
+		sig := significant(tokenize(sql[stmt.start:stmt.end], lang))
+		refs := scanNames(sig)
+		var x extracted
+		byName := make(map[string]TableRef) // tables of the statement by upper-cased alias and name
+		distinct := make(map[TableRef]bool)
+		for _, occ := range tableOccurrences(sig, refs) {
+			start, end := stmt.start+sig[occ.start].start, stmt.start+sig[occ.end].end
+			line, column := position(sql, start)
+			x.tables = append(x.tables, TableReference{
+				TableRef: occ.table,
+				Alias:    occ.alias,
+				Start:    start,
+				End:      end,
+				Line:     line,
+				Column:   column,
+			})
+			byName[strings.ToUpper(occ.table.Name)] = occ.table
+			if occ.alias != "" {
+				byName[strings.ToUpper(occ.alias)] = occ.table
+			}
+			distinct[occ.table] = true
+		}
+
+		for _, ref := range refs {
+			if ref.role != roleColumn {
+				continue
+			}
+			first := ref.index
+			for first >= 2 && sig[first-1].text == "." {
+				first -= 2
+			}
+			c := ColumnReference{Name: ref.name}
+			if first < ref.index {
+				var qualifier []string
+				for j := first; j < ref.index; j += 2 {
+					qualifier = append(qualifier, sig[j].text)
+				}
+				c.Qualifier = strings.Join(qualifier, ".")
+				c.Table = byName[strings.ToUpper(unquoteName(sig[ref.index-2]))]
+			} else if len(distinct) == 1 {
+				for t := range distinct {
+					c.Table = t
+				}
+			}
+			c.Start, c.End = stmt.start+sig[first].start, stmt.start+sig[ref.index].end
+			c.Line, c.Column = position(sql, c.Start)
+			x.columns = append(x.columns, c)
+		}
+		stmts = append(stmts, x)
+	}
+	return stmts
+}
@@ -0,0 +1,35 @@
+package sqlfmt
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// behaviorVersion numbers the revisions of what the package does around sql-formatter: the
+// transforms before it and the post-processing after it. It is increased by every change that
+// changes the output for some query and options, so that BehaviorFingerprint changes with it;
+// TestBehaviorFingerprint fails when the output for its corpus changes and the fingerprint does not.
+const behaviorVersion = 2
+
+// BehaviorFingerprint identifies the formatting behavior of the package: the backend, the embedded
+// sql-formatter bundle and the revision of the processing around it. Two builds with the same
+// fingerprint format every query the same way given the same options, so that a repository can pin
+// it (see Config.BehaviorFingerprint) and reformat in a reviewed change when an upgrade changes it.
+// The fingerprint is of the form "<backend>-<revision>-<hash>".
+func BehaviorFingerprint() string {
+	return behaviorFingerprint(jsCode)
+}
+
+// BehaviorFingerprint is like the package-level BehaviorFingerprint, for the bundle f was created
+// with, which differs from the embedded one with WithBundlePath.
+func (f *Formatter) BehaviorFingerprint() string {
+	return f.fingerprint
+}
+
+// behaviorFingerprint returns the BehaviorFingerprint of formatting with bundle.
+func behaviorFingerprint(bundle []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00", Backend, behaviorVersion)
+	h.Write(bundle) // empty with the native backend, which does not use sql-formatter
+	return fmt.Sprintf("%s-%d-%x", Backend, behaviorVersion, h.Sum(nil)[:8])
+}
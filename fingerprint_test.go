@@ -0,0 +1,164 @@
+package sqlfmt
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestBehaviorFingerprint")
+
+// behaviorCorpus exercises the processing around sql-formatter, so that a change to its output
+// is noticed by TestBehaviorFingerprint.
+func behaviorCorpus() []struct {
+	name    string
+	sql     string
+	options FormatOptions
+} {
+	with := func(change func(*FormatOptions)) FormatOptions {
+		options := DefaultFormatOptions
+		change(&options)
+		return options
+	}
+	postgres := with(func(o *FormatOptions) { o.Language = LanguagePostgreSQL })
+	mysql := with(func(o *FormatOptions) { o.Language = LanguageMySQL })
+	return []struct {
+		name    string
+		sql     string
+		options FormatOptions
+	}{
+		{"defaults", "select a, b as c from t join u on t.id = u.id where a = 1 and b like 'x%' order by a desc limit 10", DefaultFormatOptions},
+		{"lower case", "SELECT COUNT(*) FROM T WHERE X IS NOT NULL GROUP BY Y HAVING COUNT(*) > 1", with(func(o *FormatOptions) {
+			o.KeywordCase, o.FunctionCase, o.IdentifierCase = CaseOptionLower, CaseOptionLower, CaseOptionLower
+		})},
+		{"tabs", "select a from t where b in (select b from u)", with(func(o *FormatOptions) { o.UseTabs = true })},
+		{"tabular indent", "select a, b from t where a = 1 and b = 2", with(func(o *FormatOptions) { o.IndentStyle = IndentStyleTabularLeft })},
+		{"lines between queries", "select 1; select 2;\n\n\n\nselect 3;", with(func(o *FormatOptions) {
+			o.LinesBetweenQueries, o.MaxConsecutiveBlankLines = 2, 1
+		})},
+		{"no lines between queries", "select 1; select 2;", with(func(o *FormatOptions) { o.LinesBetweenQueries = NoLinesBetweenQueries })},
+		{"dense operators", "select a + b * 2, c - d from t", with(func(o *FormatOptions) { o.DenseOperators = true })},
+		{"newline before semicolon", "select 1;", with(func(o *FormatOptions) { o.NewlineBeforeSemicolon = true })},
+		{"logical operator after", "select a from t where a = 1 and (b = 2 or c = 3) and d = 4", with(func(o *FormatOptions) {
+			o.LogicalOperatorNewline, o.PredicateChainWidth = LogicalOperatorNewlineAfter, 30
+		})},
+		{"predicate chains", "select a from t where active = 1 and deleted_at is null and (x = 1 or y = 2)", with(func(o *FormatOptions) { o.PredicateChainWidth = 60 })},
+		{"aligned", "select a as first, bb as second from t where a = 1 and bbb >= 2", with(func(o *FormatOptions) { o.AlignAliases, o.AlignOperators = true, true })},
+		{"numbers", "select 1e3, .5, 1000000 from t", with(func(o *FormatOptions) {
+			o.NumberCase, o.LeadingZero, o.GroupDigits = CaseOptionUpper, LeadingZeroAlways, true
+		})},
+		{"comments", "-- leading\nselect a, /* inline */ b from t -- trailing\nwhere a = 1", DefaultFormatOptions},
+		{"hints", "select /*+ INDEX(t i) */ a from t", mysql},
+		{"executable comments", "/*!40101 SET NAMES utf8mb4 */;\nselect 1", mysql},
+		{"sqlx binds", "select * from t where a = :a and b in (?) and c = $1::int", with(func(o *FormatOptions) {
+			o.Language, o.SQLXBinds = LanguagePostgreSQL, true
+		})},
+		{"plpgsql", "create function f() returns int as $$ begin return 1; end; $$ language plpgsql", postgres},
+		{"order by dependencies", "create view v as select a from t; create table t(a int); alter table t add column b int;", with(func(o *FormatOptions) {
+			o.Language, o.OrderByDependencies = LanguagePostgreSQL, true
+		})},
+		{"sort schema", "create table b(x int); create index i on a(x); create table a(x int);", with(func(o *FormatOptions) {
+			o.Language, o.SortSchema = LanguagePostgreSQL, true
+		})},
+		{"pg_dump", "SET statement_timeout = 0;\n\\connect app\nCREATE TABLE t (a integer);\nCOPY t (a) FROM stdin;\n1\n2\n\\.\ninsert into t values (1), (2);\n", with(func(o *FormatOptions) {
+			o.DumpFormat, o.DumpInserts = DumpFormatPgDump, DumpInsertsCompact
+		})},
+		{"mysqldump", "LOCK TABLES `t` WRITE;\nINSERT INTO `t` VALUES (1,'a'),(2,'b');\nUNLOCK TABLES;\n", with(func(o *FormatOptions) {
+			o.DumpFormat, o.DumpInserts = DumpFormatMySQLDump, DumpInsertsCompact
+		})},
+		{"generated", "-- Code generated by sqlc. DO NOT EDIT.\nselect   1", DefaultFormatOptions},
+	}
+}
+
+// TestBehaviorFingerprint fails when the output for the corpus changes while BehaviorFingerprint
+// stays the same, that is, when behaviorVersion was not increased with a change of behavior. The
+// golden file records the fingerprint with the output; go test -run TestBehaviorFingerprint
+// -update rewrites it, but only once the fingerprint has changed.
+func TestBehaviorFingerprint(t *testing.T) {
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "fingerprint %s\n", f.BehaviorFingerprint())
+	for _, c := range behaviorCorpus() {
+		out, err := f.Format(c.sql, c.options)
+		if err != nil {
+			out = "error: " + err.Error()
+		}
+		fmt.Fprintf(&b, "\n== %s\n%s\n", c.name, out)
+	}
+	got := b.String()
+
+	path := filepath.Join("testdata", "fingerprint", Backend+".golden")
+	data, err := os.ReadFile(path)
+	if err != nil && !(*update && os.IsNotExist(err)) {
+		t.Fatalf("%v; run go test -run TestBehaviorFingerprint -update to create it", err)
+	}
+	want := string(data)
+	sameFingerprint := firstLine(got) == firstLine(want)
+	if got != want && sameFingerprint {
+		t.Fatalf("the output for the corpus differs from %s, but BehaviorFingerprint is the same: increase behaviorVersion, then run go test -run TestBehaviorFingerprint -update\n%s",
+			path, firstDifference(got, want))
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !sameFingerprint {
+		t.Fatalf("BehaviorFingerprint changed from %q to %q: run go test -run TestBehaviorFingerprint -update and review the output of %s",
+			firstLine(want), firstLine(got), path)
+	}
+}
+
+// TestFingerprintBackendsAgree fails when the golden files of the backends running sql-formatter
+// differ other than in their fingerprint, which happens when only one of them was updated.
+func TestFingerprintBackendsAgree(t *testing.T) {
+	var bodies []string
+	for _, backend := range []string{"quickjs", "js"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "fingerprint", backend+".golden"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, body, _ := strings.Cut(string(data), "\n")
+		bodies = append(bodies, body)
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("quickjs.golden and js.golden differ; update both, running the js backend with GOOS=js GOARCH=wasm\n%s",
+			firstDifference(bodies[1], bodies[0]))
+	}
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// firstDifference returns the first line that differs between got and want, with its number.
+func firstDifference(got, want string) string {
+	g, w := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := range max(len(g), len(w)) {
+		var gl, wl string
+		if i < len(g) {
+			gl = g[i]
+		}
+		if i < len(w) {
+			wl = w[i]
+		}
+		if gl != wl {
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, gl, wl)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package sqlfmt
+
+import (
+	"cmp"
+	"strings"
+)
+
+// DefaultGeneratedMarker is the marker of generated SQL used when FormatOptions.GeneratedMarker is
+// not set, written in a comment at the top of the file:
+//
+//	-- sqlfmt:generated
+const DefaultGeneratedMarker = "sqlfmt:generated"
+
+// IsGenerated reports whether sql is marked as generated: whether a comment before its first
+// statement starts with marker, or DefaultGeneratedMarker if marker is "". The sqlfmt command
+// leaves generated files as they are, so that the output of code generators does not fight the
+// formatter; other tools can use IsGenerated to do the same. Markers such as "Code generated"
+// match the comments many generators already write.
+func IsGenerated(sql string, lang LanguageOption, marker string) bool {
+	generated := false
+	scanTokens(sql, lang, func(t token) bool {
+		switch t.kind {
+		case tokenSpace:
+			return true
+		case tokenComment:
+			generated = isGeneratedMarker(t.text, marker)
+			return !generated
+		}
+		return false
+	})
+	return generated
+}
+
+// isGeneratedMarker reports whether the comment starts with marker, or DefaultGeneratedMarker if
+// marker is "".
+func isGeneratedMarker(comment, marker string) bool {
+	return strings.HasPrefix(commentBody(comment), cmp.Or(marker, DefaultGeneratedMarker))
+}
@@ -2,6 +2,4 @@ module github.com/0x6b/sqlfmt
 
 go 1.24.4
 
-require github.com/rosbit/go-quickjs v0.6.0
-
-require github.com/rosbit/go-embedding-utils v0.4.1 // indirect
+require github.com/rosbit/go-embedding-utils v0.4.1
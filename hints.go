@@ -0,0 +1,75 @@
+package sqlfmt
+
+import "strings"
+
+// hintSet holds the optimizer hints taken out of a query by extractHints.
+type hintSet struct {
+	hints  []hint
+	tokens int // number of significant tokens of the query
+}
+
+// hint is an optimizer hint comment, such as /*+ INDEX(t idx_a) */, which Oracle and MySQL only
+// honor right after the keyword it modifies, or a MySQL executable comment, such as
+// /*! STRAIGHT_JOIN */, which is part of the query.
+type hint struct {
+	text  string
+	after int // index of the significant token the hint follows
+}
+
+// isHint reports whether t is an optimizer hint or an executable comment.
+func isHint(t token) bool {
+	return t.kind == tokenComment && (strings.HasPrefix(t.text, "/*+") || isExecutableComment(t.text))
+}
+
+// extractHints replaces the optimizer hints of sql with a space, so that sql-formatter lays the
+// query out as if they were not there rather than moving them to a line of their own, away from
+// their keyword. Hints that start a statement, such as the /*!40101 SET NAMES utf8mb4 */; of
+// mysqldump, are statements of their own and are left in place. It returns sql unchanged and nil
+// if there is no hint to extract.
+func extractHints(sql string, lang LanguageOption) (string, *hintSet) {
+	var (
+		b    strings.Builder
+		set  hintSet
+		last string // last significant token
+	)
+	scanTokens(sql, lang, func(t token) bool {
+		switch {
+		case isHint(t) && last != "" && last != ";":
+			set.hints = append(set.hints, hint{t.text, set.tokens - 1})
+			b.WriteByte(' ')
+			return true
+		case t.kind != tokenSpace && t.kind != tokenComment:
+			set.tokens++
+			last = t.text
+		}
+		b.WriteString(t.text)
+		return true
+	})
+	if set.hints == nil {
+		return sql, nil
+	}
+	return b.String(), &set
+}
+
+// restore puts the hints back into formatted, the formatted query, each after the token it
+// followed separated by a space, and exactly as written. It reports false if formatted does not
+// have the tokens of the query, so that the hints cannot be placed.
+func (set *hintSet) restore(formatted string, lang LanguageOption) (string, bool) {
+	var b strings.Builder
+	hints := set.hints
+	n := 0
+	scanTokens(formatted, lang, func(t token) bool {
+		b.WriteString(t.text)
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			return true
+		}
+		for len(hints) > 0 && hints[0].after == n {
+			b.WriteByte(' ')
+			b.WriteString(hints[0].text)
+			hints = hints[1:]
+		}
+		n++
+		return true
+	})
+	return b.String(), n == set.tokens && len(hints) == 0
+}
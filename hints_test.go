@@ -0,0 +1,84 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHints(t *testing.T) {
+	long := "/*+ INDEX(orders orders_customer_idx) LEADING(c o) use_nl(o) NO_MERGE(v) PARALLEL(o 4) */"
+	tests := []struct {
+		name    string
+		lang    LanguageOption
+		sql     string
+		options func(FormatOptions) FormatOptions
+		want    string
+	}{
+		{
+			name: "select",
+			lang: LanguageMySQL,
+			sql:  "select /*+ MAX_EXECUTION_TIME(1000) */ a, b from t",
+			want: "SELECT /*+ MAX_EXECUTION_TIME(1000) */\n    a,\n    b\nFROM\n    t",
+		},
+		{
+			name: "update keeps the spacing inside the hint",
+			lang: LanguagePLSQL,
+			sql:  "update /*+ INDEX(t  t_idx) */ t set a = 1 where b = 2",
+			want: "UPDATE /*+ INDEX(t  t_idx) */ t\nSET\n    a = 1\nWHERE\n    b = 2",
+		},
+		{
+			name: "delete keeps the case inside the hint",
+			lang: LanguagePLSQL,
+			sql:  "delete /*+ FULL(t) parallel(t, 4) */ from t where a = 1",
+			want: "DELETE /*+ FULL(t) parallel(t, 4) */ FROM t\nWHERE\n    a = 1",
+		},
+		{
+			name: "lower-cased keywords",
+			lang: LanguageMySQL,
+			sql:  "SELECT /*+ BKA(t1) */ * FROM t1 WHERE x IN (SELECT /*+ NO_BKA(t2) */ y FROM t2)",
+			options: func(o FormatOptions) FormatOptions {
+				return o.WithKeywordCase(CaseOptionLower)
+			},
+			want: "select /*+ BKA(t1) */\n    *\nfrom\n    t1\nwhere\n    x in (\n        select /*+ NO_BKA(t2) */\n            y\n        from\n            t2\n    )",
+		},
+		{
+			name: "long hint over the expression width",
+			lang: LanguagePLSQL,
+			sql:  "select " + long + " o.id, c.name from orders o join customers c on c.id = o.customer_id",
+			options: func(o FormatOptions) FormatOptions {
+				return o.WithExpressionWidth(len(long) - 2)
+			},
+			want: "SELECT " + long + "\n    o.id,\n    c.name\nFROM\n    orders o\n    JOIN customers c ON c.id = o.customer_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultFormatOptions.WithLanguage(tt.lang)
+			if tt.options != nil {
+				options = tt.options(options)
+			}
+			got, err := Format(tt.sql, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHintsStayWithTheirKeyword(t *testing.T) {
+	sql := "select /*+ first_rows(10) */ a from t where b in (select /*+ HASH_SJ */ c from u)"
+	for _, width := range []int{10, 20, 40, 80} {
+		got, err := Format(sql, DefaultFormatOptions.WithLanguage(LanguagePLSQL).WithExpressionWidth(width))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{"SELECT /*+ first_rows(10) */", "SELECT /*+ HASH_SJ */"} {
+			if !strings.Contains(got, line+"\n") {
+				t.Errorf("expression width %d: output lacks the line %q:\n%s", width, line, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnstableFormat is matched by *UnstableFormatError.
+var ErrUnstableFormat = errors.New("formatting is not idempotent")
+
+// UnstableFormatError is returned when FormatOptions.VerifyIdempotent is set and formatting the
+// output again does not give the same output, so that formatting the file on every commit would
+// keep changing it.
+type UnstableFormatError struct {
+	// Output is the formatted SQL, and Reformatted the result of formatting Output again; it is
+	// empty if that failed.
+	Output, Reformatted string
+	// Line is the first line (1-based) that differs between Output and Reformatted.
+	Line int
+	// Diff is a unified diff from Output to Reformatted.
+	Diff string
+	// Err is the error formatting Output failed with, if it did.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *UnstableFormatError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v: formatting the output fails: %v", ErrUnstableFormat, e.Err)
+	}
+	return fmt.Sprintf("%v: line %d of the output changes when formatted again:\n%s", ErrUnstableFormat, e.Line, e.Diff)
+}
+
+// Is reports whether target is ErrUnstableFormat.
+func (e *UnstableFormatError) Is(target error) bool {
+	return target == ErrUnstableFormat
+}
+
+// Unwrap returns the error formatting the output failed with, if any.
+func (e *UnstableFormatError) Unwrap() error {
+	return e.Err
+}
+
+// formatIdempotent formats sql like formatWith, then formats the result again with the same
+// options and returns an *UnstableFormatError if that changes it.
+func (f *Formatter) formatIdempotent(e *engine, sql string, options FormatOptions, trace *passTrace) (string, []Warning, error) {
+	options.VerifyIdempotent = false
+	formatted, warnings, err := f.formatWith(e, sql, options, trace)
+	if err != nil {
+		return "", nil, err
+	}
+	again, _, err := f.formatWith(e, formatted, options, nil)
+	if err != nil {
+		return "", nil, &UnstableFormatError{Output: formatted, Err: err}
+	}
+	if again != formatted {
+		line, diff := lineDiff(formatted, again)
+		return "", nil, &UnstableFormatError{Output: formatted, Reformatted: again, Line: line, Diff: diff}
+	}
+	trace.note("verifyIdempotent", "formatting the output again does not change it")
+	return formatted, warnings, nil
+}
+
+// lineDiff returns the first line (1-based) that differs between a and b, and a unified diff with
+// a single hunk covering the lines from the first to the last that differ, with three lines of
+// context.
+func lineDiff(a, b string) (int, string) {
+	const context = 3
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	prefix := 0
+	for prefix < len(aLines) && prefix < len(bLines) && aLines[prefix] == bLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aLines)-prefix && suffix < len(bLines)-prefix &&
+		aLines[len(aLines)-1-suffix] == bLines[len(bLines)-1-suffix] {
+		suffix++
+	}
+
+	start := max(prefix-context, 0)
+	aEnd := min(len(aLines)-suffix+context, len(aLines))
+	bEnd := min(len(bLines)-suffix+context, len(bLines))
+	var d strings.Builder
+	fmt.Fprintf(&d, "--- output\n+++ reformatted\n@@ -%d,%d +%d,%d @@\n", start+1, aEnd-start, start+1, bEnd-start)
+	for _, l := range aLines[start:prefix] {
+		fmt.Fprintf(&d, " %s\n", l)
+	}
+	for _, l := range aLines[prefix : len(aLines)-suffix] {
+		fmt.Fprintf(&d, "-%s\n", l)
+	}
+	for _, l := range bLines[prefix : len(bLines)-suffix] {
+		fmt.Fprintf(&d, "+%s\n", l)
+	}
+	for _, l := range aLines[len(aLines)-suffix : aEnd] {
+		fmt.Fprintf(&d, " %s\n", l)
+	}
+	return prefix + 1, d.String()
+}
@@ -0,0 +1,5 @@
+// Package quickjs is a fork of github.com/rosbit/go-quickjs v0.6.0 (MIT, see LICENSE) that adds
+// the methods of fork.go, which sqlfmt needs and go-quickjs only has unexported equivalents of,
+// if any. The other files are those of go-quickjs, unchanged but for gofmt and build constraints.
+// It requires cgo.
+package quickjs
@@ -0,0 +1,92 @@
+package quickjs
+
+/*
+#include <stdlib.h>
+#include "quickjs-libc.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// Free frees the context and its runtime now, rather than leaving it to the finalizer set by
+// NewContext, which runs on an arbitrary thread at an arbitrary time, if ever. It must be called
+// from the thread that created the context, which must not be used afterwards.
+func (ctx *JsContext) Free() {
+	runtime.SetFinalizer(ctx, nil)
+	freeJsContext(ctx)
+}
+
+// SetMaxStackSize sets the stack size the runtime of the context may use before raising
+// "stack overflow".
+func (ctx *JsContext) SetMaxStackSize(size int) {
+	C.JS_SetMaxStackSize(C.JS_GetRuntime(ctx.c), C.size_t(size))
+}
+
+// MemoryUsage returns the number of bytes allocated by the runtime of the context and the number
+// of live objects in it. It walks the whole heap.
+func (ctx *JsContext) MemoryUsage() (bytes, objects int64) {
+	var usage C.JSMemoryUsage
+	C.JS_ComputeMemoryUsage(C.JS_GetRuntime(ctx.c), &usage)
+	return int64(usage.malloc_size), int64(usage.obj_count)
+}
+
+// IsModule reports whether script is an ES module rather than a script.
+func IsModule(script string) bool {
+	source := C.CString(script)
+	defer C.free(unsafe.Pointer(source))
+	return C.JS_DetectModule(source, C.size_t(len(script))) != 0
+}
+
+// Compile compiles script, which must not be a module, and returns its bytecode, which
+// EvalBytecode runs in any context of the same build. filename names it in stack traces.
+func (ctx *JsContext) Compile(script, filename string) ([]byte, error) {
+	source := C.CString(script) // JS_Eval needs a terminating NUL
+	defer C.free(unsafe.Pointer(source))
+	name := C.CString(filename)
+	defer C.free(unsafe.Pointer(name))
+
+	c := ctx.c
+	fn := C.JS_Eval(c, source, C.size_t(len(script)), name, C.JS_EVAL_TYPE_GLOBAL|C.JS_EVAL_FLAG_COMPILE_ONLY)
+	if C.JS_IsException(fn) != 0 {
+		return nil, exception(c)
+	}
+	defer C.JS_FreeValue(c, fn)
+
+	var size C.size_t
+	buf := C.JS_WriteObject(c, &size, fn, C.JS_WRITE_OBJ_BYTECODE)
+	if buf == nil {
+		return nil, exception(c)
+	}
+	defer C.js_free(c, unsafe.Pointer(buf))
+	return C.GoBytes(unsafe.Pointer(buf), C.int(size)), nil
+}
+
+// EvalBytecode runs a script compiled by Compile.
+func (ctx *JsContext) EvalBytecode(code []byte) error {
+	if len(code) == 0 {
+		return errors.New("no bytecode")
+	}
+	c := ctx.c
+	fn := C.JS_ReadObject(c, (*C.uint8_t)(unsafe.Pointer(&code[0])), C.size_t(len(code)), C.JS_READ_OBJ_BYTECODE)
+	if C.JS_IsException(fn) != 0 {
+		return exception(c)
+	}
+	res := C.JS_EvalFunction(c, fn) // frees fn
+	if C.JS_IsException(res) != 0 {
+		return exception(c)
+	}
+	C.JS_FreeValue(c, res)
+	return nil
+}
+
+// exception returns the pending exception of c as an error.
+func exception(c *C.JSContext) error {
+	if err := fromJsException(c); err != nil {
+		return err
+	}
+	return errors.New("exception without a stack") // fromJsException returns nil for those
+}
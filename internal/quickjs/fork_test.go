@@ -0,0 +1,51 @@
+//go:build cgo
+
+package quickjs
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBytecode(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	compiler, err := NewContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compiler.Free()
+	code, err := compiler.Compile("var answer = 6 * 7;", "answer.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compiler.Compile("var broken = {", "broken.js"); err == nil || !strings.Contains(err.Error(), "SyntaxError") {
+		t.Errorf("got error %v for a broken script, want a SyntaxError", err)
+	}
+
+	ctx, err := NewContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Free()
+	if err := ctx.EvalBytecode(code); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ctx.GetGlobal("answer"); err != nil || got != float64(42) {
+		t.Errorf("got answer %v, %v, want 42", got, err)
+	}
+	if err := ctx.EvalBytecode([]byte{1, 2, 3}); err == nil {
+		t.Error("no error for invalid bytecode")
+	}
+	if bytes, objects := ctx.MemoryUsage(); bytes <= 0 || objects <= 0 {
+		t.Errorf("got memory usage %d bytes, %d objects", bytes, objects)
+	}
+}
+
+func TestIsModule(t *testing.T) {
+	if IsModule("var a = 1;") || !IsModule("export const a = 1;") {
+		t.Error("IsModule does not tell modules from scripts")
+	}
+}
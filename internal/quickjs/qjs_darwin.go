@@ -0,0 +1,7 @@
+package quickjs
+
+/*
+#cgo CFLAGS: -I. -Wno-array-bounds -D_GNU_SOURCE -DCONFIG_BIGNUM -O2
+#cgo LDFLAGS: -lm
+*/
+import "C"
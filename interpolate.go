@@ -0,0 +1,228 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Errors returned by Interpolate.
+var (
+	ErrArgumentMismatch    = errors.New("placeholders and arguments do not match")
+	ErrUnsupportedArgument = errors.New("unsupported argument type")
+)
+
+// Interpolate renders args into the bind parameter placeholders of sql, producing a statement
+// that can be copied and run as-is. It is meant for debugging and logging only; never execute
+// its output in place of a parameterized query.
+//
+// Placeholders are recognized according to dialect: positional (?) placeholders consume args in
+// order, numbered placeholders ($1, ?1, :1) refer to args by 1-based index, and named placeholders
+// (:name, @name, $name) refer to sql.NamedArg values in args. Placeholders inside string literals,
+// quoted identifiers and comments are left untouched.
+//
+// Values are quoted and escaped using the conventions of dialect. Supported values are nil,
+// booleans, integers, floats, strings, []byte, time.Time, sql.NamedArg and driver.Valuer,
+// as well as named types and pointers to them.
+func Interpolate(sql string, args []any, dialect LanguageOption) (string, error) {
+	var positional []any
+	named := make(map[string]any)
+	for _, arg := range args {
+		if na, ok := arg.(namedArg); ok {
+			named[na.Name] = na.Value
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	var b strings.Builder
+	next := 0
+	for _, t := range tokenize(sql, dialect) {
+		if t.kind != tokenParam {
+			b.WriteString(t.text)
+			continue
+		}
+
+		var (
+			value any
+			found bool
+		)
+		switch name := t.text[1:]; {
+		case t.text == "?":
+			if next < len(positional) {
+				value, found = positional[next], true
+				next++
+			}
+		case name != "" && isDigit(name[0]):
+			idx, err := strconv.Atoi(name)
+			if err == nil && idx >= 1 && idx <= len(positional) {
+				value, found = positional[idx-1], true
+				next = max(next, idx)
+			}
+		default:
+			value, found = named[name]
+		}
+		if !found {
+			return "", fmt.Errorf("%w: no argument for placeholder %s", ErrArgumentMismatch, t.text)
+		}
+
+		literal, err := sqlLiteral(value, dialect)
+		if err != nil {
+			return "", fmt.Errorf("placeholder %s: %w", t.text, err)
+		}
+		b.WriteString(literal)
+	}
+
+	if next < len(positional) {
+		return "", fmt.Errorf("%w: %d arguments given but only %d used", ErrArgumentMismatch, len(positional), next)
+	}
+
+	return b.String(), nil
+}
+
+// namedArg aliases sql.NamedArg, whose package name is shadowed by the sql parameters used throughout this package.
+type namedArg = sql.NamedArg
+
+// sqlLiteral renders v as a SQL literal in the given dialect.
+func sqlLiteral(v any, dialect LanguageOption) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case namedArg:
+		return sqlLiteral(v.Value, dialect)
+	case driver.Valuer:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "NULL", nil
+		}
+		value, err := v.Value()
+		if err != nil {
+			return "", fmt.Errorf("calling Value: %w", err)
+		}
+		return sqlLiteral(value, dialect)
+	case time.Time:
+		return timeLiteral(v, dialect), nil
+	case []byte:
+		if v == nil {
+			return "NULL", nil
+		}
+		return bytesLiteral(v, dialect), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return sqlLiteral(rv.Elem().Interface(), dialect)
+	case reflect.Bool:
+		return boolLiteral(rv.Bool(), dialect), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("%w: non-finite float %v", ErrUnsupportedArgument, f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), nil
+	case reflect.String:
+		return stringLiteral(rv.String(), dialect), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			if rv.IsNil() {
+				return "NULL", nil
+			}
+			return bytesLiteral(rv.Bytes(), dialect), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedArgument, v)
+}
+
+// stringLiteral quotes s as a string literal in the given dialect. Dialects with backslash escapes
+// get \' for a quote, as some of them (BigQuery) reject a doubled quote and others (Hive, Spark)
+// read it as the end of one literal and the start of another.
+func stringLiteral(s string, dialect LanguageOption) string {
+	if lexiconFor(dialect).backslashEscapes {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, "'", `\'`)
+	} else {
+		s = strings.ReplaceAll(s, "'", "''")
+	}
+	if isTransactSQL(dialect) && !isASCII(s) {
+		return "N'" + s + "'"
+	}
+	return "'" + s + "'"
+}
+
+// boolLiteral renders b in the given dialect. Dialects without a boolean literal get 1 or 0.
+func boolLiteral(b bool, dialect LanguageOption) string {
+	if isTransactSQL(dialect) || dialect == LanguagePLSQL {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// bytesLiteral renders b as a binary literal in the given dialect.
+func bytesLiteral(b []byte, dialect LanguageOption) string {
+	h := hex.EncodeToString(b)
+	switch dialect {
+	case LanguagePostgreSQL, LanguageRedshift:
+		return `'\x` + h + `'::bytea`
+	case LanguageDuckDB:
+		var sb strings.Builder
+		for i := 0; i < len(h); i += 2 {
+			sb.WriteString(`\x`)
+			sb.WriteString(h[i : i+2])
+		}
+		return "'" + sb.String() + "'::BLOB"
+	case LanguageTransactSQL, LanguageTSQL:
+		return "0x" + h
+	case LanguagePLSQL:
+		return "HEXTORAW('" + h + "')"
+	case LanguageBigQuery:
+		return "FROM_HEX('" + h + "')"
+	default:
+		return "X'" + h + "'"
+	}
+}
+
+// timeLiteral renders t as a timestamp string literal in the given dialect.
+// Dialects that reject UTC offsets in timestamp literals get the wall-clock time only.
+func timeLiteral(t time.Time, dialect LanguageOption) string {
+	switch dialect {
+	case LanguageMariaDB, LanguageMySQL, LanguageTiDB, LanguageSingleStoreDB, LanguageSQLite,
+		LanguageTransactSQL, LanguageTSQL:
+		return "'" + t.Format("2006-01-02 15:04:05.999999") + "'"
+	default:
+		return "'" + t.Format("2006-01-02 15:04:05.999999-07:00") + "'"
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.FixedZone("", 9*3600))
+	blob := []byte{0xde, 0xad}
+	var null *string
+	tests := []struct {
+		dialect LanguageOption
+		sql     string
+		args    []any
+		want    string
+	}{
+		{LanguageSQL, "SELECT ?, ?, ?", []any{"it's", `a\b`, nil}, `SELECT 'it''s', 'a\b', NULL`},
+		{LanguageSQL, "SELECT ?, ?, ?", []any{at, blob, true}, "SELECT '2024-01-02 03:04:05.6+09:00', X'dead', TRUE"},
+		{LanguagePostgreSQL, "SELECT $2, $1, $1", []any{"it's", blob}, `SELECT '\xdead'::bytea, 'it''s', 'it''s'`},
+		{LanguagePostgreSQL, "SELECT $1", []any{at}, "SELECT '2024-01-02 03:04:05.6+09:00'"},
+		{LanguageMySQL, "SELECT ?, ?, ?, ?", []any{"it's", `a\b`, null, at}, `SELECT 'it\'s', 'a\\b', NULL, '2024-01-02 03:04:05.6'`},
+		{LanguageMySQL, "SELECT ?", []any{blob}, "SELECT X'dead'"},
+		{LanguageBigQuery, "SELECT ? AS x, @b", []any{"it's", sql.Named("b", blob)}, `SELECT 'it\'s' AS x, FROM_HEX('dead')`},
+		{LanguageBigQuery, "SELECT ?", []any{`a\'b`}, `SELECT 'a\\\'b'`},
+		{LanguageHive, "SELECT ?, ?, ?", []any{"it's", `a\b`, nil}, `SELECT 'it\'s', 'a\\b', NULL`},
+		{LanguageSpark, "SELECT ?, ?", []any{"it's", at}, `SELECT 'it\'s', '2024-01-02 03:04:05.6+09:00'`},
+		{LanguageSpark, "SELECT ?", []any{blob}, "SELECT X'dead'"},
+		{LanguageSQLite, "SELECT ?, :name, ?", []any{1, sql.Named("name", "it's"), `a\b`}, `SELECT 1, 'it''s', 'a\b'`},
+		{LanguageSQLite, "SELECT ?", []any{at}, "SELECT '2024-01-02 03:04:05.6'"},
+		{LanguageTransactSQL, "SELECT @a, @b, @c, @d", []any{sql.Named("a", "héllo"), sql.Named("b", false), sql.Named("c", blob), sql.Named("d", nil)},
+			"SELECT N'héllo', 0, 0xdead, NULL"},
+		{LanguagePLSQL, "SELECT :1, :2 FROM dual", []any{"it's", blob}, "SELECT 'it''s', HEXTORAW('dead') FROM dual"},
+		{LanguageDuckDB, "SELECT ?, ?", []any{"it's", blob}, `SELECT 'it''s', '\xde\xad'::BLOB`},
+		{LanguageSQL, "SELECT '?', ? -- ?", []any{2.5}, "SELECT '?', 2.5 -- ?"},
+	}
+	for _, tt := range tests {
+		got, err := Interpolate(tt.sql, tt.args, tt.dialect)
+		if err != nil {
+			t.Errorf("%s: Interpolate(%q): %v", tt.dialect, tt.sql, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Interpolate(%q) = %q, want %q", tt.dialect, tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	tests := []struct {
+		sql  string
+		args []any
+		want error
+	}{
+		{"SELECT ?, ?", []any{1}, ErrArgumentMismatch},
+		{"SELECT ?", []any{1, 2}, ErrArgumentMismatch},
+		{"SELECT :name", []any{1}, ErrArgumentMismatch},
+		{"SELECT ?", []any{struct{}{}}, ErrUnsupportedArgument},
+	}
+	for _, tt := range tests {
+		if _, err := Interpolate(tt.sql, tt.args, LanguageSQLite); !errors.Is(err, tt.want) {
+			t.Errorf("Interpolate(%q, %v): got error %v, want %v", tt.sql, tt.args, err, tt.want)
+		}
+	}
+}
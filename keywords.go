@@ -0,0 +1,73 @@
+package sqlfmt
+
+import "strings"
+
+// keywords is a dialect-agnostic set of reserved words and data type names.
+// It deliberately leaves out non-reserved words such as NAME, KEY or STATUS,
+// which are common column names, so that callers treating everything else as
+// an identifier err on the side of identifiers.
+var keywords = wordSet(`
+	ADD ALL ALTER ANALYZE AND ANY ARRAY AS ASC ASYMMETRIC AUTHORIZATION AUTO_INCREMENT
+	BEGIN BETWEEN BIGINT BINARY BIT BLOB BOOL BOOLEAN BOTH BY BYTEA
+	CALL CASCADE CASE CAST CHAR CHARACTER CHECK CLOB COLLATE COLUMN COMMIT CONCURRENTLY CONFLICT
+	CONSTRAINT CREATE CROSS CUBE CURRENT CURRENT_DATE CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
+	CURRENT_USER CURSOR
+	DATABASE DATE DATETIME DEALLOCATE DEC DECIMAL DECLARE DEFAULT DEFERRABLE DELETE DESC DESCRIBE
+	DISTINCT DO DOUBLE DROP
+	EACH ELSE ELSEIF END ESCAPE EXCEPT EXEC EXECUTE EXISTS EXPLAIN EXTRACT
+	FALSE FETCH FILTER FIRST FLOAT FOLLOWING FOR FOREIGN FROM FULL FUNCTION
+	GRANT GROUP GROUPING
+	HAVING
+	IF ILIKE IN INDEX INNER INOUT INSERT INT INTEGER INTERSECT INTERVAL INTO IS ISNULL
+	JOIN JSON JSONB
+	LAST LATERAL LEADING LEFT LIKE LIMIT LOCALTIME LOCALTIMESTAMP LOCK LONGTEXT
+	MATCHED MEDIUMINT MERGE MINUS
+	NATURAL NCHAR NEXT NO NOT NOTHING NOTNULL NULL NULLS NUMBER NUMERIC NVARCHAR
+	OF OFFSET ON ONLY OR ORDER OUT OUTER OVER OVERLAPS
+	PARTITION PERCENT PIVOT PRECEDING PRECISION PRIMARY PROCEDURE
+	QUALIFY
+	RANGE REAL RECURSIVE REFERENCES REGEXP RELEASE RENAME REPLACE RESTRICT RETURN RETURNING RETURNS
+	REVOKE RIGHT RLIKE ROLLBACK ROLLUP ROW ROWS
+	SAVEPOINT SCHEMA SELECT SEQUENCE SERIAL SESSION_USER SET SETS SHOW SIMILAR SMALLINT SOME START
+	SYMMETRIC
+	TABLE TABLESAMPLE TEMP TEMPORARY TEXT THEN TIES TIME TIMESTAMP TIMESTAMPTZ TINYINT TO TOP
+	TRAILING TRANSACTION TRIGGER TRUE TRUNCATE
+	UNBOUNDED UNION UNIQUE UNKNOWN UNNEST UNPIVOT UPDATE UPSERT USING UUID
+	VACUUM VALUES VARBINARY VARCHAR VARCHAR2 VARIADIC VARYING VIEW
+	WHEN WHERE WHILE WINDOW WITH WITHIN WITHOUT
+	XOR
+	ZONE
+`)
+
+// dataTypes are the keywords that name data types, which the native backend cases with
+// DataTypeCase rather than KeywordCase.
+var dataTypes = wordSet(`
+	BIGINT BINARY BIT BLOB BOOL BOOLEAN BYTEA CHAR CHARACTER CLOB DATE DATETIME DEC DECIMAL DOUBLE
+	FLOAT INT INTEGER INTERVAL JSON JSONB LONGTEXT MEDIUMINT NCHAR NUMBER NUMERIC NVARCHAR REAL
+	SERIAL SMALLINT TEXT TIME TIMESTAMP TIMESTAMPTZ TINYINT UUID VARBINARY VARCHAR VARCHAR2
+`)
+
+// wordSet builds a set of upper-cased words from a whitespace-separated list.
+func wordSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+// isKeyword reports whether the bare word w is a keyword.
+func isKeyword(w string) bool {
+	return keywords[strings.ToUpper(w)]
+}
+
+// applyCase converts word to the case c.
+func applyCase(word string, c CaseOption) string {
+	switch c {
+	case CaseOptionUpper:
+		return strings.ToUpper(word)
+	case CaseOptionLower:
+		return strings.ToLower(word)
+	}
+	return word
+}
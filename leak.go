@@ -0,0 +1,44 @@
+package sqlfmt
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// leakHandler is the function set with SetLeakHandler, or nil.
+var leakHandler atomic.Pointer[func(stack []byte)]
+
+// SetLeakHandler installs a function called when a Formatter is garbage-collected without having
+// been closed, with the stack trace of the NewFormatter or Clone call that created it. With or
+// without a handler, such a formatter is closed when it is collected, releasing its JavaScript
+// contexts.
+//
+// Capturing the stack costs a few microseconds per formatter, so leak detection is meant for
+// debugging and tests; it only applies to formatters created after the handler is installed.
+// Passing nil disables it.
+func SetLeakHandler(handler func(stack []byte)) {
+	if handler == nil {
+		leakHandler.Store(nil)
+		return
+	}
+	leakHandler.Store(&handler)
+}
+
+// trackLeak arranges for f to be closed when it is collected without Close, and reported to the
+// leak handler if one was installed when f was created. Close removes the finalizer.
+func trackLeak(f *Formatter) {
+	var stack []byte
+	if leakHandler.Load() != nil {
+		stack = debug.Stack()
+	}
+	runtime.SetFinalizer(f, func(f *Formatter) {
+		if f.closed.Load() {
+			return
+		}
+		if h := leakHandler.Load(); h != nil && stack != nil {
+			(*h)(stack)
+		}
+		_ = f.Close()
+	})
+}
@@ -0,0 +1,66 @@
+package sqlfmt
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// collect runs the garbage collector until done reports true, failing after a few seconds.
+func collect(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !done(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s after the formatters were collected", what)
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnclosedFormatterReleased(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 4 {
+		f, err := NewFormatter(WithIdleTimeout(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Format("select 1", DefaultFormatOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collect(t, "goroutines left running", func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestLeakHandler(t *testing.T) {
+	leaks := make(chan []byte, 2)
+	SetLeakHandler(func(stack []byte) { leaks <- stack })
+	t.Cleanup(func() { SetLeakHandler(nil) })
+
+	closed, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = closed.Close()
+	if _, err := NewFormatter(); err != nil {
+		t.Fatal(err)
+	}
+	var stack []byte
+	collect(t, "no leak reported", func() bool {
+		select {
+		case stack = <-leaks:
+			return true
+		default:
+			return false
+		}
+	})
+	if len(stack) == 0 {
+		t.Error("the leak was reported without a stack")
+	}
+	runtime.GC()
+	select {
+	case <-leaks:
+		t.Error("a closed formatter was reported as a leak")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
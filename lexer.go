@@ -0,0 +1,439 @@
+package sqlfmt
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies a lexical token produced by tokenize.
+type tokenKind int
+
+const (
+	// tokenSpace is a run of whitespace.
+	tokenSpace tokenKind = iota
+	// tokenComment is a line (-- or #) or block (/* */) comment.
+	tokenComment
+	// tokenString is a quoted string literal, including any prefix (E'', N'', X'') and dollar-quoted bodies.
+	tokenString
+	// tokenIdent is a quoted identifier ("x", `x` or [x]).
+	tokenIdent
+	// tokenWord is a bare word: a keyword, function name or unquoted identifier.
+	tokenWord
+	// tokenNumber is a numeric literal.
+	tokenNumber
+	// tokenParam is a bind parameter placeholder (?, $1, :name, @name, ...).
+	tokenParam
+	// tokenOperator is an operator such as =, <>, || or ::.
+	tokenOperator
+	// tokenPunct is one of ( ) [ ] { } , ; or .
+	tokenPunct
+)
+
+// token is a single lexical token. start and end are byte offsets into the source.
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// lexicon describes the lexical conventions of a SQL dialect.
+// It only covers what is needed to tell code apart from strings, identifiers, comments and placeholders.
+type lexicon struct {
+	backtickIdents     bool   // `ident`
+	bracketIdents      bool   // [ident]
+	doubleQuoteStrings bool   // "string" is a string literal rather than an identifier
+	backslashEscapes   bool   // 'it\'s'
+	hashComments       bool   // # comment
+	dollarQuotes       bool   // $tag$ ... $tag$
+	nestedComments     bool   // /* /* */ */
+	positionalParams   bool   // ?
+	numberedParams     string // prefixes followed by digits, e.g. "$" for $1
+	namedParams        string // prefixes followed by a name, e.g. ":@" for :name and @name
+	digitSeparators    bool   // 1_000_000
+	executableComments bool   // /*!50100 ... */ is run as SQL
+}
+
+// lexiconFor returns the lexical conventions for the given dialect.
+func lexiconFor(lang LanguageOption) lexicon {
+	switch lang {
+	case LanguageBigQuery:
+		return lexicon{backtickIdents: true, doubleQuoteStrings: true, backslashEscapes: true, positionalParams: true, namedParams: "@"}
+	case LanguageDB2, LanguageDB2i:
+		return lexicon{positionalParams: true, namedParams: ":"}
+	case LanguageDuckDB:
+		return lexicon{dollarQuotes: true, nestedComments: true, positionalParams: true, numberedParams: "$?", namedParams: "$", digitSeparators: true}
+	case LanguageHive, LanguageSpark:
+		return lexicon{backtickIdents: true, doubleQuoteStrings: true, backslashEscapes: true, positionalParams: true}
+	case LanguageMariaDB, LanguageMySQL, LanguageTiDB, LanguageSingleStoreDB:
+		return lexicon{backtickIdents: true, doubleQuoteStrings: true, backslashEscapes: true, hashComments: true, positionalParams: true, executableComments: true}
+	case LanguageN1QL:
+		return lexicon{backtickIdents: true, doubleQuoteStrings: true, backslashEscapes: true, positionalParams: true, numberedParams: "$", namedParams: "$"}
+	case LanguagePLSQL:
+		return lexicon{numberedParams: ":", namedParams: ":"}
+	case LanguagePostgreSQL:
+		return lexicon{dollarQuotes: true, nestedComments: true, numberedParams: "$", digitSeparators: true}
+	case LanguageRedshift:
+		return lexicon{numberedParams: "$"}
+	case LanguageSnowflake:
+		return lexicon{dollarQuotes: true, positionalParams: true}
+	case LanguageSQLite:
+		return lexicon{backtickIdents: true, bracketIdents: true, positionalParams: true, numberedParams: "?", namedParams: ":@$"}
+	case LanguageTransactSQL, LanguageTSQL:
+		return lexicon{bracketIdents: true, nestedComments: true, namedParams: "@"}
+	case LanguageTrino:
+		return lexicon{positionalParams: true}
+	default:
+		return lexicon{backtickIdents: true, positionalParams: true}
+	}
+}
+
+// multiCharOperators lists operators made of more than one character, longest first.
+var multiCharOperators = []string{
+	"<=>", "->>", "#>>", "!~*", "!!=",
+	"<>", "<=", ">=", "!=", "==", "||", "::", "->", "#>", "@>", "<@", "&&", "<<", ">>",
+	":=", "=>", "**", "!~", "~*", "?|", "?&", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+}
+
+// tokenize splits sql into tokens according to the conventions of lang.
+// It never fails: unterminated strings, identifiers and comments extend to the end of the input.
+// Concatenating the text of all returned tokens reproduces sql exactly.
+func tokenize(sql string, lang LanguageOption) []token {
+	var tokens []token
+	scanTokens(sql, lang, func(t token) bool {
+		tokens = append(tokens, t)
+		return true
+	})
+	return tokens
+}
+
+// scanTokens calls fn with the tokens of sql in order, as tokenize would return them, until fn
+// returns false. Unlike tokenize, it holds no more than one token in memory, which matters for
+// statements of hundreds of megabytes.
+func scanTokens(sql string, lang LanguageOption, fn func(token) bool) {
+	lexiconFor(lang).scan(sql, fn)
+}
+
+// scan implements scanTokens with the conventions of lx.
+func (lx lexicon) scan(sql string, fn func(token) bool) {
+	prev := make([]token, 0, 1) // next only looks at the last token
+	i := 0
+	for i < len(sql) {
+		kind, n := lx.next(sql[i:], prev)
+		t := token{kind: kind, text: sql[i : i+n], start: i, end: i + n}
+		if !fn(t) {
+			return
+		}
+		prev = append(prev[:0], t)
+		i += n
+	}
+}
+
+// next returns the kind and byte length of the token at the start of s.
+// prev holds the tokens lexed so far and is used to disambiguate a few constructs.
+func (lx lexicon) next(s string, prev []token) (tokenKind, int) {
+	c := s[0]
+	switch {
+	case isSpace(c):
+		n := 1
+		for n < len(s) && isSpace(s[n]) {
+			n++
+		}
+		return tokenSpace, n
+	case strings.HasPrefix(s, "--") || (lx.hashComments && c == '#'):
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			n = len(s)
+		}
+		return tokenComment, n
+	case strings.HasPrefix(s, "/*"):
+		return tokenComment, lx.blockComment(s)
+	case c == '\'':
+		return tokenString, lx.quoted(s, '\'', lx.backslashEscapes)
+	case c == '"':
+		if lx.doubleQuoteStrings {
+			return tokenString, lx.quoted(s, '"', lx.backslashEscapes)
+		}
+		return tokenIdent, lx.quoted(s, '"', false)
+	case c == '`' && lx.backtickIdents:
+		return tokenIdent, lx.quoted(s, '`', false)
+	case c == '[' && lx.bracketIdents:
+		return tokenIdent, lx.quoted(s, ']', false)
+	case c == '$' && lx.dollarQuotes:
+		if n := dollarQuoted(s); n > 0 {
+			return tokenString, n
+		}
+	}
+
+	if n := lx.prefixedString(s); n > 0 {
+		return tokenString, n
+	}
+	if n := lx.param(s, prev); n > 0 {
+		return tokenParam, n
+	}
+	if n := lx.number(s, prev); n > 0 {
+		return tokenNumber, n
+	}
+	if r, size := utf8.DecodeRuneInString(s); isWordStart(r) {
+		n := size
+		for n < len(s) {
+			r, size := utf8.DecodeRuneInString(s[n:])
+			if !isWordPart(r) {
+				break
+			}
+			n += size
+		}
+		return tokenWord, n
+	}
+	switch c {
+	case '(', ')', '[', ']', '{', '}', ',', ';', '.':
+		return tokenPunct, 1
+	}
+	for _, op := range multiCharOperators {
+		if strings.HasPrefix(s, op) {
+			return tokenOperator, len(op)
+		}
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return tokenOperator, size
+}
+
+// blockComment returns the length of the /* */ comment at the start of s.
+func (lx lexicon) blockComment(s string) int {
+	depth := 0
+	for i := 0; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*' && (depth == 0 || lx.nestedComments):
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// quoted returns the length of the quoted run at the start of s, which opens with s[0] and closes with closer.
+// A doubled closer is an escaped closer. If backslash is true, a backslash escapes the next byte.
+func (lx lexicon) quoted(s string, closer byte, backslash bool) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == closer:
+			if i+1 < len(s) && s[i+1] == closer {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// prefixedString returns the length of a prefixed string literal such as E'..', N'..', X'..' or B'..'
+// at the start of s, or 0 if there is none.
+func (lx lexicon) prefixedString(s string) int {
+	n := 0
+	for n < len(s) && n < 2 && isASCIILetter(s[n]) {
+		n++
+	}
+	if n == 0 || n >= len(s) || s[n] != '\'' {
+		return 0
+	}
+	switch strings.ToUpper(s[:n]) {
+	case "E":
+		return n + lx.quoted(s[n:], '\'', true)
+	case "N", "X", "B", "R", "U", "BR", "RB":
+		return n + lx.quoted(s[n:], '\'', lx.backslashEscapes)
+	}
+	return 0
+}
+
+// dollarQuoted returns the length of a $tag$ ... $tag$ string at the start of s, or 0 if there is none.
+func dollarQuoted(s string) int {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return 0
+	}
+	tag := s[:end+2]
+	for _, r := range tag[1 : len(tag)-1] {
+		if !isWordPart(r) || r == '$' {
+			return 0
+		}
+	}
+	if len(tag) > 2 && unicode.IsDigit(rune(tag[1])) {
+		return 0
+	}
+	closing := strings.Index(s[len(tag):], tag)
+	if closing < 0 {
+		return len(s)
+	}
+	return len(tag) + closing + len(tag)
+}
+
+// param returns the length of the bind parameter at the start of s, or 0 if there is none.
+func (lx lexicon) param(s string, prev []token) int {
+	c := s[0]
+	if c == '?' && lx.positionalParams {
+		if strings.ContainsRune(lx.numberedParams, '?') {
+			return 1 + countDigits(s[1:])
+		}
+		return 1
+	}
+	if !strings.ContainsRune(lx.numberedParams+lx.namedParams, rune(c)) || len(s) < 2 {
+		return 0
+	}
+	if c == ':' && (strings.HasPrefix(s, "::") || strings.HasPrefix(s, ":=") || lastText(prev) == ":") {
+		return 0
+	}
+	if strings.ContainsRune(lx.numberedParams, rune(c)) {
+		if n := countDigits(s[1:]); n > 0 {
+			return 1 + n
+		}
+	}
+	if strings.ContainsRune(lx.namedParams, rune(c)) {
+		if r, _ := utf8.DecodeRuneInString(s[1:]); isWordStart(r) {
+			n := 1
+			for n < len(s) {
+				r, size := utf8.DecodeRuneInString(s[n:])
+				if !isWordPart(r) || r == '$' {
+					break
+				}
+				n += size
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+// number returns the length of the numeric literal at the start of s, or 0 if there is none.
+func (lx lexicon) number(s string, prev []token) int {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') && isHexDigit(s[2]) {
+		n := 2
+		for n < len(s) && isHexDigit(s[n]) {
+			n++
+		}
+		return n
+	}
+	n := lx.digits(s)
+	if n == 0 && !(s[0] == '.' && len(s) > 1 && isDigit(s[1]) && !endsExpression(prev)) {
+		return 0
+	}
+	if n < len(s) && s[n] == '.' {
+		n++
+		n += lx.digits(s[n:])
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		m := n + 1
+		if m < len(s) && (s[m] == '+' || s[m] == '-') {
+			m++
+		}
+		if d := lx.digits(s[m:]); d > 0 {
+			n = m + d
+		}
+	}
+	// A run of digits directly followed by letters (e.g. 1st_column) is a word, not a number.
+	if n < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[n:]); isWordStart(r) {
+			return 0
+		}
+	}
+	return n
+}
+
+// endsExpression reports whether the last significant token in prev can end an operand,
+// in which case a following "." is a qualifier rather than the start of a number.
+func endsExpression(prev []token) bool {
+	for i := len(prev) - 1; i >= 0; i-- {
+		switch prev[i].kind {
+		case tokenSpace, tokenComment:
+			return false
+		case tokenWord, tokenIdent:
+			return true
+		case tokenPunct:
+			return prev[i].text == ")" || prev[i].text == "]"
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func lastText(prev []token) string {
+	if len(prev) == 0 {
+		return ""
+	}
+	return prev[len(prev)-1].text
+}
+
+// digits returns the length of the run of digits at the start of s, including the underscores
+// between digits in dialects with digitSeparators.
+func (lx lexicon) digits(s string) int {
+	n := countDigits(s)
+	for lx.digitSeparators && n > 0 && n+1 < len(s) && s[n] == '_' && isDigit(s[n+1]) {
+		n++
+		n += countDigits(s[n:])
+	}
+	return n
+}
+
+func countDigits(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isWordStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isWordPart(r rune) bool {
+	return r == '_' || r == '$' || r == '#' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// significant returns tokens without whitespace and comments.
+func significant(tokens []token) []token {
+	out := make([]token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != tokenSpace && t.kind != tokenComment {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// position converts a byte offset in s to a 1-based line and column (counted in runes).
+func position(s string, offset int) (line, column int) {
+	if offset > len(s) {
+		offset = len(s)
+	}
+	line = 1 + strings.Count(s[:offset], "\n")
+	lineStart := strings.LastIndexByte(s[:offset], '\n') + 1
+	column = 1 + utf8.RuneCountInString(s[lineStart:offset])
+	return line, column
+}
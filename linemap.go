@@ -0,0 +1,107 @@
+package sqlfmt
+
+import "strings"
+
+// LineSource is the part of the input an output line was formatted from: the bytes from the start
+// of the first to the end of the last input token that ended up on the line. Start and End are
+// -1 for lines holding no token of the input, such as blank lines.
+type LineSource struct {
+	Start, End int
+}
+
+// alignWindow is the number of tokens alignTokens looks ahead to find where input and output
+// agree again after tokens that formatting changed.
+const alignWindow = 32
+
+// mapLines returns the source in input of each line of output, its formatted version.
+func mapLines(input, output string, lang LanguageOption) []LineSource {
+	in := nonSpaceTokens(input, lang)
+	out := nonSpaceTokens(output, lang)
+	sources := alignTokens(in, out)
+
+	lines := make([]LineSource, strings.Count(output, "\n")+1)
+	for i := range lines {
+		lines[i] = LineSource{-1, -1}
+	}
+	line, pos := 0, 0 // line of output at offset pos
+	for j, t := range out {
+		line += strings.Count(output[pos:t.start], "\n")
+		last := line + strings.Count(t.text, "\n") // a block comment can span lines
+		pos = t.end
+		if src := sources[j]; src.Start >= 0 {
+			for l := line; l <= last; l++ {
+				lines[l] = lines[l].extend(src)
+			}
+		}
+		line = last
+	}
+	return lines
+}
+
+// extend returns s grown to cover src.
+func (s LineSource) extend(src LineSource) LineSource {
+	if s.Start < 0 {
+		return src
+	}
+	return LineSource{min(s.Start, src.Start), max(s.End, src.End)}
+}
+
+// alignTokens returns the source in the input of each token of out, the formatted tokens of in.
+// Tokens are matched by position if formatting kept their number, as it does unless transforms
+// rewrite the query. Otherwise tokens are matched by text, and the tokens of out that replace some
+// of in, such as the column list of an expanded *, get the span of what they replace; tokens that
+// formatting inserted, such as the schema of a qualified table, get no source.
+func alignTokens(in, out []token) []LineSource {
+	sources := make([]LineSource, len(out))
+	if len(in) == len(out) {
+		for j, t := range in {
+			sources[j] = LineSource{t.start, t.end}
+		}
+		return sources
+	}
+
+	i := 0
+	for j := 0; j < len(out); {
+		if i < len(in) && sameToken(in[i], out[j]) {
+			sources[j] = LineSource{in[i].start, in[i].end}
+			i++
+			j++
+			continue
+		}
+		di, dj, ok := resync(in[i:], out[j:])
+		if !ok {
+			// Nothing matches nearby: take the tokens for rewrites of one another.
+			di, dj = min(1, len(in)-i), 1
+		}
+		src := LineSource{-1, -1}
+		if di > 0 {
+			src = LineSource{in[i].start, in[i+di-1].end}
+		}
+		for k := range dj {
+			sources[j+k] = src
+		}
+		i += di
+		j += dj
+	}
+	return sources
+}
+
+// resync returns the numbers of tokens of in and out to skip for them to start with the same
+// token, fewest first, within alignWindow. It reports false if there is no such pair.
+func resync(in, out []token) (int, int, bool) {
+	for d := 1; d <= alignWindow; d++ {
+		for di := 0; di <= d; di++ {
+			dj := d - di
+			if di < len(in) && dj < len(out) && sameToken(in[di], out[dj]) {
+				return di, dj, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// sameToken reports whether the output token b can be the formatted version of the input token a:
+// formatting changes the case of words, and the layout of numbers and comments.
+func sameToken(a, b token) bool {
+	return a.kind == b.kind && (strings.EqualFold(a.text, b.text) || a.kind == tokenNumber || a.kind == tokenComment)
+}
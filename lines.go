@@ -0,0 +1,53 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineRange is a range of lines, from Start to End inclusive (1-based).
+type LineRange struct {
+	Start, End int
+}
+
+// FormatLines formats the statements of sql that overlap any of lines and leaves the rest of the
+// text, including the whitespace between statements, unchanged. It allows adopting a style
+// incrementally, formatting only the statements touched by a change.
+func (f *Formatter) FormatLines(sql string, options FormatOptions, lines []LineRange) (string, error) {
+	if err := f.check("FormatLines"); err != nil {
+		return "", err
+	}
+	var (
+		b       strings.Builder
+		last    int
+		line    = 1 // of sql[counted]
+		counted int
+	)
+	for _, stmt := range splitStatements(sql, options.Language) {
+		first := line + strings.Count(sql[counted:stmt.start], "\n")
+		end := first + strings.Count(sql[stmt.start:stmt.end], "\n")
+		line, counted = end, stmt.end
+		if !overlaps(lines, first, end) {
+			continue
+		}
+		formatted, err := f.Format(sql[stmt.start:stmt.end], options)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", first, err)
+		}
+		b.WriteString(sql[last:stmt.start])
+		b.WriteString(formatted)
+		last = stmt.end
+	}
+	b.WriteString(sql[last:])
+	return b.String(), nil
+}
+
+// overlaps reports whether any of ranges has a line between first and last.
+func overlaps(ranges []LineRange, first, last int) bool {
+	for _, r := range ranges {
+		if r.Start <= last && r.End >= first {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatLines(t *testing.T) {
+	sql := "select a from t;\n\nselect b\nfrom u;\nselect c from v;\n"
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	options := DefaultFormatOptions
+	options.TabWidth, options.NewlineBeforeSemicolon = 2, false
+	tests := []struct {
+		lines []LineRange
+		want  string
+	}{
+		{nil, sql},
+		{[]LineRange{{2, 2}}, sql},
+		{[]LineRange{{4, 4}}, "select a from t;\n\nSELECT\n  b\nFROM\n  u;\nselect c from v;\n"},
+		{[]LineRange{{1, 1}, {5, 9}}, "SELECT\n  a\nFROM\n  t;\n\nselect b\nfrom u;\nSELECT\n  c\nFROM\n  v;\n"},
+	}
+	for _, tt := range tests {
+		got, err := f.FormatLines(sql, options, tt.lines)
+		if err != nil {
+			t.Errorf("%v: %v", tt.lines, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+
+	if Backend == "native" {
+		return // the native backend does not reject invalid SQL
+	}
+	_, err = f.FormatLines("select 1;\n\nselect (;\n", options, []LineRange{{3, 3}})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("got error %v, want one at line 3", err)
+	}
+}
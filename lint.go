@@ -0,0 +1,364 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Severity is the level at which a rule reports problems.
+type Severity string
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = "off"
+	// SeverityWarn reports problems without failing a check.
+	SeverityWarn Severity = "warn"
+	// SeverityError reports problems that fail a check.
+	SeverityError Severity = "error"
+)
+
+// FormatRule is the name under which check mode reports unformatted input. It can be given a
+// severity in LintConfig.Rules like any lint rule, but is not run by Lint itself.
+const FormatRule = "format"
+
+// Diagnostic is a problem reported by a lint rule.
+type Diagnostic struct {
+	// Rule is the name of the rule that reported the problem.
+	Rule string `json:"rule"`
+	// Severity is the severity configured for the rule.
+	Severity Severity `json:"severity"`
+	// Message describes the problem.
+	Message string `json:"message"`
+	// Line and Column locate the start of the problem (1-based).
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Start and End are the byte offsets of the offending input.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Fix is the edit that resolves the problem, or nil if the rule cannot fix it automatically.
+	Fix *Fix `json:"fix,omitempty"`
+}
+
+// Fix is an automatic fix for a diagnostic: the bytes [Start, End) of the input are replaced by Text.
+type Fix struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// String formats the diagnostic as line:column: severity: rule: message.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s: %s", d.Line, d.Column, d.Severity, d.Rule, d.Message)
+}
+
+// Rule is a lint rule. Rules are identified by name and registered with RegisterRule, inside this
+// package or outside of it: a rule reads the input through its LintContext, which offers the
+// tokens the built-in rules work on.
+type Rule struct {
+	// Name identifies the rule in configuration and suppression comments, e.g. "no-select-star".
+	Name string
+	// Description explains what the rule checks.
+	Description string
+	// Severity is used when the configuration does not mention the rule. The zero value means off.
+	Severity Severity
+	// Check inspects the input and reports problems through the context.
+	Check func(c *LintContext)
+}
+
+// LintContext gives a rule access to the input being linted.
+type LintContext struct {
+	// SQL is the input.
+	SQL string
+	// Language is the dialect of the input.
+	Language LanguageOption
+
+	rule        *Rule
+	severity    Severity
+	tokens      []token
+	sig         []token
+	diagnostics []Diagnostic
+}
+
+// LintToken is a lexical token of the input of a lint rule.
+type LintToken struct {
+	// Kind is the kind of token: whitespace, comment, string, identifier, word, number, parameter,
+	// operator or punctuation, as in ExplainedToken.
+	Kind string
+	// Text is the token as written in the input.
+	Text string
+	// Start and End are the byte offsets of the token in the input, as passed to Report.
+	Start, End int
+}
+
+// Tokens returns the tokens of the input other than whitespace and comments, tokenized according
+// to Language. The slice is the rule's own to modify.
+func (c *LintContext) Tokens() []LintToken {
+	return lintTokens(c.sig)
+}
+
+// AllTokens returns all tokens of the input, whitespace and comments included; their texts
+// concatenate to SQL. The slice is the rule's own to modify.
+func (c *LintContext) AllTokens() []LintToken {
+	return lintTokens(c.tokens)
+}
+
+func lintTokens(tokens []token) []LintToken {
+	out := make([]LintToken, len(tokens))
+	for i, t := range tokens {
+		out[i] = LintToken{Kind: tokenKindNames[t.kind], Text: t.text, Start: t.start, End: t.end}
+	}
+	return out
+}
+
+// Report records a problem spanning the bytes [start, end) of the input.
+func (c *LintContext) Report(start, end int, format string, args ...any) {
+	line, column := position(c.SQL, start)
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Rule:     c.rule.Name,
+		Severity: c.severity,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     line,
+		Column:   column,
+		Start:    start,
+		End:      end,
+	})
+}
+
+// ReportFix records a problem spanning the bytes [start, end) of the input, together with the
+// fix replacing those bytes by replacement.
+func (c *LintContext) ReportFix(start, end int, replacement string, format string, args ...any) {
+	c.Report(start, end, format, args...)
+	c.diagnostics[len(c.diagnostics)-1].Fix = &Fix{Start: start, End: end, Text: replacement}
+}
+
+// LintConfig selects the rules run by Lint and their severities.
+type LintConfig struct {
+	// Rules sets the severity of rules by name. Rules not listed use their default.
+	Rules map[string]Severity `json:"rules,omitempty"`
+}
+
+// SeverityOf returns the effective severity of the named rule.
+func (c LintConfig) SeverityOf(rule *Rule) Severity {
+	if s, ok := c.Rules[rule.Name]; ok {
+		return s
+	}
+	if rule.Severity == "" {
+		return SeverityOff
+	}
+	return rule.Severity
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = make(map[string]*Rule)
+)
+
+// RegisterRule makes a rule available to Lint. It panics if the rule has no name or Check function,
+// or if a rule with the same name is already registered.
+func RegisterRule(r *Rule) {
+	if r == nil || r.Name == "" || r.Check == nil {
+		panic("sqlfmt: RegisterRule called with an incomplete rule")
+	}
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	if _, dup := rules[r.Name]; dup {
+		panic("sqlfmt: RegisterRule called twice for rule " + r.Name)
+	}
+	rules[r.Name] = r
+}
+
+// Rules returns all registered rules sorted by name.
+func Rules() []*Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	out := make([]*Rule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Lint runs the rules enabled by config over sql and returns the problems found, ordered by position.
+// Problems can be suppressed with comments:
+//
+//	-- sqlfmt-disable-next-line [rule ...]  suppresses the rules on the next line
+//	-- sqlfmt-disable-line [rule ...]       suppresses the rules on the comment's line
+//	-- sqlfmt-disable [rule ...]            suppresses the rules until a matching sqlfmt-enable
+//	-- sqlfmt-enable [rule ...]             ends a suppression
+//
+// Omitting the rule names applies the comment to all rules.
+func Lint(sql string, lang LanguageOption, config LintConfig) ([]Diagnostic, error) {
+	registered := Rules()
+	for name := range config.Rules {
+		if name != FormatRule && ruleByName(registered, name) == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+	}
+
+	tokens := tokenize(sql, lang)
+	sig := significant(tokens)
+	var diagnostics []Diagnostic
+	for _, r := range registered {
+		severity := config.SeverityOf(r)
+		if severity == SeverityOff {
+			continue
+		}
+		c := &LintContext{SQL: sql, Language: lang, rule: r, severity: severity, tokens: tokens, sig: sig}
+		r.Check(c)
+		diagnostics = append(diagnostics, c.diagnostics...)
+	}
+
+	diagnostics = newSuppressions(sql, tokens).filter(diagnostics)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Start != diagnostics[j].Start {
+			return diagnostics[i].Start < diagnostics[j].Start
+		}
+		return diagnostics[i].Rule < diagnostics[j].Rule
+	})
+	return diagnostics, nil
+}
+
+// maxFixPasses bounds the number of lint and fix rounds performed by LintFix.
+const maxFixPasses = 10
+
+// LintFix applies the fixes of the diagnostics reported by Lint and returns the fixed input together
+// with the diagnostics that remain. Fixes are applied in rounds until no fixable diagnostic is left,
+// since one fix may make room for another; overlapping fixes are deferred to the next round.
+func LintFix(sql string, lang LanguageOption, config LintConfig) (string, []Diagnostic, error) {
+	for pass := 0; ; pass++ {
+		diagnostics, err := Lint(sql, lang, config)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var (
+			spans []span
+			last  = -1
+		)
+		for _, d := range diagnostics {
+			if d.Fix == nil || d.Fix.Start < last {
+				continue
+			}
+			spans = append(spans, span{d.Fix.Start, d.Fix.End, d.Fix.Text})
+			last = d.Fix.End
+		}
+		if len(spans) == 0 || pass == maxFixPasses {
+			return sql, diagnostics, nil
+		}
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+		sql = replaceSpans(sql, spans)
+	}
+}
+
+func ruleByName(rules []*Rule, name string) *Rule {
+	for _, r := range rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// suppressions records which rules are disabled on which lines by suppression comments.
+type suppressions struct {
+	lines  map[int][]string // line -> rules suppressed on that line ("" for all)
+	ranges []suppressedRange
+}
+
+type suppressedRange struct {
+	rule       string // "" for all rules
+	start, end int    // byte offsets
+}
+
+// newSuppressions collects the suppression comments in tokens.
+func newSuppressions(sql string, tokens []token) suppressions {
+	s := suppressions{lines: make(map[int][]string)}
+	open := make(map[string]int) // rule -> start offset of an unterminated sqlfmt-disable
+	for _, t := range tokens {
+		if t.kind != tokenComment {
+			continue
+		}
+		directive, names := parseDirective(t.text, "sqlfmt-")
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		line, _ := position(sql, t.start)
+		switch directive {
+		case "disable-next-line":
+			s.lines[line+1] = append(s.lines[line+1], names...)
+		case "disable-line":
+			s.lines[line] = append(s.lines[line], names...)
+		case "disable":
+			for _, n := range names {
+				if _, ok := open[n]; !ok {
+					open[n] = t.end
+				}
+			}
+		case "enable":
+			for _, n := range names {
+				if n == "" {
+					for rule, start := range open {
+						s.ranges = append(s.ranges, suppressedRange{rule, start, t.start})
+					}
+					clear(open)
+					break
+				}
+				if start, ok := open[n]; ok {
+					s.ranges = append(s.ranges, suppressedRange{n, start, t.start})
+					delete(open, n)
+				}
+			}
+		}
+	}
+	for rule, start := range open {
+		s.ranges = append(s.ranges, suppressedRange{rule, start, len(sql)})
+	}
+	return s
+}
+
+// parseDirective extracts a directive such as "disable-next-line" and its comma- or space-separated
+// arguments from a comment whose body starts with prefix. It returns "" if the comment is not a directive.
+func parseDirective(comment, prefix string) (string, []string) {
+	body := commentBody(comment)
+	if !strings.HasPrefix(body, prefix) {
+		return "", nil
+	}
+	fields := strings.FieldsFunc(body[len(prefix):], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// commentBody returns the text of a comment without its markers and surrounding whitespace.
+func commentBody(comment string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(comment, "--"), "#"), "/*"), "*/"))
+}
+
+// filter removes suppressed diagnostics.
+func (s suppressions) filter(diagnostics []Diagnostic) []Diagnostic {
+	out := diagnostics[:0]
+	for _, d := range diagnostics {
+		if !s.suppressed(d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func (s suppressions) suppressed(d Diagnostic) bool {
+	for _, rule := range s.lines[d.Line] {
+		if rule == "" || rule == d.Rule {
+			return true
+		}
+	}
+	for _, r := range s.ranges {
+		if (r.rule == "" || r.rule == d.Rule) && r.start <= d.Start && d.Start < r.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package sqlfmt
+
+import "strings"
+
+func init() {
+	RegisterRule(&Rule{
+		Name:        "no-select-star",
+		Description: "Disallow * and t.* in select lists; list the columns explicitly.",
+		Severity:    SeverityError,
+		Check:       checkNoSelectStar,
+	})
+	RegisterRule(&Rule{
+		Name:        "require-explicit-join",
+		Description: "Disallow comma-separated tables in FROM; use an explicit JOIN.",
+		Severity:    SeverityError,
+		Check:       checkRequireExplicitJoin,
+	})
+	RegisterRule(&Rule{
+		Name:        "no-ambiguous-column-alias",
+		Description: "Disallow select list items with the same output name, e.g. an alias shadowing another selected column.",
+		Severity:    SeverityError,
+		Check:       checkNoAmbiguousColumnAlias,
+	})
+
+	// Style rules, which can be fixed automatically with LintFix.
+	RegisterRule(&Rule{
+		Name:        "require-trailing-semicolon",
+		Description: "Require the last statement to be terminated with a semicolon (fixable).",
+		Check:       checkRequireTrailingSemicolon,
+	})
+	RegisterRule(&Rule{
+		Name:        "no-double-quoted-strings",
+		Description: "Disallow double-quoted string literals in MySQL dialects; use single quotes (fixable).",
+		Check:       checkNoDoubleQuotedStrings,
+	})
+	RegisterRule(&Rule{
+		Name:        "require-column-alias-as",
+		Description: "Require AS before select list aliases (fixable).",
+		Check:       checkRequireColumnAliasAs,
+	})
+}
+
+func checkNoSelectStar(c *LintContext) {
+	depths := nestingDepths(c.sig)
+	for i, t := range c.sig {
+		if t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		_, end := selectListEnd(c.sig, depths, i)
+		for _, item := range selectItems(c.sig, depths, i, end) {
+			last := c.sig[item[1]-1]
+			if last.text != "*" {
+				continue
+			}
+			start := c.sig[item[0]]
+			if item[1]-item[0] == 1 || c.sig[item[1]-2].text == "." {
+				c.Report(start.start, last.end, "avoid %s; list the columns explicitly", sqlText(c.SQL, start, last))
+			}
+		}
+	}
+}
+
+func checkRequireExplicitJoin(c *LintContext) {
+	for i, t := range c.sig {
+		if t.text != "," {
+			continue
+		}
+		if clause := enclosingClause(c.sig, i+1); clause == "FROM" || clause == "ON" {
+			c.Report(t.start, t.end, "use an explicit JOIN instead of a comma-separated table list")
+		}
+	}
+}
+
+func checkNoAmbiguousColumnAlias(c *LintContext) {
+	depths := nestingDepths(c.sig)
+	for i, t := range c.sig {
+		if t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		_, end := selectListEnd(c.sig, depths, i)
+		seen := make(map[string]bool) // lower-cased output names
+		for _, item := range selectItems(c.sig, depths, i, end) {
+			name, tok, aliased := outputName(c.sig, item)
+			if name == "" {
+				continue
+			}
+			key := strings.ToLower(name)
+			if seen[key] {
+				if aliased {
+					c.Report(tok.start, tok.end, "alias %s duplicates the name of another select list item", tok.text)
+				} else {
+					c.Report(tok.start, tok.end, "column %s duplicates the name of another select list item", tok.text)
+				}
+				continue
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func checkRequireTrailingSemicolon(c *LintContext) {
+	if len(c.sig) == 0 {
+		return
+	}
+	if last := c.sig[len(c.sig)-1]; last.text != ";" {
+		c.ReportFix(last.end, last.end, ";", "statement is not terminated with a semicolon")
+	}
+}
+
+func checkNoDoubleQuotedStrings(c *LintContext) {
+	switch c.Language {
+	case LanguageMySQL, LanguageMariaDB, LanguageTiDB, LanguageSingleStoreDB:
+	default:
+		return
+	}
+	for _, t := range c.sig {
+		if t.kind == tokenString && strings.HasPrefix(t.text, `"`) {
+			c.ReportFix(t.start, t.end, singleQuoted(t.text), "use single quotes for string literal %s", t.text)
+		}
+	}
+}
+
+// singleQuoted converts a MySQL double-quoted string literal into an equivalent single-quoted one.
+func singleQuoted(literal string) string {
+	body := strings.TrimSuffix(literal[1:], `"`)
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && i+1 < len(body):
+			i++
+			if body[i] == '"' {
+				b.WriteByte('"')
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(body[i])
+			}
+		case c == '"' && i+1 < len(body) && body[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case c == '\'':
+			b.WriteString("''")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+func checkRequireColumnAliasAs(c *LintContext) {
+	depths := nestingDepths(c.sig)
+	for i, t := range c.sig {
+		if t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		_, end := selectListEnd(c.sig, depths, i)
+		for _, item := range selectItems(c.sig, depths, i, end) {
+			_, alias, aliased := outputName(c.sig, item)
+			if aliased && !strings.EqualFold(c.sig[item[1]-2].text, "AS") {
+				c.ReportFix(alias.start, alias.start, "AS ", "use AS before alias %s", alias.text)
+			}
+		}
+	}
+}
+
+// selectListEnd returns the index of the FROM keyword of the SELECT at sig[i] (or -1) and
+// the index just past its select list.
+func selectListEnd(sig []token, depths []int, i int) (from, end int) {
+	from, end = selectBounds(sig, depths, i)
+	if from >= 0 {
+		return from, from
+	}
+	return from, end
+}
+
+// outputName returns the name under which the select list item spanning sig[item[0]:item[1]]
+// appears in the result, the token carrying that name, and whether the name is an alias.
+// It returns "" for expressions without an alias.
+func outputName(sig []token, item [2]int) (string, token, bool) {
+	last := sig[item[1]-1]
+	if !isName(last) {
+		return "", token{}, false
+	}
+	n := item[1] - item[0]
+	if n == 1 {
+		return unquoteName(last), last, false
+	}
+	prev := sig[item[1]-2]
+	switch {
+	case prev.text == ".":
+		return unquoteName(last), last, false
+	case strings.EqualFold(prev.text, "AS") || endsOperand(prev):
+		return unquoteName(last), last, true
+	}
+	return "", token{}, false
+}
+
+// sqlText returns the source text spanning tokens first through last.
+func sqlText(sql string, first, last token) string {
+	return sql[first.start:last.end]
+}
@@ -0,0 +1,133 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Rules registered the way a package using sqlfmt would, off unless a test enables them. The fixes
+// of test-expand-star and test-upper-keywords overlap: both start at SELECT.
+func init() {
+	RegisterRule(&Rule{
+		Name: "test-expand-star",
+		Check: func(c *LintContext) {
+			tokens := c.Tokens()
+			for i := 1; i < len(tokens); i++ {
+				if tokens[i].Text == "*" && strings.EqualFold(tokens[i-1].Text, "SELECT") {
+					c.ReportFix(tokens[i-1].Start, tokens[i].End, "select a, b", "expand *")
+				}
+			}
+		},
+	})
+	RegisterRule(&Rule{Name: "test-lint-tokens", Check: func(c *LintContext) { tokensSeen(c) }})
+	RegisterRule(&Rule{
+		Name: "test-upper-keywords",
+		Check: func(c *LintContext) {
+			for _, t := range c.AllTokens() {
+				if t.Kind == "word" && isKeyword(t.Text) && t.Text != strings.ToUpper(t.Text) {
+					c.ReportFix(t.Start, t.End, strings.ToUpper(t.Text), "use upper case for %s", t.Text)
+				}
+			}
+		},
+	})
+}
+
+// tokensSeen is called by the test-lint-tokens rule.
+var tokensSeen func(c *LintContext)
+
+// positions returns the rule and position of each diagnostic, as "rule@line:column".
+func positions(diagnostics []Diagnostic) []string {
+	var out []string
+	for _, d := range diagnostics {
+		out = append(out, fmt.Sprintf("%s@%d:%d", d.Rule, d.Line, d.Column))
+	}
+	return out
+}
+
+func TestLint(t *testing.T) {
+	config := LintConfig{Rules: map[string]Severity{"require-trailing-semicolon": SeverityWarn}}
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"select * from a, b;", []string{"no-select-star@1:8", "require-explicit-join@1:16"}},
+		{"select a from t", []string{"require-trailing-semicolon@1:16"}},
+		{"-- sqlfmt-disable-next-line no-select-star\nselect * from a, b;", []string{"require-explicit-join@2:16"}},
+		{"-- sqlfmt-disable-next-line\nselect * from a, b;", nil},
+		{"select * from a, b; -- sqlfmt-disable-line require-explicit-join\nselect t.* from t;", []string{"no-select-star@1:8", "no-select-star@2:8"}},
+		{"-- sqlfmt-disable require-explicit-join\nselect a from b, c;\n-- sqlfmt-enable require-explicit-join\nselect a from b, c;", []string{"require-explicit-join@4:16"}},
+		{"-- sqlfmt-disable\nselect * from b, c;\n-- sqlfmt-enable\nselect a, a from t;", []string{"no-ambiguous-column-alias@4:11"}},
+	}
+	for _, tt := range tests {
+		diagnostics, err := Lint(tt.sql, LanguageSQL, config)
+		if err != nil {
+			t.Errorf("%q: %v", tt.sql, err)
+			continue
+		}
+		if got := positions(diagnostics); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	if _, err := Lint("select 1", LanguageSQL, LintConfig{Rules: map[string]Severity{"no-such-rule": SeverityError}}); err == nil {
+		t.Error("an unknown rule in the configuration was accepted")
+	}
+}
+
+func TestLintFix(t *testing.T) {
+	style := LintConfig{Rules: map[string]Severity{
+		"require-trailing-semicolon": SeverityWarn,
+		"require-column-alias-as":    SeverityWarn,
+		"no-double-quoted-strings":   SeverityWarn,
+	}}
+	custom := LintConfig{Rules: map[string]Severity{"test-expand-star": SeverityWarn, "test-upper-keywords": SeverityWarn}}
+	tests := []struct {
+		sql    string
+		config LintConfig
+		want   string
+		left   []string // the diagnostics without a fix
+	}{
+		{"select a x, b y from t", style, "select a AS x, b AS y from t;", nil},
+		{`select "it's" x from t, u`, style, `select 'it''s' AS x from t, u;`, []string{"require-explicit-join@1:27"}},
+		{"-- sqlfmt-disable-line\nselect a x from t", style, "-- sqlfmt-disable-line\nselect a AS x from t;", nil},
+		{"select * from t", custom, "SELECT a, b FROM t", nil},
+	}
+	for _, tt := range tests {
+		got, diagnostics, err := LintFix(tt.sql, LanguageMySQL, tt.config)
+		if err != nil {
+			t.Errorf("%q: %v", tt.sql, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+		if left := positions(diagnostics); !slices.Equal(left, tt.left) {
+			t.Errorf("%q: got remaining diagnostics %q, want %q", tt.sql, left, tt.left)
+		}
+	}
+}
+
+func TestLintTokens(t *testing.T) {
+	const sql = "select a -- x\nfrom t"
+	var all, sig []LintToken
+	tokensSeen = func(c *LintContext) { all, sig = c.AllTokens(), c.Tokens() }
+	if _, err := Lint(sql, LanguageSQL, LintConfig{Rules: map[string]Severity{"test-lint-tokens": SeverityWarn}}); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	for _, tok := range all {
+		if sql[tok.Start:tok.End] != tok.Text {
+			t.Errorf("token %+v does not match the input", tok)
+		}
+		text.WriteString(tok.Text)
+	}
+	if text.String() != sql {
+		t.Errorf("AllTokens concatenate to %q, want %q", text.String(), sql)
+	}
+	want := []LintToken{{"word", "select", 0, 6}, {"word", "a", 7, 8}, {"word", "from", 14, 18}, {"word", "t", 19, 20}}
+	if !slices.Equal(sig, want) {
+		t.Errorf("got Tokens %+v, want %+v", sig, want)
+	}
+}
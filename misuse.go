@@ -0,0 +1,112 @@
+package sqlfmt
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// MisuseError is returned, in builds with the sqlfmt_debug tag, when a Formatter is used in a way
+// it does not support: a Formatter that was not returned by NewFormatter or Clone (the zero value,
+// or a copy of one), a formatter used after Close, or a JavaScript context handed out to two calls
+// at once or returned to a pool that does not own it. Without the checks, such misuse shows up as a
+// nil pointer dereference, a bare ErrFormatterClosed or a crash inside cgo, far from its cause.
+//
+// The checks cost a stack trace per Close and an atomic operation per call, so they are meant for
+// tests and debugging: go test -tags sqlfmt_debug ./...
+type MisuseError struct {
+	// Op is the method called, such as "Format", or "" for misuse detected inside the pool.
+	Op string
+	// Problem describes the misuse.
+	Problem string
+	// Stack is the stack trace of the Close call, for a formatter used after Close.
+	Stack []byte
+	// Err is ErrFormatterClosed for a formatter used after Close, and nil otherwise.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *MisuseError) Error() string {
+	msg := "misuse of Formatter: " + e.Problem
+	if e.Op != "" {
+		msg = e.Op + ": " + msg
+	}
+	if e.Stack != nil {
+		msg += "; closed at:\n" + string(e.Stack)
+	}
+	return msg
+}
+
+// Unwrap returns e.Err, so that use after Close matches ErrFormatterClosed.
+func (e *MisuseError) Unwrap() error {
+	return e.Err
+}
+
+// check returns an error if f cannot be used for op: ErrFormatterClosed once f is closed, and with
+// misuseGuard a *MisuseError describing any misuse of f.
+func (f *Formatter) check(op string) error {
+	if !misuseGuard {
+		if f.closed.Load() {
+			return ErrFormatterClosed
+		}
+		return nil
+	}
+	switch {
+	case f == nil:
+		return &MisuseError{Op: op, Problem: "nil *Formatter"}
+	case f.pool == nil:
+		return &MisuseError{Op: op, Problem: "Formatter not created with NewFormatter or Clone"}
+	case f.self != f:
+		return &MisuseError{Op: op, Problem: "Formatter copied by value; share the *Formatter or use Clone"}
+	case f.closed.Load():
+		e := &MisuseError{Op: op, Problem: "used after Close", Err: ErrFormatterClosed}
+		if stack := f.closeStack.Load(); stack != nil {
+			e.Stack = *stack
+		}
+		return e
+	}
+	return nil
+}
+
+// recordClose keeps the stack trace of the call closing f, reported when f is used afterwards.
+func (f *Formatter) recordClose() {
+	if misuseGuard {
+		stack := debug.Stack()
+		f.closeStack.Store(&stack)
+	}
+}
+
+// engineGuard tracks the pool owning an engine and whether the engine is handed out, for the
+// checks of misuseGuard.
+type engineGuard struct {
+	owner *pool
+	inUse atomic.Bool
+}
+
+// checkOut marks e, an engine of p taken from the idle list, as in use. With misuseGuard, it fails
+// if e is already in use or belongs to another pool.
+func (p *pool) checkOut(e *engine) error {
+	if !misuseGuard {
+		return nil
+	}
+	if e.guard.owner != p {
+		return &MisuseError{Problem: "JavaScript context shared by two pools"}
+	}
+	if !e.guard.inUse.CompareAndSwap(false, true) {
+		return &MisuseError{Problem: "JavaScript context handed out while in use"}
+	}
+	return nil
+}
+
+// checkIn marks e, returned to p, as idle. With misuseGuard, it panics with a *MisuseError if e
+// was not in use or belongs to another pool, since using it again would corrupt its context.
+func (p *pool) checkIn(e *engine) {
+	if !misuseGuard {
+		return
+	}
+	if e.guard.owner != p {
+		panic(&MisuseError{Problem: "JavaScript context returned to a pool that does not own it"})
+	}
+	if !e.guard.inUse.CompareAndSwap(true, false) {
+		panic(&MisuseError{Problem: "JavaScript context returned to the pool twice"})
+	}
+}
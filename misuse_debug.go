@@ -0,0 +1,6 @@
+//go:build sqlfmt_debug
+
+package sqlfmt
+
+// misuseGuard enables the checks reporting a *MisuseError.
+const misuseGuard = true
@@ -0,0 +1,7 @@
+//go:build !sqlfmt_debug
+
+package sqlfmt
+
+// misuseGuard enables the checks reporting a *MisuseError; build with the sqlfmt_debug tag to
+// turn them on.
+const misuseGuard = false
@@ -0,0 +1,462 @@
+//go:build tinygo || sqlfmt_native
+
+package sqlfmt
+
+import "strings"
+
+// nativeFormat lays out sql in the style of sql-formatter, in plain Go. It covers the common
+// statements of the standard dialect: clauses on their own lines with their contents indented,
+// one item per line in clause lists, joins, AND and OR at the start of lines, CASE expressions
+// and parenthesized subqueries. Other parenthesized expressions are kept on one line whatever
+// their length (ExpressionWidth is ignored), as is IndentStyle. Dialects only affect
+// tokenization: unlike sql-formatter, nativeFormat does not reject invalid SQL.
+func nativeFormat(sql string, options FormatOptions) string {
+	p := &nativePrinter{options: options, frames: []nativeFrame{{kind: frameBlock}}, lineStart: true}
+	switch {
+	case options.UseTabs:
+		p.unit = "\t"
+	case options.TabWidth > 0:
+		p.unit = strings.Repeat(" ", options.TabWidth)
+	default:
+		p.unit = "  "
+	}
+
+	tokens := tokenize(sql, options.Language)
+	var code []nativeToken
+	for i, t := range tokens {
+		if t.kind == tokenSpace {
+			continue
+		}
+		nt := nativeToken{token: t}
+		nt.newlineBefore = i == 0 || tokens[i-1].kind == tokenSpace && strings.Contains(tokens[i-1].text, "\n")
+		nt.newlineAfter = i+1 < len(tokens) && tokens[i+1].kind == tokenSpace && strings.Contains(tokens[i+1].text, "\n")
+		code = append(code, nt)
+	}
+	for i := 0; i < len(code); {
+		i += p.print(code, i)
+	}
+	return strings.TrimRight(string(p.out), " \t\n")
+}
+
+// nativeToken is a token other than whitespace, with where the input breaks lines around it.
+type nativeToken struct {
+	token
+	newlineBefore bool
+	newlineAfter  bool
+}
+
+type frameKind int
+
+const (
+	// frameBlock is a statement or a parenthesized subquery, whose clauses go on their own lines.
+	frameBlock frameKind = iota
+	// frameParen is a parenthesized expression kept on one line.
+	frameParen
+	// frameCase is a CASE expression, whose WHEN and ELSE go on their own lines.
+	frameCase
+)
+
+// nativeFrame is a nesting level of the statement being printed.
+type nativeFrame struct {
+	kind   frameKind
+	level  int  // indentation of the clause keywords of a block, or of the WHEN of a CASE
+	clause bool // a block has started its first clause
+}
+
+// nativePrinter accumulates the output of nativeFormat.
+type nativePrinter struct {
+	options FormatOptions
+	unit    string // one level of indentation
+	out     []byte
+	frames  []nativeFrame
+
+	line      int   // indentation level of the current line
+	lineStart bool  // nothing written on the current line yet
+	pending   bool  // the next token starts a new line at pendingAt
+	pendingAt int   // indentation level of the pending line
+	separate  int   // line breaks due before the next statement
+	prev      token // last token written, other than comments
+	unary     bool  // prev is a unary operator
+	between   bool  // within BETWEEN, whose AND does not start a line
+}
+
+// clauses are the keywords put on their own lines, with the contents of the clause on the
+// following lines. Longer phrases come first.
+var clauses = [][]string{
+	{"SELECT", "DISTINCT"}, {"SELECT", "ALL"}, {"SELECT"}, {"FROM"}, {"WHERE"}, {"GROUP", "BY"},
+	{"HAVING"}, {"ORDER", "BY"}, {"LIMIT"}, {"OFFSET"}, {"WINDOW"}, {"QUALIFY"}, {"VALUES"},
+	{"SET"}, {"RETURNING"}, {"INSERT", "INTO"}, {"WITH", "RECURSIVE"}, {"WITH"},
+	{"PARTITION", "BY"},
+}
+
+// inlineClauses start a line like clauses but keep their contents on it.
+var inlineClauses = [][]string{{"DELETE", "FROM"}, {"UPDATE"}, {"ON", "CONFLICT"}}
+
+// setOperations separate the SELECT statements of a compound query.
+var setOperations = [][]string{
+	{"UNION", "ALL"}, {"UNION", "DISTINCT"}, {"UNION"}, {"INTERSECT", "ALL"}, {"INTERSECT"},
+	{"EXCEPT", "ALL"}, {"EXCEPT"}, {"MINUS"},
+}
+
+// joinModifiers are the words that can precede JOIN in a join phrase.
+var joinModifiers = wordSet("NATURAL LEFT RIGHT FULL INNER CROSS OUTER")
+
+// print prints the token code[i] and the words forming a phrase with it, and returns the number
+// of tokens printed.
+func (p *nativePrinter) print(code []nativeToken, i int) int {
+	t := code[i]
+	top := &p.frames[len(p.frames)-1]
+	switch t.kind {
+	case tokenComment:
+		p.comment(t)
+		return 1
+	case tokenWord:
+		return p.word(code, i, top)
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			// Subqueries and window specifications are laid out like statements
+			next := nextCode(code, i)
+			if strings.EqualFold(p.prev.text, "OVER") || next != nil && next.kind == tokenWord &&
+				(strings.EqualFold(next.text, "SELECT") || strings.EqualFold(next.text, "WITH")) {
+				p.write(t.token)
+				p.frames = append(p.frames, nativeFrame{kind: frameBlock, level: p.line + 1})
+				return 1
+			}
+			p.write(t.token)
+			p.frames = append(p.frames, nativeFrame{kind: frameParen})
+			return 1
+		case ")":
+			for len(p.frames) > 1 {
+				f := p.frames[len(p.frames)-1]
+				p.frames = p.frames[:len(p.frames)-1]
+				if f.kind == frameBlock && f.clause {
+					p.breakLine(f.level - 1)
+				}
+				if f.kind != frameCase {
+					break
+				}
+			}
+			p.between = false
+			p.write(t.token)
+			return 1
+		case ",":
+			p.write(t.token)
+			if top.kind == frameBlock && top.clause {
+				p.breakLine(top.level + 1)
+			}
+			return 1
+		case ";":
+			if p.options.NewlineBeforeSemicolon || p.prevComment() {
+				p.breakLine(0)
+			}
+			p.write(t.token)
+			p.frames = p.frames[:1]
+			p.frames[0].clause = false
+			p.between = false
+			p.pending = false
+			p.separate = 1 + p.options.BlankLinesBetweenQueries()
+			return 1
+		}
+	}
+	p.write(t.token)
+	return 1
+}
+
+// word prints the keyword phrase or word starting at code[i] and returns its number of tokens.
+func (p *nativePrinter) word(code []nativeToken, i int, top *nativeFrame) int {
+	upper := strings.ToUpper(code[i].text)
+	if top.kind == frameBlock {
+		if n := matchPhrase(code, i, clauses); n > 0 && p.isClause(upper, top) {
+			p.breakLine(top.level)
+			p.phrase(code[i : i+n])
+			top.clause = true
+			p.breakLine(top.level + 1)
+			return n
+		}
+		if n := matchPhrase(code, i, inlineClauses); n > 0 && p.isClause(upper, top) {
+			p.breakLine(top.level)
+			p.phrase(code[i : i+n])
+			top.clause = true
+			return n
+		}
+		if n := matchPhrase(code, i, setOperations); n > 0 {
+			p.breakLine(top.level)
+			p.phrase(code[i : i+n])
+			top.clause = false
+			p.breakLine(top.level)
+			return n
+		}
+		if n := matchJoin(code, i); n > 0 && top.clause {
+			p.breakLine(top.level + 1)
+			p.phrase(code[i : i+n])
+			return n
+		}
+	}
+
+	switch upper {
+	case "AND", "OR", "XOR":
+		if upper == "AND" && p.between {
+			p.between = false
+			break
+		}
+		var level int
+		switch {
+		case top.kind == frameBlock && top.clause:
+			level = top.level + 1
+		case top.kind == frameCase:
+			level = top.level
+		default:
+			p.phrase(code[i : i+1])
+			return 1
+		}
+		if p.options.LogicalOperatorNewline == LogicalOperatorNewlineAfter {
+			p.phrase(code[i : i+1])
+			p.breakLine(level)
+		} else {
+			p.breakLine(level)
+			p.phrase(code[i : i+1])
+		}
+		return 1
+	case "BETWEEN":
+		p.between = true
+	case "CASE":
+		p.phrase(code[i : i+1])
+		p.frames = append(p.frames, nativeFrame{kind: frameCase, level: p.line + 1})
+		return 1
+	case "WHEN", "ELSE":
+		if top.kind == frameCase {
+			p.breakLine(top.level)
+		}
+	case "END":
+		if top.kind == frameCase {
+			p.breakLine(top.level - 1)
+			p.frames = p.frames[:len(p.frames)-1]
+		}
+	}
+
+	t := code[i].token
+	next := nextCode(code, i)
+	// KEY is left out of keywords as a common column name, but not after PRIMARY or FOREIGN.
+	keyword := isKeyword(upper) ||
+		upper == "KEY" && (strings.EqualFold(p.prev.text, "PRIMARY") || strings.EqualFold(p.prev.text, "FOREIGN"))
+	switch {
+	case keyword && dataTypes[upper]:
+		t.text = applyCase(t.text, p.options.DataTypeCase)
+	case keyword:
+		t.text = applyCase(t.text, p.options.KeywordCase)
+	case next != nil && next.text == "(" && !tableKeywords[strings.ToUpper(p.prev.text)]:
+		t.text = applyCase(t.text, p.options.FunctionCase)
+	default:
+		t.text = applyCase(t.text, p.options.IdentifierCase)
+	}
+	p.write(t)
+	return 1
+}
+
+// isClause reports whether the clause keyword upper starts a clause of the block top, rather than
+// being part of an expression such as IS DISTINCT FROM or TIMESTAMP WITH TIME ZONE.
+func (p *nativePrinter) isClause(upper string, top *nativeFrame) bool {
+	prev := strings.ToUpper(p.prev.text)
+	switch upper {
+	case "WITH":
+		return !top.clause
+	case "FROM":
+		return prev != "DISTINCT"
+	case "SET":
+		return prev != "CHARACTER"
+	case "UPDATE":
+		return prev != "DO" && prev != "KEY"
+	}
+	return true
+}
+
+// phrase writes the keywords words, separated by single spaces.
+func (p *nativePrinter) phrase(words []nativeToken) {
+	for _, w := range words {
+		t := w.token
+		t.text = applyCase(t.text, p.options.KeywordCase)
+		p.write(t)
+	}
+}
+
+// comment writes a comment, keeping it on its own line or at the end of the previous one as in
+// the input.
+func (p *nativePrinter) comment(t nativeToken) {
+	text := strings.TrimRight(t.text, " \t\r\n")
+	lineComment := strings.HasPrefix(text, "--") || strings.HasPrefix(text, "#")
+	if !t.newlineBefore && (p.pending || p.separate > 0 && lineComment) {
+		// Trailing comment of a line already ended
+		p.out = append(p.out, ' ')
+		p.out = append(p.out, text...)
+	} else {
+		if t.newlineBefore && !p.lineStart && !p.pending && p.separate == 0 {
+			p.breakLine(p.contentLevel())
+		}
+		p.flush()
+		if !p.lineStart {
+			p.out = append(p.out, ' ')
+		}
+		p.out = append(p.out, text...)
+		p.lineStart = false
+	}
+	if (lineComment || t.newlineAfter) && !p.pending && p.separate == 0 {
+		p.breakLine(p.contentLevel())
+	}
+}
+
+// prevComment reports whether the current line ends with a line comment.
+func (p *nativePrinter) prevComment() bool {
+	line := p.out[strings.LastIndexByte(string(p.out), '\n')+1:]
+	for _, t := range tokenize(string(line), p.options.Language) {
+		if t.kind == tokenComment && !strings.HasPrefix(t.text, "/*") {
+			return true
+		}
+	}
+	return false
+}
+
+// contentLevel returns the indentation level of a line continuing the current frame.
+func (p *nativePrinter) contentLevel() int {
+	top := p.frames[len(p.frames)-1]
+	switch {
+	case top.kind == frameBlock && top.clause:
+		return top.level + 1
+	case top.kind == frameParen:
+		return p.line
+	}
+	return top.level
+}
+
+// breakLine makes the next token start a line indented by level, unless the output is empty.
+func (p *nativePrinter) breakLine(level int) {
+	if len(p.out) == 0 && p.separate == 0 {
+		p.line = level
+		return
+	}
+	p.pending = true
+	p.pendingAt = level
+}
+
+// flush writes the line breaks due before the next token.
+func (p *nativePrinter) flush() {
+	switch {
+	case p.separate > 0:
+		p.out = append(trimSpaces(p.out), strings.Repeat("\n", p.separate)...)
+		p.separate = 0
+		p.pending = false
+		p.line = 0
+		p.lineStart = true
+	case p.pending:
+		p.out = append(trimSpaces(p.out), '\n')
+		p.out = append(p.out, strings.Repeat(p.unit, p.pendingAt)...)
+		p.pending = false
+		p.line = p.pendingAt
+		p.lineStart = true
+	}
+}
+
+// write writes the token t, preceded by a space if it needs one.
+func (p *nativePrinter) write(t token) {
+	p.flush()
+	if !p.lineStart && p.spaceBefore(t) {
+		p.out = append(p.out, ' ')
+	}
+	p.out = append(p.out, t.text...)
+	p.lineStart = false
+	p.unary = t.kind == tokenOperator && (t.text == "-" || t.text == "+" || t.text == "~") && !p.endsOperand()
+	p.prev = t
+}
+
+// spaceBefore reports whether a space separates the token t from the previous one.
+func (p *nativePrinter) spaceBefore(t token) bool {
+	switch t.text {
+	case ")", ",", ";", ".", "]", "::":
+		return false
+	case "(":
+		// Like the output of sql-formatter once joinCalls has run: keywords other than data types
+		// keep the space the input has before (, as in IN (1, 2)
+		upper := strings.ToUpper(p.prev.text)
+		return p.prev.kind == tokenWord && isKeyword(upper) && !dataTypes[upper] && p.prev.end != t.start
+	case "[":
+		return !p.endsOperand()
+	}
+	switch p.prev.text {
+	case "(", ".", "[", "::":
+		return false
+	}
+	// Never glue operators into a comment opener, as in a - -1
+	if last := p.prev.text[max(len(p.prev.text)-1, 0):]; last == "-" && t.text[0] == '-' || last == "/" && t.text[0] == '*' {
+		return true
+	}
+	if p.unary {
+		return false
+	}
+	if p.options.DenseOperators && (t.kind == tokenOperator || p.prev.kind == tokenOperator) {
+		return false
+	}
+	return true
+}
+
+// endsOperand reports whether the previous token can end an operand, so that a following - or +
+// is a binary operator.
+func (p *nativePrinter) endsOperand() bool {
+	switch p.prev.kind {
+	case tokenWord:
+		return !isKeyword(strings.ToUpper(p.prev.text))
+	case tokenIdent, tokenNumber, tokenString, tokenParam:
+		return true
+	case tokenPunct:
+		return p.prev.text == ")" || p.prev.text == "]"
+	}
+	return false
+}
+
+// matchPhrase returns the number of words of the first of phrases starting at code[i], or 0.
+func matchPhrase(code []nativeToken, i int, phrases [][]string) int {
+	for _, phrase := range phrases {
+		if i+len(phrase) > len(code) {
+			continue
+		}
+		n := 0
+		for n < len(phrase) && code[i+n].kind == tokenWord && strings.EqualFold(code[i+n].text, phrase[n]) {
+			n++
+		}
+		if n == len(phrase) {
+			return n
+		}
+	}
+	return 0
+}
+
+// matchJoin returns the number of words of the join phrase ([NATURAL] [LEFT] [OUTER] JOIN and the
+// like) starting at code[i], or 0.
+func matchJoin(code []nativeToken, i int) int {
+	for n := i; n < len(code) && code[n].kind == tokenWord; n++ {
+		upper := strings.ToUpper(code[n].text)
+		if upper == "JOIN" || upper == "STRAIGHT_JOIN" {
+			return n - i + 1
+		}
+		if !joinModifiers[upper] {
+			break
+		}
+	}
+	return 0
+}
+
+// nextCode returns the token following code[i], skipping comments, or nil.
+func nextCode(code []nativeToken, i int) *nativeToken {
+	for j := i + 1; j < len(code); j++ {
+		if code[j].kind != tokenComment {
+			return &code[j]
+		}
+	}
+	return nil
+}
+
+func trimSpaces(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == ' ' || b[len(b)-1] == '\t') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
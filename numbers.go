@@ -0,0 +1,92 @@
+package sqlfmt
+
+import "strings"
+
+// formatsNumbers reports whether options rewrite numeric literals.
+func formatsNumbers(options FormatOptions) bool {
+	return options.NumberCase != "" && options.NumberCase != CaseOptionPreserve ||
+		options.LeadingZero != "" && options.LeadingZero != LeadingZeroPreserve ||
+		options.GroupDigits && lexiconFor(options.Language).digitSeparators
+}
+
+// formatNumbers rewrites the numeric literals of formatted with formatNumber.
+func formatNumbers(formatted string, options FormatOptions) string {
+	var b strings.Builder
+	b.Grow(len(formatted))
+	scanTokens(formatted, options.Language, func(t token) bool {
+		if t.kind == tokenNumber {
+			b.WriteString(formatNumber(t.text, options))
+		} else {
+			b.WriteString(t.text)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// formatNumber returns the numeric literal n written as set by options.NumberCase,
+// options.LeadingZero and options.GroupDigits. Hexadecimal literals keep their lowercase 0x
+// prefix whatever the case of their digits.
+func formatNumber(n string, options FormatOptions) string {
+	if len(n) > 2 && n[0] == '0' && (n[1] == 'x' || n[1] == 'X') {
+		switch options.NumberCase {
+		case CaseOptionUpper:
+			return "0x" + strings.ToUpper(n[2:])
+		case CaseOptionLower:
+			return "0x" + strings.ToLower(n[2:])
+		}
+		return n
+	}
+
+	mantissa, exponent := n, ""
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		mantissa, exponent = n[:i], n[i+1:]
+	}
+	whole, fraction, point := strings.Cut(mantissa, ".")
+	switch {
+	case !point:
+	case options.LeadingZero == LeadingZeroAlways && whole == "":
+		whole = "0"
+	case options.LeadingZero == LeadingZeroNever && whole == "0" && fraction != "":
+		whole = ""
+	}
+	if options.GroupDigits && lexiconFor(options.Language).digitSeparators {
+		whole = groupDigits(whole)
+	}
+
+	var b strings.Builder
+	b.WriteString(whole)
+	if point {
+		b.WriteString(".")
+		b.WriteString(fraction)
+	}
+	if exponent != "" {
+		switch options.NumberCase {
+		case CaseOptionUpper:
+			b.WriteString("E")
+		case CaseOptionLower:
+			b.WriteString("e")
+		default:
+			b.WriteByte(n[len(mantissa)])
+		}
+		b.WriteString(exponent)
+	}
+	return b.String()
+}
+
+// groupDigits separates digits, the integer part of a number, in groups of three with underscores
+// if there are more than four of them, replacing the underscores already there.
+func groupDigits(digits string) string {
+	digits = strings.ReplaceAll(digits, "_", "")
+	if len(digits) <= 4 {
+		return digits
+	}
+	var b strings.Builder
+	for i, c := range []byte(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,236 @@
+package sqlfmt
+
+// The With methods return a copy of the options with one field changed, so that variations of a
+// base style can be built without modifying it:
+//
+//	options := DefaultFormatOptions.WithLanguage(LanguagePostgreSQL).WithTabWidth(2)
+
+// WithDataTypeCase returns a copy of o with the case of data types set to dataTypeCase.
+func (o FormatOptions) WithDataTypeCase(dataTypeCase CaseOption) FormatOptions {
+	o.DataTypeCase = dataTypeCase
+	return o
+}
+
+// WithDenseOperators returns a copy of o with whether operators are packed densely set to dense.
+func (o FormatOptions) WithDenseOperators(dense bool) FormatOptions {
+	o.DenseOperators = dense
+	return o
+}
+
+// WithExpressionWidth returns a copy of o with the maximum length of parenthesized expressions set to width.
+func (o FormatOptions) WithExpressionWidth(width int) FormatOptions {
+	o.ExpressionWidth = width
+	return o
+}
+
+// WithFunctionCase returns a copy of o with the case of function names set to functionCase.
+func (o FormatOptions) WithFunctionCase(functionCase CaseOption) FormatOptions {
+	o.FunctionCase = functionCase
+	return o
+}
+
+// WithIdentifierCase returns a copy of o with the case of identifiers set to identifierCase.
+func (o FormatOptions) WithIdentifierCase(identifierCase CaseOption) FormatOptions {
+	o.IdentifierCase = identifierCase
+	return o
+}
+
+// WithIndentStyle returns a copy of o with the indentation style set to style.
+func (o FormatOptions) WithIndentStyle(style IndentStyleOption) FormatOptions {
+	o.IndentStyle = style
+	return o
+}
+
+// WithKeywordCase returns a copy of o with the case of reserved keywords set to keywordCase.
+func (o FormatOptions) WithKeywordCase(keywordCase CaseOption) FormatOptions {
+	o.KeywordCase = keywordCase
+	return o
+}
+
+// WithLanguage returns a copy of o with the SQL dialect set to language.
+func (o FormatOptions) WithLanguage(language LanguageOption) FormatOptions {
+	o.Language = language
+	return o
+}
+
+// WithLinesBetweenQueries returns a copy of o with the number of empty lines between statements set to lines.
+func (o FormatOptions) WithLinesBetweenQueries(lines int) FormatOptions {
+	o.LinesBetweenQueries = lines
+	return o
+}
+
+// WithLogicalOperatorNewline returns a copy of o with the newline placement for logical operators set to placement.
+func (o FormatOptions) WithLogicalOperatorNewline(placement LogicalOperatorNewlineOption) FormatOptions {
+	o.LogicalOperatorNewline = placement
+	return o
+}
+
+// WithNewlineBeforeSemicolon returns a copy of o with whether semicolons go on a separate line set to newline.
+func (o FormatOptions) WithNewlineBeforeSemicolon(newline bool) FormatOptions {
+	o.NewlineBeforeSemicolon = newline
+	return o
+}
+
+// WithTabWidth returns a copy of o with the number of spaces per indentation level set to width.
+func (o FormatOptions) WithTabWidth(width int) FormatOptions {
+	o.TabWidth = width
+	return o
+}
+
+// WithUseTabs returns a copy of o with whether to indent with tabs set to useTabs.
+func (o FormatOptions) WithUseTabs(useTabs bool) FormatOptions {
+	o.UseTabs = useTabs
+	return o
+}
+
+// WithCatalog returns a copy of o with the catalog of known tables set to catalog.
+func (o FormatOptions) WithCatalog(catalog Catalog) FormatOptions {
+	o.Catalog = catalog
+	return o
+}
+
+// WithQualifyTables returns a copy of o with whether unqualified tables are prefixed with their schema set to qualify.
+func (o FormatOptions) WithQualifyTables(qualify bool) FormatOptions {
+	o.QualifyTables = qualify
+	return o
+}
+
+// WithColumnCatalog returns a copy of o with the columns of known tables set to catalog.
+func (o FormatOptions) WithColumnCatalog(catalog ColumnCatalog) FormatOptions {
+	o.ColumnCatalog = catalog
+	return o
+}
+
+// WithExpandStar returns a copy of o with whether SELECT * is expanded into column lists set to expand.
+func (o FormatOptions) WithExpandStar(expand bool) FormatOptions {
+	o.ExpandStar = expand
+	return o
+}
+
+// WithVerifyTokens returns a copy of o with whether the output is verified to have the input's tokens set to verify.
+func (o FormatOptions) WithVerifyTokens(verify bool) FormatOptions {
+	o.VerifyTokens = verify
+	return o
+}
+
+// WithVerifyIdempotent returns a copy of o with whether the output is verified to be a fixed point of formatting set to verify.
+func (o FormatOptions) WithVerifyIdempotent(verify bool) FormatOptions {
+	o.VerifyIdempotent = verify
+	return o
+}
+
+// WithMaxConsecutiveBlankLines returns a copy of o with the maximum number of blank lines kept from the input set to max.
+func (o FormatOptions) WithMaxConsecutiveBlankLines(max int) FormatOptions {
+	o.MaxConsecutiveBlankLines = max
+	return o
+}
+
+// WithPreserveLineBreaks returns a copy of o with whether the line breaks of the input are kept set to preserve.
+func (o FormatOptions) WithPreserveLineBreaks(preserve bool) FormatOptions {
+	o.PreserveLineBreaks = preserve
+	return o
+}
+
+// WithAlignOperators returns a copy of o with whether operators of consecutive predicates are aligned set to align.
+func (o FormatOptions) WithAlignOperators(align bool) FormatOptions {
+	o.AlignOperators = align
+	return o
+}
+
+// WithAlignAliases returns a copy of o with whether SELECT list aliases are aligned set to align.
+func (o FormatOptions) WithAlignAliases(align bool) FormatOptions {
+	o.AlignAliases = align
+	return o
+}
+
+// WithDenseOperatorExceptions returns a copy of o with the operators that keep their spaces when
+// DenseOperators is set to exceptions, a comma-separated list of operators and groups of operators.
+func (o FormatOptions) WithDenseOperatorExceptions(exceptions string) FormatOptions {
+	o.DenseOperatorExceptions = exceptions
+	return o
+}
+
+// WithOrderByDependencies returns a copy of o with whether schema statements are reordered by dependencies set to order.
+func (o FormatOptions) WithOrderByDependencies(order bool) FormatOptions {
+	o.OrderByDependencies = order
+	return o
+}
+
+// WithSortSchema returns a copy of o with whether schema statements are sorted by kind and name set to sort.
+func (o FormatOptions) WithSortSchema(sort bool) FormatOptions {
+	o.SortSchema = sort
+	return o
+}
+
+// WithDumpFormat returns a copy of o with the dump tool that produced the input set to format.
+func (o FormatOptions) WithDumpFormat(format DumpFormatOption) FormatOptions {
+	o.DumpFormat = format
+	return o
+}
+
+// WithDumpInserts returns a copy of o with how the INSERT statements of a dump are handled set to inserts.
+func (o FormatOptions) WithDumpInserts(inserts DumpInsertsOption) FormatOptions {
+	o.DumpInserts = inserts
+	return o
+}
+
+// WithNumberCase returns a copy of o with the case of exponent markers and hexadecimal digits set to numberCase.
+func (o FormatOptions) WithNumberCase(numberCase CaseOption) FormatOptions {
+	o.NumberCase = numberCase
+	return o
+}
+
+// WithLeadingZero returns a copy of o with how decimals below one are written set to leadingZero.
+func (o FormatOptions) WithLeadingZero(leadingZero LeadingZeroOption) FormatOptions {
+	o.LeadingZero = leadingZero
+	return o
+}
+
+// WithGroupDigits returns a copy of o with whether the digits of long integers are grouped set to group.
+func (o FormatOptions) WithGroupDigits(group bool) FormatOptions {
+	o.GroupDigits = group
+	return o
+}
+
+// WithPredicateChainWidth returns a copy of o with the maximum width of AND/OR chains kept on one line set to width.
+func (o FormatOptions) WithPredicateChainWidth(width int) FormatOptions {
+	o.PredicateChainWidth = width
+	return o
+}
+
+// WithGeneratedMarker returns a copy of o with the comment marking generated input set to marker.
+func (o FormatOptions) WithGeneratedMarker(marker string) FormatOptions {
+	o.GeneratedMarker = marker
+	return o
+}
+
+// WithExecutableComments returns a copy of o with how MySQL executable comments are handled set to executable.
+func (o FormatOptions) WithExecutableComments(executable ExecutableCommentsOption) FormatOptions {
+	o.ExecutableComments = executable
+	return o
+}
+
+// WithSQLXBinds returns a copy of o with whether sqlx binds are kept as written set to sqlx.
+func (o FormatOptions) WithSQLXBinds(sqlx bool) FormatOptions {
+	o.SQLXBinds = sqlx
+	return o
+}
+
+// WithMapLines returns a copy of o with whether output lines are mapped to their input set to mapLines.
+func (o FormatOptions) WithMapLines(mapLines bool) FormatOptions {
+	o.MapLines = mapLines
+	return o
+}
+
+// NoLinesBetweenQueries is the LinesBetweenQueries putting statements on consecutive lines, since
+// 0 stands for the sql-formatter default. Merge sets it for a LinesBetweenQueries of 0.
+const NoLinesBetweenQueries = -1
+
+// BlankLinesBetweenQueries returns the number of empty lines put between statements with o: its
+// LinesBetweenQueries, 1 if that is 0, or 0 if it is NoLinesBetweenQueries.
+func (o FormatOptions) BlankLinesBetweenQueries() int {
+	if o.LinesBetweenQueries == 0 {
+		return 1
+	}
+	return max(o.LinesBetweenQueries, 0)
+}
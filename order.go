@@ -0,0 +1,205 @@
+package sqlfmt
+
+import "strings"
+
+// schemaRanks orders the kinds of schema statements for OrderByDependencies: objects are created
+// before the objects that usually depend on them, and grants and comments come last. Statements
+// of other kinds are not moved.
+var schemaRanks = map[string]int{
+	"DATABASE":          0,
+	"ROLE":              0,
+	"USER":              0,
+	"SCHEMA":            1,
+	"EXTENSION":         2,
+	"TYPE":              3,
+	"DOMAIN":            3,
+	"SEQUENCE":          4,
+	"FUNCTION":          5,
+	"PROCEDURE":         5,
+	"TABLE":             6,
+	"VIEW":              7,
+	"MATERIALIZED VIEW": 8,
+	"INDEX":             9,
+	"TRIGGER":           10,
+	"ALTER":             11,
+	"COMMENT":           12,
+	"GRANT":             13,
+	"REVOKE":            13,
+}
+
+// schemaRank returns the rank of obj in schemaRanks, and false if the statement must not be moved.
+func schemaRank(obj SchemaObject) (int, bool) {
+	kind := obj.Kind
+	if strings.HasPrefix(kind, "ALTER ") {
+		kind = "ALTER"
+	}
+	rank, ok := schemaRanks[kind]
+	return rank, ok
+}
+
+// orderByDependencies reorders the schema statements of sql so that each comes after the objects
+// it depends on, and objects of different kinds come in the order of schemaRanks. Only runs of
+// consecutive CREATE, ALTER, COMMENT, GRANT and REVOKE statements are reordered: any other
+// statement, such as SET or INSERT, stays in place and statements are not moved across it.
+// Comments before a statement move with it, as do comments on the line where it ends. With byName,
+// statements of the same rank are sorted by the name of their object rather than kept in input
+// order, which makes the order canonical.
+func orderByDependencies(sql string, lang LanguageOption, byName bool) string {
+	g := Dependencies(sql, lang)
+	if len(g.Objects) < 2 {
+		return sql
+	}
+
+	// spans[i] is the text of statement i, with its comments.
+	spans := make([][2]int, len(g.Objects))
+	for i, obj := range g.Objects {
+		spans[i] = [2]int{obj.Start, obj.End}
+		if i == 0 || strings.Contains(sql[spans[i-1][1]:obj.Start], "\n") {
+			continue
+		}
+		// A comment on the line where the previous statement ends belongs to it.
+		tokens := tokenize(sql[obj.Start:obj.End], lang)
+		if len(tokens) > 0 && tokens[0].kind == tokenComment {
+			spans[i-1][1] = obj.Start + tokens[0].end
+			for _, t := range tokens[1:] {
+				if t.kind != tokenSpace {
+					spans[i][0] = obj.Start + t.start
+					break
+				}
+			}
+		}
+	}
+
+	order := make([]int, 0, len(g.Objects))
+	for i := 0; i < len(g.Objects); {
+		if _, ok := schemaRank(g.Objects[i]); !ok {
+			order = append(order, i)
+			i++
+			continue
+		}
+		j := i
+		for j < len(g.Objects) {
+			if _, ok := schemaRank(g.Objects[j]); !ok {
+				break
+			}
+			j++
+		}
+		order = append(order, sortRun(g, i, j, byName)...)
+		i = j
+	}
+
+	var b strings.Builder
+	b.WriteString(sql[:spans[0][0]])
+	for k, i := range order {
+		if k > 0 {
+			b.WriteString(sql[spans[k-1][1]:spans[k][0]]) // keep the layout between positions
+		}
+		text := sql[spans[i][0]:spans[i][1]]
+		if k < len(order)-1 && !strings.HasSuffix(sql[:g.Objects[i].End], ";") {
+			// The last statement of the input, moved: terminate it before any trailing comment.
+			sig := significant(tokenize(text, lang))
+			end := sig[len(sig)-1].end
+			text = text[:end] + ";" + text[end:]
+		}
+		b.WriteString(text)
+	}
+	b.WriteString(sql[spans[len(spans)-1][1]:])
+	return b.String()
+}
+
+// sortRun returns the indexes of the statements g.Objects[start:end] in dependency order. Among the
+// statements whose dependencies in the run are satisfied, the one with the lowest rank comes
+// first, then with byName the one whose object name sorts first, then the one that comes first in
+// the input. A dependency cycle is broken the same way, with the statements left.
+//
+// Besides the statement creating it, a statement depends on the ALTER statements before it of
+// each object it depends on, which may add the columns it uses. Functions, procedures and ALTER
+// statements, whose needs are not fully known since function bodies are not looked into, are
+// never moved ahead of a statement that comes before them.
+func sortRun(g SchemaGraph, start, end int, byName bool) []int {
+	placed := make([]bool, end-start)
+	after := make([][]int, end-start) // statements of the run each must come after
+	for i := start; i < end; i++ {
+		obj := g.Objects[i]
+		if pinned(obj) {
+			for j := start; j < i; j++ {
+				after[i-start] = append(after[i-start], j)
+			}
+		}
+		for _, d := range obj.DependsOn {
+			if d.Object >= start && d.Object < end && d.Object != i {
+				after[i-start] = append(after[i-start], d.Object)
+			}
+			for j := start; j < i; j++ {
+				if alter := g.Objects[j]; strings.HasPrefix(alter.Kind, "ALTER ") && sameObject(alter.Name, d.Name) {
+					after[i-start] = append(after[i-start], j)
+				}
+			}
+		}
+	}
+	ready := func(i int) bool {
+		for _, j := range after[i-start] {
+			if !placed[j-start] {
+				return false
+			}
+		}
+		return true
+	}
+	before := func(i, j int) bool {
+		ri, _ := schemaRank(g.Objects[i])
+		rj, _ := schemaRank(g.Objects[j])
+		if ri != rj {
+			return ri < rj
+		}
+		if byName {
+			if c := strings.Compare(sortName(g.Objects[i]), sortName(g.Objects[j])); c != 0 {
+				return c < 0
+			}
+		}
+		return i < j
+	}
+
+	order := make([]int, 0, end-start)
+	for len(order) < end-start {
+		next, fallback := -1, -1
+		for i := start; i < end; i++ {
+			if placed[i-start] {
+				continue
+			}
+			if fallback < 0 || before(i, fallback) {
+				fallback = i
+			}
+			if ready(i) && (next < 0 || before(i, next)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			next = fallback // a cycle
+		}
+		placed[next-start] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// pinned reports whether obj must not move ahead of the statements before it.
+func pinned(obj SchemaObject) bool {
+	return obj.Kind == "FUNCTION" || obj.Kind == "PROCEDURE" || strings.HasPrefix(obj.Kind, "ALTER ")
+}
+
+// sameObject reports whether a and b may name the same object: like SchemaGraph.creator, a name
+// without schema matches the name in any schema.
+func sameObject(a, b TableRef) bool {
+	return a.Name != "" && strings.EqualFold(a.Name, b.Name) &&
+		(a.Schema == "" || b.Schema == "" || strings.EqualFold(a.Schema, b.Schema))
+}
+
+// sortName returns the name obj is sorted by: the lower-cased name of its object, or of the first
+// object it depends on for statements without a name, such as unnamed indexes.
+func sortName(obj SchemaObject) string {
+	name := obj.Name
+	if name.Name == "" && len(obj.DependsOn) > 0 {
+		name = obj.DependsOn[0].Name
+	}
+	return strings.ToLower(name.String())
+}
@@ -0,0 +1,80 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrderByDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string // statements, by their first words, in the expected order
+	}{
+		{
+			name: "alter before views and functions",
+			sql: `CREATE TABLE t(a int);
+ALTER TABLE t ADD COLUMN b int;
+CREATE VIEW v AS SELECT b FROM t;
+CREATE TABLE u(id int);
+CREATE FUNCTION g() RETURNS SETOF u AS $$ SELECT * FROM u $$ LANGUAGE sql;`,
+			want: []string{"CREATE TABLE t", "CREATE TABLE u", "ALTER TABLE t", "CREATE VIEW v", "CREATE FUNCTION g"},
+		},
+		{
+			name: "function argument types",
+			sql: `CREATE FUNCTION f(x app.point, OUT y int) RETURNS int AS $$ SELECT 1 $$ LANGUAGE sql;
+CREATE TABLE app.point(x int, y int);`,
+			want: []string{"CREATE TABLE app.point", "CREATE FUNCTION f"},
+		},
+		{
+			name: "returns table",
+			sql: `CREATE FUNCTION f() RETURNS TABLE(p app.point) AS $$ SELECT NULL $$ LANGUAGE sql;
+CREATE TABLE app.point(x int, y int);`,
+			want: []string{"CREATE TABLE app.point", "CREATE FUNCTION f"},
+		},
+		{
+			name: "functions stay after earlier tables",
+			sql: `CREATE TABLE t(a int);
+CREATE FUNCTION f() RETURNS int AS $$ SELECT count(*) FROM t $$ LANGUAGE sql;`,
+			want: []string{"CREATE TABLE t", "CREATE FUNCTION f"},
+		},
+		{
+			name: "views after tables",
+			sql: `CREATE VIEW v AS SELECT a FROM t;
+CREATE TABLE t(a int);`,
+			want: []string{"CREATE TABLE t", "CREATE VIEW v"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orderByDependencies(tt.sql, LanguagePostgreSQL, false)
+			pos := -1
+			for _, stmt := range tt.want {
+				i := strings.Index(got, stmt)
+				if i < 0 {
+					t.Fatalf("%q missing from output:\n%s", stmt, got)
+				}
+				if i < pos {
+					t.Fatalf("%q is out of order in output:\n%s", stmt, got)
+				}
+				pos = i
+			}
+		})
+	}
+}
+
+func TestDependenciesFunctionTypes(t *testing.T) {
+	g := Dependencies(`CREATE FUNCTION f(IN a t, b u.c%TYPE, v%ROWTYPE, d double precision DEFAULT 0) RETURNS SETOF w
+AS $$ SELECT 1 $$ LANGUAGE sql;`, LanguagePostgreSQL)
+	if len(g.Objects) != 1 {
+		t.Fatalf("got %d objects, want 1", len(g.Objects))
+	}
+	var got []string
+	for _, d := range g.Objects[0].DependsOn {
+		got = append(got, d.Name.String()+" "+d.Reason)
+	}
+	want := []string{"t type", "u type", "v type", "w type"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got dependencies %q, want %q", got, want)
+	}
+}
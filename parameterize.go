@@ -0,0 +1,234 @@
+package sqlfmt
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrPlaceholdersPresent is returned by Parameterize when the input already contains bind parameters.
+var ErrPlaceholdersPresent = errors.New("SQL already contains placeholders")
+
+// PlaceholderStyle defines the bind parameter syntax produced by Parameterize.
+type PlaceholderStyle string
+
+const (
+	// PlaceholderQuestion produces positional ? placeholders (MySQL, SQLite, ...).
+	PlaceholderQuestion PlaceholderStyle = "?"
+	// PlaceholderDollar produces numbered $1, $2, ... placeholders (PostgreSQL, ...).
+	PlaceholderDollar PlaceholderStyle = "$"
+	// PlaceholderColon produces numbered :1, :2, ... placeholders (Oracle, ...).
+	PlaceholderColon PlaceholderStyle = ":"
+	// PlaceholderAt produces named @p1, @p2, ... placeholders (SQL Server, ...).
+	PlaceholderAt PlaceholderStyle = "@"
+)
+
+// placeholder returns the n-th (1-based) placeholder in this style.
+func (s PlaceholderStyle) placeholder(n int) (string, error) {
+	switch s {
+	case PlaceholderQuestion:
+		return "?", nil
+	case PlaceholderDollar, PlaceholderColon:
+		return string(s) + strconv.Itoa(n), nil
+	case PlaceholderAt:
+		return "@p" + strconv.Itoa(n), nil
+	default:
+		return "", fmt.Errorf("unknown placeholder style %q", string(s))
+	}
+}
+
+// typedLiteralKeywords are keywords introducing a typed literal (DATE '2024-01-01'), whose string
+// cannot be replaced by a placeholder.
+var typedLiteralKeywords = map[string]bool{
+	"DATE": true, "TIME": true, "TIMESTAMP": true, "TIMESTAMPTZ": true, "DATETIME": true, "INTERVAL": true,
+}
+
+// Parameterize is the inverse of Interpolate: it replaces the string and numeric literals of sql
+// with placeholders in the given style and returns the rewritten SQL together with the extracted
+// values in placeholder order. It is intended for converting queries built by string
+// concatenation into parameterized ones.
+//
+// String literals become string values ([]byte for hexadecimal literals), integer literals become
+// int64 and other numeric literals become float64. Literals that cannot be bound are left in
+// place: typed literals such as DATE '2024-01-01', and ordinal positions in ORDER BY and GROUP BY.
+// Parameterize only makes sense for DML; literals in DDL are rewritten as well.
+func Parameterize(sql string, dialect LanguageOption, style PlaceholderStyle) (string, []any, error) {
+	if _, err := style.placeholder(1); err != nil {
+		return "", nil, err
+	}
+
+	tokens := tokenize(sql, dialect)
+	sig := significant(tokens)
+	for _, t := range sig {
+		if t.kind == tokenParam {
+			return "", nil, fmt.Errorf("%w: %s", ErrPlaceholdersPresent, t.text)
+		}
+	}
+
+	// Decide which significant tokens are replaced, keyed by their start offset.
+	lx := lexiconFor(dialect)
+	replace := make(map[int]any)
+	for i, t := range sig {
+		var prev, next token
+		if i > 0 {
+			prev = sig[i-1]
+		}
+		if i+1 < len(sig) {
+			next = sig[i+1]
+		}
+
+		switch t.kind {
+		case tokenString:
+			if prev.kind == tokenWord && typedLiteralKeywords[strings.ToUpper(prev.text)] {
+				continue
+			}
+			if v, ok := lx.stringValue(t.text); ok {
+				replace[t.start] = v
+			}
+		case tokenNumber:
+			if isOrdinal(sig, i, prev, next) {
+				continue
+			}
+			if v, ok := numberValue(t.text); ok {
+				replace[t.start] = v
+			}
+		}
+	}
+
+	var (
+		b    strings.Builder
+		args []any
+	)
+	for _, t := range tokens {
+		v, ok := replace[t.start]
+		if !ok || t.kind == tokenSpace || t.kind == tokenComment {
+			b.WriteString(t.text)
+			continue
+		}
+		args = append(args, v)
+		ph, _ := style.placeholder(len(args))
+		b.WriteString(ph)
+	}
+
+	return b.String(), args, nil
+}
+
+// isOrdinal reports whether the number at sig[i] is a column ordinal in an ORDER BY or GROUP BY list.
+func isOrdinal(sig []token, i int, prev, next token) bool {
+	if !(prev.text == "," || strings.EqualFold(prev.text, "BY")) {
+		return false
+	}
+	if next.text != "" && next.text != "," && next.text != ";" && next.text != ")" && next.kind != tokenWord {
+		return false
+	}
+	// Walk back over the list to find the clause it belongs to.
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch t := sig[j]; {
+		case t.text == ")":
+			depth++
+		case t.text == "(":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case depth == 0 && t.kind == tokenWord && strings.EqualFold(t.text, "BY") && j > 0:
+			kw := strings.ToUpper(sig[j-1].text)
+			return kw == "ORDER" || kw == "GROUP"
+		case depth == 0 && t.text == ";":
+			return false
+		}
+	}
+	return false
+}
+
+// stringValue decodes a string literal token into the value it denotes.
+// It reports false for literals that have no sensible Go value, such as bit strings.
+func (lx lexicon) stringValue(text string) (any, bool) {
+	if strings.HasPrefix(text, "$") {
+		tag := text[:strings.IndexByte(text[1:], '$')+2]
+		if len(text) < 2*len(tag) {
+			return nil, false
+		}
+		return text[len(tag) : len(text)-len(tag)], true
+	}
+
+	quote := strings.IndexAny(text, `'"`)
+	if quote < 0 || len(text)-quote < 2 || text[len(text)-1] != text[quote] {
+		return nil, false
+	}
+	prefix := strings.ToUpper(text[:quote])
+	body := text[quote+1 : len(text)-1]
+	q := text[quote : quote+1]
+
+	switch prefix {
+	case "X":
+		b, err := hex.DecodeString(body)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	case "", "N":
+		if lx.backslashEscapes {
+			return unescapeBackslashes(body, q), true
+		}
+		return strings.ReplaceAll(body, q+q, q), true
+	case "E":
+		return unescapeBackslashes(body, q), true
+	default:
+		return nil, false
+	}
+}
+
+// unescapeBackslashes decodes C-style backslash escapes and doubled quotes in a string literal body.
+func unescapeBackslashes(body, quote string) string {
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && i+1 < len(body):
+			i++
+			switch body[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'Z':
+				b.WriteByte(26)
+			default:
+				b.WriteByte(body[i])
+			}
+		case c == quote[0] && i+1 < len(body) && body[i+1] == quote[0]:
+			b.WriteByte(c)
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// numberValue decodes a numeric literal token.
+func numberValue(text string) (any, bool) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		if n, err := strconv.ParseInt(text[2:], 16, 64); err == nil {
+			return n, true
+		}
+		return nil, false
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
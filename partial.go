@@ -0,0 +1,104 @@
+package sqlfmt
+
+// PartialFormatOptions is a set of overrides for FormatOptions. Its fields mirror those of
+// FormatOptions and decode from the same JSON keys, but a nil field means "not set", so that
+// values equal to the zero value, such as LinesBetweenQueries 0 or UseTabs false, can still be
+// layered over a base with Merge. A LinesBetweenQueries of 0 puts statements on consecutive lines.
+type PartialFormatOptions struct {
+	DataTypeCase           *CaseOption                   `json:"dataTypeCase,omitempty"`
+	DenseOperators         *bool                         `json:"denseOperators,omitempty"`
+	ExpressionWidth        *int                          `json:"expressionWidth,omitempty"`
+	FunctionCase           *CaseOption                   `json:"functionCase,omitempty"`
+	IdentifierCase         *CaseOption                   `json:"identifierCase,omitempty"`
+	IndentStyle            *IndentStyleOption            `json:"indentStyle,omitempty"`
+	KeywordCase            *CaseOption                   `json:"keywordCase,omitempty"`
+	Language               *LanguageOption               `json:"language,omitempty"`
+	LinesBetweenQueries    *int                          `json:"linesBetweenQueries,omitempty"`
+	LogicalOperatorNewline *LogicalOperatorNewlineOption `json:"logicalOperatorNewline,omitempty"`
+	NewlineBeforeSemicolon *bool                         `json:"newlineBeforeSemicolon,omitempty"`
+	TabWidth               *int                          `json:"tabWidth,omitempty"`
+	UseTabs                *bool                         `json:"useTabs,omitempty"`
+
+	Catalog                  Catalog                   `json:"-"`
+	QualifyTables            *bool                     `json:"qualifyTables,omitempty"`
+	ColumnCatalog            ColumnCatalog             `json:"-"`
+	ExpandStar               *bool                     `json:"expandStar,omitempty"`
+	VerifyTokens             *bool                     `json:"verifyTokens,omitempty"`
+	VerifyIdempotent         *bool                     `json:"verifyIdempotent,omitempty"`
+	MaxConsecutiveBlankLines *int                      `json:"maxConsecutiveBlankLines,omitempty"`
+	PreserveLineBreaks       *bool                     `json:"preserveLineBreaks,omitempty"`
+	AlignOperators           *bool                     `json:"alignOperators,omitempty"`
+	AlignAliases             *bool                     `json:"alignAliases,omitempty"`
+	DenseOperatorExceptions  *string                   `json:"denseOperatorExceptions,omitempty"`
+	OrderByDependencies      *bool                     `json:"orderByDependencies,omitempty"`
+	SortSchema               *bool                     `json:"sortSchema,omitempty"`
+	DumpFormat               *DumpFormatOption         `json:"dumpFormat,omitempty"`
+	DumpInserts              *DumpInsertsOption        `json:"dumpInserts,omitempty"`
+	NumberCase               *CaseOption               `json:"numberCase,omitempty"`
+	LeadingZero              *LeadingZeroOption        `json:"leadingZero,omitempty"`
+	GroupDigits              *bool                     `json:"groupDigits,omitempty"`
+	PredicateChainWidth      *int                      `json:"predicateChainWidth,omitempty"`
+	GeneratedMarker          *string                   `json:"generatedMarker,omitempty"`
+	ExecutableComments       *ExecutableCommentsOption `json:"executableComments,omitempty"`
+	SQLXBinds                *bool                     `json:"sqlxBinds,omitempty"`
+	MapLines                 *bool                     `json:"mapLines,omitempty"`
+}
+
+// Merge returns base with the fields set in override replaced by their values. Overrides can be
+// stacked, for example a project configuration over DefaultFormatOptions and command-line flags
+// over both:
+//
+//	options := sqlfmt.Merge(sqlfmt.Merge(sqlfmt.DefaultFormatOptions, project), flags)
+func Merge(base FormatOptions, override PartialFormatOptions) FormatOptions {
+	overrideField(&base.DataTypeCase, override.DataTypeCase)
+	overrideField(&base.DenseOperators, override.DenseOperators)
+	overrideField(&base.ExpressionWidth, override.ExpressionWidth)
+	overrideField(&base.FunctionCase, override.FunctionCase)
+	overrideField(&base.IdentifierCase, override.IdentifierCase)
+	overrideField(&base.IndentStyle, override.IndentStyle)
+	overrideField(&base.KeywordCase, override.KeywordCase)
+	overrideField(&base.Language, override.Language)
+	overrideField(&base.LinesBetweenQueries, override.LinesBetweenQueries)
+	overrideField(&base.LogicalOperatorNewline, override.LogicalOperatorNewline)
+	overrideField(&base.NewlineBeforeSemicolon, override.NewlineBeforeSemicolon)
+	overrideField(&base.TabWidth, override.TabWidth)
+	overrideField(&base.UseTabs, override.UseTabs)
+	if override.Catalog != nil {
+		base.Catalog = override.Catalog
+	}
+	overrideField(&base.QualifyTables, override.QualifyTables)
+	if override.ColumnCatalog != nil {
+		base.ColumnCatalog = override.ColumnCatalog
+	}
+	overrideField(&base.ExpandStar, override.ExpandStar)
+	overrideField(&base.VerifyTokens, override.VerifyTokens)
+	overrideField(&base.VerifyIdempotent, override.VerifyIdempotent)
+	overrideField(&base.MaxConsecutiveBlankLines, override.MaxConsecutiveBlankLines)
+	overrideField(&base.PreserveLineBreaks, override.PreserveLineBreaks)
+	overrideField(&base.AlignOperators, override.AlignOperators)
+	overrideField(&base.AlignAliases, override.AlignAliases)
+	overrideField(&base.DenseOperatorExceptions, override.DenseOperatorExceptions)
+	overrideField(&base.OrderByDependencies, override.OrderByDependencies)
+	overrideField(&base.SortSchema, override.SortSchema)
+	overrideField(&base.DumpFormat, override.DumpFormat)
+	overrideField(&base.DumpInserts, override.DumpInserts)
+	overrideField(&base.NumberCase, override.NumberCase)
+	overrideField(&base.LeadingZero, override.LeadingZero)
+	overrideField(&base.GroupDigits, override.GroupDigits)
+	overrideField(&base.PredicateChainWidth, override.PredicateChainWidth)
+	overrideField(&base.GeneratedMarker, override.GeneratedMarker)
+	overrideField(&base.ExecutableComments, override.ExecutableComments)
+	overrideField(&base.SQLXBinds, override.SQLXBinds)
+	overrideField(&base.MapLines, override.MapLines)
+	if lines := override.LinesBetweenQueries; lines != nil && *lines <= 0 {
+		base.LinesBetweenQueries = NoLinesBetweenQueries
+	}
+	return base
+}
+
+// overrideField sets *field to *value if value is set.
+func overrideField[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
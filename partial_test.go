@@ -0,0 +1,65 @@
+package sqlfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+// nonZero returns a value of type typ other than its zero value.
+func nonZero(t *testing.T, typ reflect.Type) reflect.Value {
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int:
+		v.SetInt(7)
+	default:
+		t.Fatalf("no value for %s", typ)
+	}
+	return v
+}
+
+// Merge and the cache key list the fields of the options by hand; these tests notice a field
+// added without them.
+
+func TestMergeSetsEveryField(t *testing.T) {
+	typ := reflect.TypeOf(PartialFormatOptions{})
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.Type.Kind() == reflect.Interface {
+			continue // Catalog and ColumnCatalog, which have no implementation here
+		}
+		var override PartialFormatOptions
+		value := reflect.ValueOf(&override).Elem().Field(i)
+		if field.Type.Kind() == reflect.Pointer {
+			value.Set(reflect.New(field.Type.Elem()))
+			value.Elem().Set(nonZero(t, field.Type.Elem()))
+		} else {
+			value.Set(nonZero(t, field.Type))
+		}
+
+		got := reflect.ValueOf(Merge(FormatOptions{}, override)).FieldByName(field.Name)
+		if got.IsZero() {
+			t.Errorf("Merge does not set %s", field.Name)
+		}
+	}
+}
+
+func TestCacheKeyCoversEveryOption(t *testing.T) {
+	c := newResultCache(1, nil)
+	base, _ := c.key("select 1", FormatOptions{})
+	typ := reflect.TypeOf(FormatOptions{})
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.Name == "Catalog" || field.Name == "ColumnCatalog" {
+			continue // not cached
+		}
+		var options FormatOptions
+		reflect.ValueOf(&options).Elem().Field(i).Set(nonZero(t, field.Type))
+		if key, _ := c.key("select 1", options); key == base {
+			t.Errorf("the cache key does not depend on %s", field.Name)
+		}
+	}
+}
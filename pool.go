@@ -0,0 +1,318 @@
+package sqlfmt
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pool hands out engines to concurrent callers. Engines are created on demand, up to size, and
+// reused afterwards; callers block while all of them are in use, or while fewer than queue callers
+// are waiting already. With an idleTTL, engines left idle for that long are closed, down to min,
+// so that the pool follows the load. A pool is shared by a formatter and its clones, and closed
+// when the last of them releases it.
+type pool struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	idle    []idleEngine  // most recently returned last
+	engines []*engine     // all engines of the pool, for stats
+	size    int           // maximum number of engines
+	min     int           // number of engines kept however long they are idle
+	queue   int           // number of waiting callers from which another engine is created
+	idleTTL time.Duration // time after which idle engines are closed, or 0 to keep them
+	created int           // number of engines of the pool
+	waiting int           // number of callers waiting in get
+	refs    int           // number of formatters using the pool
+	config  engineConfig  // settings of the engines created by the pool
+	closed  bool
+	done    chan struct{} // closed with the pool, to stop reap
+
+	keywordsMu sync.Mutex
+	keywords   map[LanguageOption]map[string]bool // dialect keyword sets, see dialectKeywords
+}
+
+// idleEngine is an engine waiting in the pool, with the time it was returned.
+type idleEngine struct {
+	e     *engine
+	since time.Time
+}
+
+// newPool returns a pool sized as set by the pool options of fc, of engines created with config,
+// holding first, which is created eagerly so that a broken setup is reported by NewFormatter
+// rather than by the first Format call.
+func newPool(fc formatterConfig, config engineConfig, first *engine) *pool {
+	size := fc.poolSize
+	if size < 1 {
+		size = defaultPoolSize()
+	}
+	p := &pool{
+		idle:    []idleEngine{{first, time.Now()}},
+		engines: []*engine{first},
+		size:    size,
+		min:     min(max(fc.minPoolSize, 0), size),
+		queue:   max(fc.scaleUpQueue, 0),
+		idleTTL: fc.idleTimeout,
+		created: 1,
+		refs:    1,
+		config:  config,
+	}
+	p.cond.L = &p.mu
+	first.guard.owner = p
+	if p.idleTTL > 0 {
+		p.done = make(chan struct{})
+		go p.reap()
+	}
+	return p
+}
+
+// defaultPoolSize is the pool size used unless WithPoolSize says otherwise.
+func defaultPoolSize() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// get returns an idle engine, creating one if none is idle and the pool is not full, or waits for
+// one to be returned with put.
+func (p *pool) get() (*engine, error) {
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrFormatterClosed
+		}
+		if n := len(p.idle); n > 0 {
+			e := p.idle[n-1].e
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+			if err := p.checkOut(e); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+		// All engines are in use: add one unless the pool is full, or it is enough to wait for
+		// one to be returned. The pool needs at least one engine for that.
+		if p.created < p.size && (p.created < max(p.min, 1) || p.waiting >= p.queue) {
+			break
+		}
+		p.waiting++
+		p.cond.Wait()
+		p.waiting--
+	}
+	p.created++
+	p.mu.Unlock()
+
+	e, err := newEngine(p.config)
+	if err != nil {
+		p.mu.Lock()
+		p.created--
+		p.mu.Unlock()
+		p.cond.Signal()
+		return nil, err
+	}
+	p.adopt(e)
+	return e, nil
+}
+
+// prewarm creates engines until the pool holds n of them (or is full) and warms them up, together
+// with the engines that are idle.
+func (p *pool) prewarm(n int) error {
+	p.mu.Lock()
+	n = min(n, p.size)
+	p.mu.Unlock()
+	if err := p.warmup(); err != nil {
+		return err
+	}
+	for {
+		p.mu.Lock()
+		if p.closed || p.created >= n {
+			p.mu.Unlock()
+			return nil
+		}
+		p.created++
+		p.mu.Unlock()
+
+		e, err := newEngine(p.config)
+		if err == nil {
+			err = e.warm()
+		}
+		if err != nil {
+			p.mu.Lock()
+			p.created--
+			p.mu.Unlock()
+			return err
+		}
+		p.adopt(e)
+		p.put(e)
+	}
+}
+
+// warmup warms up the engines that are currently idle.
+func (p *pool) warmup() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrFormatterClosed
+	}
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, ie := range idle {
+		if checkErr := p.checkOut(ie.e); checkErr != nil {
+			err = cmp.Or(err, checkErr)
+			continue
+		}
+		if err == nil {
+			err = ie.e.warm()
+		}
+		p.put(ie.e)
+	}
+	return err
+}
+
+// stats measures the idle engines and returns the last measurement of every engine, in order of
+// creation. Engines in use keep their previous measurement.
+func (p *pool) stats() ([]ContextStats, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrFormatterClosed
+	}
+	idle := p.idle
+	p.idle = nil
+	engines := append([]*engine(nil), p.engines...)
+	p.mu.Unlock()
+
+	var err error
+	for _, ie := range idle {
+		if checkErr := p.checkOut(ie.e); checkErr != nil {
+			err = cmp.Or(err, checkErr)
+			continue
+		}
+		ie.e.measure()
+		p.putIdle(ie.e, ie.since) // measuring does not count as use
+	}
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]ContextStats, len(engines))
+	for i, e := range engines {
+		if s := e.stats.Load(); s != nil {
+			stats[i] = *s
+		}
+	}
+	return stats, nil
+}
+
+// put returns an engine obtained from get to the pool. A broken engine is closed instead, making
+// room for a new one.
+func (p *pool) put(e *engine) {
+	p.putIdle(e, time.Now())
+}
+
+// putIdle is put for an engine idle since the given time.
+func (p *pool) putIdle(e *engine, since time.Time) {
+	p.checkIn(e)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		e.close()
+		return
+	}
+	if e.broken.Load() {
+		e.close()
+		p.engines = slices.DeleteFunc(p.engines, func(x *engine) bool { return x == e })
+		p.created--
+		p.cond.Signal()
+		return
+	}
+	p.idle = append(p.idle, idleEngine{e, since})
+	p.cond.Signal()
+}
+
+// reap closes the engines idle for longer than idleTTL, checking every half idleTTL until the pool
+// is closed.
+func (p *pool) reap() {
+	ticker := time.NewTicker(max(p.idleTTL/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.shrink(now)
+		}
+	}
+}
+
+// shrink closes the engines idle since before now minus idleTTL, least recently used first, as
+// long as the pool keeps min engines.
+func (p *pool) shrink(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.idle = slices.DeleteFunc(p.idle, func(ie idleEngine) bool {
+		if p.created <= p.min || now.Sub(ie.since) < p.idleTTL {
+			return false
+		}
+		ie.e.close()
+		p.engines = slices.DeleteFunc(p.engines, func(x *engine) bool { return x == ie.e })
+		p.created--
+		return true
+	})
+}
+
+// adopt records e, an engine created by p, as one of its engines, in use by the caller.
+func (p *pool) adopt(e *engine) {
+	e.guard.owner = p
+	e.guard.inUse.Store(true)
+	p.mu.Lock()
+	p.engines = append(p.engines, e)
+	p.mu.Unlock()
+}
+
+// acquire adds a reference to the pool. It returns false if the pool is already closed.
+func (p *pool) acquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.refs++
+	return true
+}
+
+// release drops a reference to the pool and closes it when none is left.
+func (p *pool) release() {
+	p.mu.Lock()
+	p.refs--
+	last := p.refs == 0
+	p.mu.Unlock()
+	if last {
+		p.close()
+	}
+}
+
+// close marks the pool as closed and wakes up waiting callers, which then fail with
+// ErrFormatterClosed. Idle engines are closed now; engines in use are closed when they are returned.
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for _, ie := range p.idle {
+		ie.e.close()
+	}
+	p.idle = nil
+	p.engines = nil
+	if p.done != nil {
+		close(p.done)
+	}
+	p.cond.Broadcast()
+}
@@ -0,0 +1,84 @@
+package sqlfmt
+
+import "strings"
+
+// preserveLineBreaks lays out the tokens of output, which has the casing and spacing chosen by
+// sql-formatter, along the line breaks of input: tokens stay on the lines the author put them on,
+// and only indentation, spacing and casing change. A line starting with a token that sql-formatter
+// also put at the start of a line gets that line's indentation; other lines are indented one level
+// deeper than the line sql-formatter put the token on. Up to maxBlank blank lines are kept within
+// statements; statements are separated as sql-formatter separates them. Comments are copied from
+// the input unchanged.
+//
+// Tokens are matched by position, so output is returned unchanged if sql-formatter did not produce
+// the same number of tokens as the input.
+func preserveLineBreaks(input, output string, options FormatOptions, maxBlank int) string {
+	in := nonSpaceTokens(input, options.Language)
+	out := nonSpaceTokens(output, options.Language)
+	if len(in) != len(out) {
+		return output
+	}
+
+	unit := strings.Repeat(" ", indentWidth(options))
+	if options.UseTabs {
+		unit = "\t"
+	}
+
+	var b strings.Builder
+	for i, t := range out {
+		text := t.text
+		if t.kind == tokenComment {
+			text = in[i].text
+		}
+		if i == 0 {
+			b.WriteString(text)
+			continue
+		}
+
+		inGap := input[in[i-1].end:in[i].start]
+		outGap := output[out[i-1].end:t.start]
+		breaks := strings.Count(inGap, "\n")
+		switch {
+		case breaks > 0 && out[i-1].text == ";":
+			// Separate statements as configured by LinesBetweenQueries.
+			b.WriteString(outGap)
+		case breaks > 0:
+			b.WriteString(strings.Repeat("\n", min(breaks, maxBlank+1)))
+			// Comments are indented like the token they precede.
+			j := i
+			for j+1 < len(out) && out[j].kind == tokenComment {
+				j++
+			}
+			indent := lineIndent(output, out[j].start)
+			if !strings.Contains(output[out[j-1].end:out[j].start], "\n") {
+				indent += unit
+			}
+			b.WriteString(indent)
+		case !strings.Contains(outGap, "\n"):
+			b.WriteString(outGap)
+		case out[i-1].text == "(" || out[i-1].text == "." || t.text == ")" || t.text == "," || t.text == "." || t.text == ";":
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// lineIndent returns the leading whitespace of the line of s containing offset.
+func lineIndent(s string, offset int) string {
+	start := strings.LastIndexByte(s[:offset], '\n') + 1
+	end := start
+	for end < len(s) && (s[end] == ' ' || s[end] == '\t') {
+		end++
+	}
+	return s[start:end]
+}
+
+// indentWidth returns the number of spaces per indentation level, which sql-formatter defaults to 2.
+func indentWidth(options FormatOptions) int {
+	if options.TabWidth > 0 {
+		return options.TabWidth
+	}
+	return 2
+}
@@ -0,0 +1,235 @@
+package sqlfmt
+
+import "strings"
+
+// nameRole classifies what an identifier in a statement refers to.
+type nameRole int
+
+const (
+	// roleColumn is a column reference, possibly qualified.
+	roleColumn nameRole = iota
+	// roleTable is a table (or view) reference, or the qualifier of a column.
+	roleTable
+	// roleSchema is a schema (or catalog) qualifying a table.
+	roleSchema
+	// roleTableAlias is an alias introduced for a table reference.
+	roleTableAlias
+	// roleColumnAlias is an alias introduced for a select expression.
+	roleColumnAlias
+	// roleFunction is the name of a called function.
+	roleFunction
+	// roleCTE is the name of a common table expression at its definition.
+	roleCTE
+)
+
+// nameRef is an identifier found by scanNames.
+type nameRef struct {
+	index     int      // index of the token in the significant token slice
+	role      nameRole // what the identifier refers to
+	name      string   // the identifier with quotes removed
+	qualified bool     // whether the name is preceded by a qualifier (schema.table, table.column)
+}
+
+// tableKeywords are keywords directly followed by a table reference.
+var tableKeywords = wordSet(`FROM JOIN UPDATE INTO TABLE VIEW TRUNCATE REFERENCES EXISTS USING`)
+
+// clauseKeywords are keywords that start a clause; they are used to find which clause a token belongs to.
+var clauseKeywords = wordSet(`SELECT FROM WHERE GROUP ORDER HAVING LIMIT OFFSET SET VALUES ON USING JOIN RETURNING
+	WINDOW UNION INTERSECT EXCEPT INTO UPDATE DELETE INSERT WITH QUALIFY`)
+
+// scanNames classifies the identifiers among the significant tokens sig.
+// The classification is heuristic: it relies on the surrounding keywords rather than a full parse,
+// which is good enough for the rewrites and analyses built on top of it.
+func scanNames(sig []token) []nameRef {
+	var refs []nameRef
+	for i := 0; i < len(sig); i++ {
+		if !isName(sig[i]) || (i > 0 && sig[i-1].text == ".") {
+			continue
+		}
+
+		// Collect the dotted chain a.b.c starting at i.
+		chain := []int{i}
+		star := false
+		for j := i + 1; j+1 < len(sig) && sig[j].text == "."; j += 2 {
+			if sig[j+1].text == "*" {
+				star = true
+				break
+			}
+			if !isName(sig[j+1]) && sig[j+1].kind != tokenWord {
+				break
+			}
+			chain = append(chain, j+1)
+		}
+		end := chain[len(chain)-1]
+		if star {
+			end += 2
+		}
+		prev, next := tokenAt(sig, i-1), tokenAt(sig, end+1)
+
+		switch {
+		case !star && isTablePosition(sig, i):
+			if next.text == "(" && len(chain) == 1 && !strings.EqualFold(prev.text, "INTO") &&
+				!strings.EqualFold(prev.text, "TABLE") && !strings.EqualFold(prev.text, "REFERENCES") {
+				refs = append(refs, nameRef{index: i, role: roleFunction, name: unquoteName(sig[i])})
+				break
+			}
+			for k, idx := range chain[:len(chain)-1] {
+				refs = append(refs, nameRef{index: idx, role: roleSchema, name: unquoteName(sig[idx]), qualified: k > 0})
+			}
+			refs = append(refs, nameRef{index: end, role: roleTable, name: unquoteName(sig[end]), qualified: len(chain) > 1})
+			// An optional alias follows the table reference.
+			a := end + 1
+			if strings.EqualFold(tokenAt(sig, a).text, "AS") {
+				a++
+			}
+			if isName(tokenAt(sig, a)) && tokenAt(sig, a+1).text != "." {
+				refs = append(refs, nameRef{index: a, role: roleTableAlias, name: unquoteName(sig[a])})
+				end = a
+			}
+		case len(chain) == 1 && isCTEName(sig, i):
+			refs = append(refs, nameRef{index: i, role: roleCTE, name: unquoteName(sig[i])})
+		case !star && next.text == "(":
+			for k, idx := range chain[:len(chain)-1] {
+				refs = append(refs, nameRef{index: idx, role: roleSchema, name: unquoteName(sig[idx]), qualified: k > 0})
+			}
+			refs = append(refs, nameRef{index: end, role: roleFunction, name: unquoteName(sig[end]), qualified: len(chain) > 1})
+		case !star && len(chain) == 1 && isColumnAlias(sig, i):
+			refs = append(refs, nameRef{index: i, role: roleColumnAlias, name: unquoteName(sig[i])})
+		default:
+			columns := chain
+			if !star {
+				columns = chain[:len(chain)-1]
+			}
+			for k, idx := range columns {
+				role := roleSchema
+				if k == len(columns)-1 {
+					role = roleTable
+				}
+				refs = append(refs, nameRef{index: idx, role: role, name: unquoteName(sig[idx]), qualified: k > 0})
+			}
+			if !star {
+				refs = append(refs, nameRef{index: end, role: roleColumn, name: unquoteName(sig[end]), qualified: len(chain) > 1})
+			}
+		}
+		i = end
+	}
+	return refs
+}
+
+// isName reports whether t can be an identifier: a quoted identifier or a bare word that is not a keyword.
+func isName(t token) bool {
+	return t.kind == tokenIdent || (t.kind == tokenWord && !isKeyword(t.text))
+}
+
+// unquoteName returns the identifier denoted by t with any quoting removed.
+func unquoteName(t token) string {
+	if t.kind != tokenIdent || len(t.text) < 2 {
+		return t.text
+	}
+	open, body := t.text[0], t.text[1:len(t.text)-1]
+	closer := string(open)
+	if open == '[' {
+		closer = "]"
+	}
+	return strings.ReplaceAll(body, closer+closer, closer)
+}
+
+// tokenAt returns sig[i], or the zero token if i is out of range.
+func tokenAt(sig []token, i int) token {
+	if i < 0 || i >= len(sig) {
+		return token{}
+	}
+	return sig[i]
+}
+
+// isTablePosition reports whether the name starting at sig[i] is where a table reference is expected.
+func isTablePosition(sig []token, i int) bool {
+	prev := tokenAt(sig, i-1)
+	if prev.kind == tokenWord && tableKeywords[strings.ToUpper(prev.text)] {
+		return true
+	}
+	if prev.text != "," {
+		return false
+	}
+	// A comma after a join condition continues the FROM list.
+	kw := enclosingClause(sig, i)
+	return kw == "FROM" || kw == "UPDATE" || kw == "ON"
+}
+
+// enclosingClause returns the upper-cased clause keyword that sig[i] belongs to at its nesting level,
+// or "" if there is none.
+func enclosingClause(sig []token, i int) string {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		t := sig[j]
+		switch {
+		case t.text == ")":
+			depth++
+		case t.text == "(":
+			if depth == 0 {
+				return ""
+			}
+			depth--
+		case t.text == ";":
+			return ""
+		case depth == 0 && t.kind == tokenWord && clauseKeywords[strings.ToUpper(t.text)]:
+			kw := strings.ToUpper(t.text)
+			if kw == "JOIN" {
+				return "FROM"
+			}
+			return kw
+		}
+	}
+	return ""
+}
+
+// isCTEName reports whether sig[i] names a common table expression at its definition (WITH x AS (...)).
+func isCTEName(sig []token, i int) bool {
+	next := tokenAt(sig, i+1)
+	if next.text == "(" {
+		// WITH x (a, b) AS (...)
+		depth := 0
+		j := i + 1
+		for ; j < len(sig); j++ {
+			if sig[j].text == "(" {
+				depth++
+			} else if sig[j].text == ")" {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		next = tokenAt(sig, j+1)
+	}
+	if !strings.EqualFold(next.text, "AS") {
+		return false
+	}
+	prev := strings.ToUpper(tokenAt(sig, i-1).text)
+	return prev == "WITH" || prev == "RECURSIVE" || (prev == "," && enclosingClause(sig, i) == "WITH")
+}
+
+// isColumnAlias reports whether sig[i] is an alias for a select expression, either after AS
+// or directly following the expression.
+func isColumnAlias(sig []token, i int) bool {
+	prev := tokenAt(sig, i-1)
+	if prev.kind == tokenWord && strings.EqualFold(prev.text, "AS") {
+		return true
+	}
+	if enclosingClause(sig, i) != "SELECT" {
+		return false
+	}
+	next := tokenAt(sig, i+1)
+	if next.text != "," && !(next.kind == tokenWord && isKeyword(next.text)) && next.text != "" && next.text != ";" && next.text != ")" {
+		return false
+	}
+	switch prev.kind {
+	case tokenIdent, tokenString, tokenNumber:
+		return true
+	case tokenWord:
+		return !isKeyword(prev.text) || strings.EqualFold(prev.text, "END")
+	case tokenPunct:
+		return prev.text == ")"
+	}
+	return false
+}
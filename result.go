@@ -0,0 +1,49 @@
+package sqlfmt
+
+import "time"
+
+// FormatResult is the outcome of FormatWithResult: the formatted SQL and metadata about the call,
+// for services that log or monitor formatting.
+type FormatResult struct {
+	// Output is the formatted SQL, as returned by Format.
+	Output string
+	// InputBytes and OutputBytes are the sizes of the input and of Output in bytes.
+	InputBytes, OutputBytes int
+	// Statements is the number of statements in the input.
+	Statements int
+	// Duration is the time the call took, including waiting for a free JavaScript context.
+	Duration time.Duration
+	// CacheHit reports whether Output was served from the cache set with WithResultCache rather
+	// than computed.
+	CacheHit bool
+	// Warnings lists the problems that did not prevent formatting, as reported by FormatWithWarnings.
+	Warnings []Warning
+	// LineSources holds, with FormatOptions.MapLines, the source in the input of each line of
+	// Output, so that tools reporting on the input can locate what they find in the output.
+	LineSources []LineSource
+}
+
+// FormatWithResult formats sql like FormatWithWarnings and returns the output together with its
+// metadata. On error, the result still carries the input size, statement count and duration.
+func (f *Formatter) FormatWithResult(sql string, options FormatOptions) (FormatResult, error) {
+	start := time.Now()
+	formatted, warnings, hit, err := f.formatWithWarnings(sql, options)
+	result := FormatResult{
+		Output:      formatted,
+		InputBytes:  len(sql),
+		OutputBytes: len(formatted),
+		Statements:  countStatements(sql, options.Language),
+		Duration:    time.Since(start),
+		CacheHit:    hit,
+		Warnings:    warnings,
+	}
+	if err == nil && options.MapLines {
+		result.LineSources = mapLines(sql, formatted, options.Language)
+	}
+	return result, err
+}
+
+// countStatements returns the number of non-empty statements in sql.
+func countStatements(sql string, lang LanguageOption) int {
+	return len(splitStatements(sql, lang))
+}
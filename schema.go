@@ -0,0 +1,147 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// optionDescriptions describes the options for ConfigSchema, by JSON name.
+var optionDescriptions = map[string]string{
+	"dataTypeCase":             "Case of data types (e.g., INT, VARCHAR).",
+	"denseOperators":           "Whether to pack operators densely without spaces.",
+	"expressionWidth":          "Maximum length of parenthesized expressions.",
+	"functionCase":             "Case of function names (e.g., COUNT, SUM).",
+	"identifierCase":           "Case of identifiers (e.g., column names, table names).",
+	"indentStyle":              "Indentation style.",
+	"keywordCase":              "Case of reserved keywords (e.g., SELECT, FROM).",
+	"language":                 "SQL dialect.",
+	"linesBetweenQueries":      "Number of empty lines between SQL statements.",
+	"logicalOperatorNewline":   "Newline placement for logical operators (AND, OR, XOR).",
+	"newlineBeforeSemicolon":   "Whether to place the query separator (;) on a separate line.",
+	"tabWidth":                 "Number of spaces used for indentation (ignored if useTabs is true).",
+	"useTabs":                  "Whether to use TAB characters for indentation instead of spaces.",
+	"qualifyTables":            "Whether to prefix unqualified table references with their schema from the catalog.",
+	"expandStar":               "Whether to replace SELECT * with explicit column lists from the catalog.",
+	"verifyIdempotent":         "Whether to format the output again and fail unless that leaves it unchanged.",
+	"verifyTokens":             "Whether to verify that the output has the same tokens as the input.",
+	"maxConsecutiveBlankLines": "Maximum number of consecutive blank lines kept from the input inside statements.",
+	"preserveLineBreaks":       "Whether to keep the line breaks of the input.",
+	"alignOperators":           "Whether to align the operators of consecutive predicates and assignments.",
+	"alignAliases":             "Whether to align the aliases of single-line items across each SELECT list.",
+	"denseOperatorExceptions":  "Comma-separated operators or groups of operators (comparison, arithmetic, cast, concatenation, json, bitwise) that keep their spaces when denseOperators is set.",
+	"orderByDependencies":      "Whether to reorder schema statements so that objects come after their dependencies.",
+	"dumpFormat":               "Dump tool that produced the input, whose non-SQL parts are passed through unformatted.",
+	"dumpInserts":              "How the INSERT statements of a dump are handled: formatted, passed through verbatim or one row per line.",
+	"numberCase":               "Case of the exponent markers and hexadecimal digits of numeric literals.",
+	"leadingZero":              "Whether decimals below one are written with a zero before the decimal point (0.5) or without (.5).",
+	"groupDigits":              "Whether to separate the digits of long integers in groups of three with underscores, in dialects that accept them.",
+	"predicateChainWidth":      "Maximum width of the AND/OR chains of WHERE, HAVING and ON clauses kept on one line (0 always breaks them).",
+	"generatedMarker":          "Text starting a comment at the top of a file that marks it as generated, so that the sqlfmt command leaves it alone (default sqlfmt:generated).",
+	"executableComments":       "How MySQL executable comments (/*!50100 ... */) are handled: their SQL formatted or passed through verbatim.",
+	"sqlxBinds":                "Keep the binds of sqlx, :name for sqlx.Named and ? for sqlx.In, as written and valid in every dialect.",
+	"mapLines":                 "Map each output line to the input bytes it was formatted from, in the result of FormatWithResult.",
+	"sortSchema":               "Whether to also sort independent schema statements of the same kind by object name.",
+}
+
+// ConfigSchema returns a JSON Schema (draft 2020-12) describing configuration files, for editors
+// to complete and validate .sqlfmt.json. Like ParseConfigStrict, it rejects unknown keys. The lint
+// rules listed are those registered when it is called.
+func ConfigSchema() []byte {
+	options := map[string]any{}
+	defaults := reflect.ValueOf(DefaultFormatOptions)
+	t := defaults.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		property := map[string]any{
+			"description": optionDescriptions[name],
+			"default":     defaults.Field(i).Interface(),
+		}
+		switch t.Field(i).Type.Kind() {
+		case reflect.Bool:
+			property["type"] = "boolean"
+		case reflect.Int:
+			property["type"] = "integer"
+			property["minimum"] = 0
+		case reflect.String:
+			property["type"] = "string"
+		}
+		if values, ok := optionValues[name]; ok {
+			property["enum"] = values
+		}
+		options[name] = property
+	}
+
+	severity := map[string]any{
+		"oneOf": []any{
+			map[string]any{"enum": []Severity{SeverityOff, SeverityWarn, SeverityError}},
+			map[string]any{"type": "boolean", "description": "false is off, true is error."},
+		},
+	}
+	rules := map[string]any{
+		FormatRule: withDescription(severity, "Reports files that are not formatted (check only)."),
+	}
+	for _, r := range Rules() {
+		rules[r.Name] = withDescription(severity, r.Description)
+	}
+	options["lint"] = map[string]any{
+		"description": "Lint settings.",
+		"type":        "object",
+		"properties": map[string]any{
+			"rules": map[string]any{
+				"description":          "Severity of lint rules by name.",
+				"type":                 "object",
+				"properties":           rules,
+				"additionalProperties": false,
+			},
+		},
+		"additionalProperties": false,
+	}
+
+	options["embedded"] = map[string]any{
+		"description": "Patterns locating SQL in files of other languages, for the format and check commands.",
+		"type":        "array",
+		"items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"files": map[string]any{"description": "Pattern matching the base names of the files, such as *.py.", "type": "string"},
+				"start": map[string]any{"description": "Regular expression matching the text before the SQL.", "type": "string"},
+				"end":   map[string]any{"description": "Regular expression matching the text after the SQL.", "type": "string"},
+			},
+			"required":             []string{"files", "start", "end"},
+			"additionalProperties": false,
+		},
+	}
+
+	options["behaviorFingerprint"] = map[string]any{
+		"description": "Formatting behavior the check command requires, as printed by sqlfmt doctor; check fails when an upgrade changes it.",
+		"type":        "string",
+	}
+
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "sqlfmt configuration",
+		"type":                 "object",
+		"properties":           options,
+		"additionalProperties": false,
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err) // the schema only contains marshalable values
+	}
+	return append(data, '\n')
+}
+
+// withDescription returns a copy of schema with a description.
+func withDescription(schema map[string]any, description string) map[string]any {
+	out := map[string]any{"description": description}
+	for k, v := range schema {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+//go:build !tinygo && !sqlfmt_native
+
+package sqlfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// setupCode defines the functions called by the package on top of the sql-formatter bundle.
+const setupCode = `
+	function formatSql(sql, optionsJson) {
+		const options = JSON.parse(optionsJson);
+		return sqlFormatter.format(sql, options);
+	}
+
+	function dialectKeywords(language) {
+		const dialect = sqlFormatter[language] || sqlFormatter.sql;
+		const o = dialect.tokenizerOptions;
+		const lists = [o.reservedSelect, o.reservedClauses, o.reservedSetOperations, o.reservedJoins,
+			o.reservedPhrases || []];
+		const words = {};
+		for (const list of lists) {
+			for (const phrase of sqlFormatter.expandPhrases(list)) {
+				for (const word of phrase.split(/\s+/)) {
+					words[word] = true;
+				}
+			}
+		}
+		// Keywords are single words, some of which, such as END-EXEC, are not valid phrases.
+		for (const word of o.reservedKeywords || []) {
+			words[word] = true;
+		}
+		return Object.keys(words).join(" ");
+	}
+
+	function dialectInfo(language) {
+		const o = sqlFormatter[language].tokenizerOptions;
+		return JSON.stringify({
+			keywords: dialectKeywords(language).split(" "),
+			dataTypes: o.reservedDataTypes || [],
+			functions: o.reservedFunctionNames || [],
+			stringTypes: o.stringTypes || [],
+			identTypes: o.identTypes || [],
+			paramTypes: o.paramTypes || {},
+			lineCommentTypes: o.lineCommentTypes || ["--"],
+		});
+	}
+`
+
+// formatSQL formats sql with sql-formatter.
+func (e *engine) formatSQL(sql string, options FormatOptions) (string, error) {
+	optionsJSON, err := json.Marshal(struct {
+		FormatOptions
+		LinesBetweenQueries int `json:"linesBetweenQueries"` // sent even when 0
+	}{options, options.BlankLinesBetweenQueries()})
+	if err != nil {
+		return "", fmt.Errorf("marshaling options: %w", err)
+	}
+	res, err := e.call("formatSql", sql, string(optionsJSON))
+	if err != nil {
+		return "", err
+	}
+	formatted, ok := res.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected result type: %T", res)
+	}
+	return formatted, nil
+}
+
+// keywordList returns the reserved words of sql-formatter's language, separated by spaces.
+func (e *engine) keywordList(language LanguageOption) (string, error) {
+	res, err := e.call("dialectKeywords", string(language))
+	if err != nil {
+		return "", err
+	}
+	words, _ := res.(string)
+	return words, nil
+}
+
+// bundleDialect is the description of a dialect returned by the dialectInfo function of setupCode,
+// taken from the tokenizer options of sql-formatter.
+type bundleDialect struct {
+	Keywords, DataTypes, Functions []string
+	StringTypes, IdentTypes        []quoteType
+	ParamTypes                     struct {
+		Positional              bool
+		Numbered, Named, Quoted []string
+	}
+	LineCommentTypes []string
+}
+
+// quoteType is a kind of quoted token of sql-formatter: the quote, such as `""-qq` for a token in
+// double quotes where doubled quotes escape, and its prefixes, such as "U&".
+// sql-formatter writes the quote alone as a string.
+type quoteType struct {
+	Quote         string
+	Prefixes      []string
+	RequirePrefix bool
+}
+
+// UnmarshalJSON decodes a quote type written as a string or an object.
+func (q *quoteType) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &q.Quote); err == nil {
+		return nil
+	}
+	type plain quoteType
+	return json.Unmarshal(data, (*plain)(q))
+}
+
+// forms returns examples of the token: the quotes without the escaping rules, with each prefix.
+func (q quoteType) forms() []string {
+	quote, _, _ := strings.Cut(q.Quote, "-")
+	if quote == "" {
+		return nil // a custom regular expression
+	}
+	quote = strings.ReplaceAll(quote, "..", "...")
+	var forms []string
+	if !q.RequirePrefix {
+		forms = append(forms, quote)
+	}
+	for _, p := range q.Prefixes {
+		forms = append(forms, p+quote)
+	}
+	return forms
+}
+
+// dialect describes the sql-formatter language.
+func (e *engine) dialect(language LanguageOption) (Dialect, error) {
+	res, err := e.call("dialectInfo", string(language))
+	if err != nil {
+		return Dialect{}, err
+	}
+	encoded, _ := res.(string)
+	var b bundleDialect
+	if err := json.Unmarshal([]byte(encoded), &b); err != nil {
+		return Dialect{}, fmt.Errorf("decoding dialect description: %w", err)
+	}
+
+	d := Dialect{Keywords: b.Keywords, DataTypes: b.DataTypes, Functions: b.Functions, LineComments: b.LineCommentTypes}
+	for _, t := range b.StringTypes {
+		d.StringQuotes = appendUnique(d.StringQuotes, t.forms()...)
+	}
+	for _, t := range b.IdentTypes {
+		d.IdentifierQuotes = appendUnique(d.IdentifierQuotes, t.forms()...)
+	}
+	if b.ParamTypes.Positional {
+		d.Placeholders = append(d.Placeholders, "?")
+	}
+	for _, p := range b.ParamTypes.Numbered {
+		d.Placeholders = appendUnique(d.Placeholders, p+"1")
+	}
+	for _, p := range b.ParamTypes.Named {
+		d.Placeholders = appendUnique(d.Placeholders, p+"name")
+	}
+	if quoted := b.ParamTypes.Quoted; len(quoted) > 0 {
+		quote := `""`
+		if len(d.IdentifierQuotes) > 0 {
+			quote = d.IdentifierQuotes[0]
+		}
+		for _, p := range quoted {
+			d.Placeholders = appendUnique(d.Placeholders, p+quote[:len(quote)/2]+"name"+quote[len(quote)/2:])
+		}
+	}
+	return d, nil
+}
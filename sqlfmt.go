@@ -1,35 +1,114 @@
 // Package sqlfmt provides SQL formatting functionality using the sql-formatter JavaScript library.
 // It supports multiple SQL dialects and offers multiple formatting options for consistent SQL code style.
+//
+// # Threads
+//
+// sql-formatter runs in QuickJS contexts, which are tied to the OS thread that creates them:
+// QuickJS checks for stack overflow against that thread's stack, so calling into a context from
+// another thread fails spuriously or corrupts memory. Each context is therefore owned by a
+// goroutine locked to its own OS thread with runtime.LockOSThread, and Format hands its work to
+// that goroutine over a channel. Callers can use a Formatter from any goroutine without pinning
+// anything themselves; a Formatter occupies one OS thread per context (see WithPoolSize) until
+// it is closed.
+//
+// # WebAssembly
+//
+// QuickJS needs cgo, which GOOS=js GOARCH=wasm does not support. Built for js/wasm, the package
+// calls sql-formatter through the JavaScript runtime hosting the module instead (a browser or
+// Node.js), evaluating the bundle in its global scope unless it already defines sqlFormatter.
+// The API is the same, but JavaScript runs on the host's single thread, so the contexts of the
+// pool do not format in parallel, WithMaxStackSize has no effect and ContextStats reports zeros.
+//
+// # Native backend
+//
+// Built with TinyGo, or with the sqlfmt_native build tag, the package formats SQL in Go instead
+// of sql-formatter, needing neither cgo, a JavaScript runtime nor the bundle, and does not encode
+// options to JSON on the formatting path. The native layout follows sql-formatter's for common
+// statements but is a subset of it: parenthesized expressions stay on one line, IndentStyle and
+// ExpressionWidth are ignored, and invalid SQL is formatted as well as possible rather than
+// rejected with a ParseError. The helpers that work on SQL text, such as Parameterize, are the
+// same with every backend.
+//
+// The native build imports neither encoding/json, reflect nor database/sql, so it leaves out what
+// needs them: configuration files (Config, LoadConfig and the JSON Schema), options from the
+// environment, Interpolate, and directive comments, which fail to format. Explain does not report
+// the effect of each option.
+//
+// # Debug builds
+//
+// Built with the sqlfmt_debug tag, the package checks how formatters are used and returns a
+// *MisuseError for a Formatter that is copied by value or not created with NewFormatter, used
+// after Close (with the stack of the Close call), or whose contexts are handed out twice. Test
+// suites can run with -tags sqlfmt_debug to catch such bugs where they happen.
 package sqlfmt
 
 import (
-	_ "embed"
-	"encoding/json"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"regexp"
+	"runtime"
+	"strings"
 	"sync"
-
-	"github.com/rosbit/go-quickjs"
+	"sync/atomic"
+	"time"
 )
 
-//go:embed assets/sql-formatter.min.js
-var jsCode []byte
-
 // Common errors returned by the package.
 var (
 	ErrEmptySQL        = errors.New("empty SQL string")
 	ErrSQLTooLarge     = errors.New("SQL string too large")
 	ErrFormatterClosed = errors.New("formatter is closed")
+	ErrNoCatalog       = errors.New("no catalog configured")
+	ErrStackOverflow   = errors.New("JavaScript stack overflow (see WithMaxStackSize)")
+	ErrNoBundle        = errors.New("no sql-formatter bundle: built with sqlfmt_noembed and WithBundlePath not given")
+	ErrMultipleQueries = errors.New("more than one statement")
+	ErrUnknownLanguage = errors.New("unknown language")
 )
 
-// spaceBeforeParenRegex matches a space before ( that is not at the start of a line
-// It's a hacky workaround for https://github.com/sql-formatter-org/sql-formatter/issues/444
-// (?m) enables multiline mode
-// ([^\n\s]) captures any character that's not a newline or space
-// \s matches the space we want to remove
-// \( matches the opening parenthesis
-var spaceBeforeParenRegex = regexp.MustCompile(`(?m)([^\n\s])\s\(`)
+// joinCalls removes the space sql-formatter puts between a name and the ( after it in formatted,
+// its output for input, a workaround for https://github.com/sql-formatter-org/sql-formatter/issues/444.
+// Names are identifiers, words other than keywords, data types such as VARCHAR, and the words input
+// writes directly before a (, such as COUNT or EXISTS. Spaces at the start of a line are kept, and
+// so are strings and comments. It also reports whether it rejoined a function call: a name other
+// than a keyword that input writes directly before a (, unlike a table and its column list. The
+// spaces it removes elsewhere are a matter of style rather than a fix.
+func joinCalls(input, formatted string, lang LanguageOption) (string, bool) {
+	called := make(map[string]bool) // words directly followed by ( in input
+	var prev token
+	scanTokens(input, lang, func(t token) bool {
+		if t.text == "(" && (prev.kind == tokenIdent || prev.kind == tokenWord) {
+			called[strings.ToUpper(prev.text)] = true
+		}
+		prev = t
+		return true
+	})
+
+	var (
+		b        strings.Builder
+		last     int
+		rejoined bool
+	)
+	tokens := tokenize(formatted, lang)
+	for i := 1; i+1 < len(tokens); i++ {
+		name, space := tokens[i-1], tokens[i]
+		if tokens[i+1].text != "(" || space.kind != tokenSpace || strings.Contains(space.text, "\n") {
+			continue
+		}
+		word := strings.ToUpper(name.text)
+		keyword := name.kind == tokenWord && isKeyword(word)
+		if name.kind != tokenIdent && name.kind != tokenWord || keyword && !dataTypes[word] && !called[word] {
+			continue
+		}
+		b.WriteString(formatted[last:space.start])
+		last = space.end
+		rejoined = rejoined || !keyword && called[word]
+	}
+	if last == 0 {
+		return formatted, false
+	}
+	b.WriteString(formatted[last:])
+	return b.String(), rejoined
+}
 
 // CaseOption defines the possible values for case-related formatting options.
 type CaseOption string
@@ -91,6 +170,53 @@ const (
 	LanguageTrino         LanguageOption = "trino"
 )
 
+// DumpFormatOption defines the dump tools whose output structure FormatOptions.DumpFormat
+// recognizes.
+type DumpFormatOption string
+
+const (
+	// DumpFormatPgDump is the plain-text output of pg_dump and pg_dumpall
+	DumpFormatPgDump DumpFormatOption = "pg_dump"
+	// DumpFormatMySQLDump is the output of mysqldump and mariadb-dump
+	DumpFormatMySQLDump DumpFormatOption = "mysqldump"
+)
+
+// DumpInsertsOption defines how the INSERT statements of a dump are handled when DumpFormat is set.
+type DumpInsertsOption string
+
+const (
+	// DumpInsertsFormat formats INSERT statements like any other (the default)
+	DumpInsertsFormat DumpInsertsOption = "format"
+	// DumpInsertsVerbatim passes INSERT statements through unformatted
+	DumpInsertsVerbatim DumpInsertsOption = "verbatim"
+	// DumpInsertsCompact puts each row of INSERT ... VALUES on its own line, without other changes
+	DumpInsertsCompact DumpInsertsOption = "compact"
+)
+
+// LeadingZeroOption defines how FormatOptions.LeadingZero writes decimals below one.
+type LeadingZeroOption string
+
+const (
+	// LeadingZeroPreserve keeps decimals as written (the default)
+	LeadingZeroPreserve LeadingZeroOption = "preserve"
+	// LeadingZeroAlways writes a zero before the decimal point (0.5)
+	LeadingZeroAlways LeadingZeroOption = "always"
+	// LeadingZeroNever drops the zero before the decimal point (.5)
+	LeadingZeroNever LeadingZeroOption = "never"
+)
+
+// ExecutableCommentsOption defines how FormatOptions.ExecutableComments handles the MySQL
+// executable comments, such as /*!50100 PARTITION BY HASH(id) */, which MySQL runs as SQL.
+type ExecutableCommentsOption string
+
+const (
+	// ExecutableCommentsFormat applies the keyword case and spacing of the query to the SQL inside
+	// the comment markers (the default)
+	ExecutableCommentsFormat ExecutableCommentsOption = "format"
+	// ExecutableCommentsVerbatim passes executable comments through as written
+	ExecutableCommentsVerbatim ExecutableCommentsOption = "verbatim"
+)
+
 // FormatOptions configures how SQL queries should be formatted.
 // It mirrors the options available in the sql-formatter JavaScript library.
 // For detailed documentation, see: https://github.com/sql-formatter-org/sql-formatter/tree/master/docs
@@ -111,7 +237,8 @@ type FormatOptions struct {
 	KeywordCase CaseOption `json:"keywordCase,omitempty"`
 	// SQL dialect to use (e.g., "mysql", "postgresql")
 	Language LanguageOption `json:"language,omitempty"`
-	// Number of empty lines between SQL statements
+	// Number of empty lines between SQL statements (0 for the sql-formatter default of 1,
+	// NoLinesBetweenQueries to put them on consecutive lines)
 	LinesBetweenQueries int `json:"linesBetweenQueries,omitempty"`
 	// Newline placement for logical operators (AND, OR, XOR)
 	LogicalOperatorNewline LogicalOperatorNewlineOption `json:"logicalOperatorNewline,omitempty"`
@@ -121,6 +248,64 @@ type FormatOptions struct {
 	TabWidth int `json:"tabWidth,omitempty"`
 	// Whether to use TAB characters for indentation instead of spaces
 	UseTabs bool `json:"useTabs,omitempty"`
+
+	// The options below are implemented by this package rather than sql-formatter.
+
+	// Catalog of known tables, used by schema-aware transforms such as QualifyTables
+	Catalog Catalog `json:"-"`
+	// Whether to prefix unqualified table references with their schema from Catalog
+	QualifyTables bool `json:"qualifyTables,omitempty"`
+	// Columns of known tables, used by ExpandStar
+	ColumnCatalog ColumnCatalog `json:"-"`
+	// Whether to replace SELECT * and t.* with explicit column lists from ColumnCatalog
+	ExpandStar bool `json:"expandStar,omitempty"`
+	// Whether to verify that the output has the same tokens as the input and fail otherwise
+	VerifyTokens bool `json:"verifyTokens,omitempty"`
+	// Whether to format the output again and fail unless that leaves it unchanged
+	VerifyIdempotent bool `json:"verifyIdempotent,omitempty"`
+	// Maximum number of consecutive blank lines kept from the input inside statements (0 removes them all)
+	MaxConsecutiveBlankLines int `json:"maxConsecutiveBlankLines,omitempty"`
+	// Whether to keep the line breaks of the input, only adjusting indentation, spacing and casing
+	PreserveLineBreaks bool `json:"preserveLineBreaks,omitempty"`
+	// Whether to align the operators of consecutive predicates in WHERE, HAVING and ON and of assignments in SET
+	AlignOperators bool `json:"alignOperators,omitempty"`
+	// Whether to align the AS keywords (or aliases) of single-line items across each SELECT list
+	AlignAliases bool `json:"alignAliases,omitempty"`
+	// Comma-separated operators (e.g., "::,=") or groups of operators (comparison, arithmetic, cast,
+	// concatenation, json, bitwise) that keep a space on each side when DenseOperators is set
+	DenseOperatorExceptions string `json:"denseOperatorExceptions,omitempty"`
+	// Whether to reorder the CREATE, ALTER, COMMENT and GRANT statements of a schema script so that
+	// objects come after their dependencies, tables before views before grants
+	OrderByDependencies bool `json:"orderByDependencies,omitempty"`
+	// Whether to also sort independent schema statements of the same kind by object name, for a
+	// canonical order (implies OrderByDependencies)
+	SortSchema bool `json:"sortSchema,omitempty"`
+	// Dump tool that produced the input, whose non-SQL parts are passed through unformatted; with
+	// the standard Language, the dialect of the tool is used
+	DumpFormat DumpFormatOption `json:"dumpFormat,omitempty"`
+	// How the INSERT statements of a dump are handled, which matters for dumps of large tables
+	DumpInserts DumpInsertsOption `json:"dumpInserts,omitempty"`
+	// Case of the exponent markers and hexadecimal digits of numeric literals (e.g., 1E6, 0xFF)
+	NumberCase CaseOption `json:"numberCase,omitempty"`
+	// Whether decimals below one are written with a zero before the decimal point (0.5) or without (.5)
+	LeadingZero LeadingZeroOption `json:"leadingZero,omitempty"`
+	// Whether to separate the digits of integers longer than four digits in groups of three with
+	// underscores (1_000_000), in the dialects that accept them (PostgreSQL, DuckDB)
+	GroupDigits bool `json:"groupDigits,omitempty"`
+	// Maximum width of the AND/OR chains of WHERE, HAVING and ON clauses kept on one line; longer
+	// chains put each predicate on its own line as set by LogicalOperatorNewline (0 always does)
+	PredicateChainWidth int `json:"predicateChainWidth,omitempty"`
+	// Text starting a comment, before the first statement, that marks the input as generated, so that
+	// the sqlfmt command leaves it alone (see IsGenerated; default "sqlfmt:generated")
+	GeneratedMarker string `json:"generatedMarker,omitempty"`
+	// How MySQL executable comments (/*!50100 ... */) are handled; they always keep their place
+	ExecutableComments ExecutableCommentsOption `json:"executableComments,omitempty"`
+	// Whether to keep the binds of sqlx, :name for sqlx.Named and ? for sqlx.In, as written and valid
+	// in every dialect
+	SQLXBinds bool `json:"sqlxBinds,omitempty"`
+	// Whether FormatWithResult maps each output line to the input bytes it was formatted from
+	// (FormatResult.LineSources)
+	MapLines bool `json:"mapLines,omitempty"`
 }
 
 // DefaultFormatOptions provides a default configuration for SQL formatting.
@@ -140,105 +325,452 @@ var DefaultFormatOptions = FormatOptions{
 	UseTabs:                false,
 }
 
-// Formatter provides SQL formatting functionality with a reusable JavaScript context.
+// Formatter provides SQL formatting functionality with reusable JavaScript contexts.
+// A Formatter is safe for concurrent use: calls are spread over a pool of contexts, which are
+// created on demand up to the size set with WithPoolSize and, with WithIdleTimeout, released
+// when the load drops.
 type Formatter struct {
-	ctx    *quickjs.JsContext
-	mu     sync.Mutex
-	closed bool
+	pool        *pool
+	cache       *resultCache // set with WithResultCache, or nil
+	fingerprint string       // BehaviorFingerprint of the bundle
+	closed      atomic.Bool
+
+	self       *Formatter             // f itself, to tell a copy from the original (see check)
+	closeStack atomic.Pointer[[]byte] // stack trace of Close, with misuseGuard
+}
+
+// FormatterOption configures a Formatter created by NewFormatter.
+type FormatterOption func(*formatterConfig)
+
+// formatterConfig holds the settings applied by FormatterOption values.
+type formatterConfig struct {
+	poolSize     int
+	minPoolSize  int
+	scaleUpQueue int
+	idleTimeout  time.Duration
+	cacheSize    int
+	prewarm      int
+	bundlePath   string
+	maxStackSize int
+}
+
+// WithPoolSize sets the maximum number of JavaScript contexts the formatter uses to serve
+// concurrent calls. Each context holds its own copy of sql-formatter. The default is
+// runtime.GOMAXPROCS(0); values below 1 select the default.
+func WithPoolSize(n int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.poolSize = n
+	}
+}
+
+// WithMinPoolSize sets the number of JavaScript contexts that WithIdleTimeout does not release,
+// which NewFormatter creates and warms up like WithPrewarm. The default is 0.
+func WithMinPoolSize(n int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.minPoolSize = n
+	}
+}
+
+// WithIdleTimeout makes the formatter release JavaScript contexts that have not been used for d,
+// down to the size set with WithMinPoolSize, so that a long-running program holds as many
+// contexts as its current load needs rather than as its peak did. The default, 0, keeps contexts
+// until the formatter is closed.
+func WithIdleTimeout(d time.Duration) FormatterOption {
+	return func(c *formatterConfig) {
+		c.idleTimeout = d
+	}
+}
+
+// WithScaleUpQueue makes a call that finds every JavaScript context in use wait for one to be
+// free, rather than create another, until n calls are waiting already. Higher values keep the
+// pool smaller under bursts of short calls, at the cost of latency. The default, 0, creates a
+// context whenever none is free, up to the pool size.
+func WithScaleUpQueue(n int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.scaleUpQueue = n
+	}
+}
+
+// WithResultCache makes the formatter keep the results of its latest n Format calls in memory and
+// return them for the same input and options without formatting again, which pays off for
+// services that see the same queries over and over. Results are keyed on a hash of the input, the
+// options, the backend and the sql-formatter bundle, so that changing any of them never returns
+// stale output. Calls with a Catalog or ColumnCatalog, and calls that fail, are not cached. The
+// default, 0, disables the cache.
+func WithResultCache(n int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.cacheSize = n
+	}
+}
+
+// WithPrewarm makes NewFormatter create n JavaScript contexts (at most the pool size) and warm
+// them up with a trivial format, instead of creating them on first use. This moves the cost of
+// initialization from the first calls to construction.
+func WithPrewarm(n int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.prewarm = n
+	}
+}
+
+// WithBundlePath loads sql-formatter from a file or an http(s) URL instead of the copy embedded in
+// the package. Together with the sqlfmt_noembed build tag, which leaves the embedded copy out of
+// the binary, it lets programs use a bundle shipped separately. The bundle must be the browser
+// build (sql-formatter.min.js), which defines the global sqlFormatter.
+func WithBundlePath(path string) FormatterOption {
+	return func(c *formatterConfig) {
+		c.bundlePath = path
+	}
+}
+
+// WithMaxStackSize sets the stack size, in bytes, that each JavaScript context may use. sql-formatter
+// formats expressions recursively, so deeply nested queries (such as machine-generated ones) can
+// exceed the QuickJS default of 256 KiB and fail with ErrStackOverflow. The limit only guards the
+// stack of the context's OS thread, which is typically 8 MiB, and should stay well below it.
+func WithMaxStackSize(bytes int) FormatterOption {
+	return func(c *formatterConfig) {
+		c.maxStackSize = bytes
+	}
 }
 
 // NewFormatter creates a new SQL formatter instance.
 // The returned Formatter must be closed when no longer needed to free resources.
-func NewFormatter() (*Formatter, error) {
-	ctx, err := quickjs.NewContext()
-	if err != nil {
-		return nil, fmt.Errorf("creating QuickJS context: %w", err)
+func NewFormatter(opts ...FormatterOption) (*Formatter, error) {
+	var config formatterConfig
+	for _, opt := range opts {
+		opt(&config)
 	}
 
-	f := &Formatter{ctx: ctx}
+	bundle := jsCode
+	if config.bundlePath != "" {
+		var err error
+		if bundle, err = loadBundle(config.bundlePath); err != nil {
+			return nil, err
+		}
+	}
+	if len(bundle) == 0 && needsBundle() {
+		return nil, ErrNoBundle
+	}
 
-	if err := f.initialize(); err != nil {
-		// QuickJS contexts don't have explicit close in this library
+	engineConfig := engineConfig{bundle: bundle, maxStackSize: config.maxStackSize}
+	e, err := newEngine(engineConfig)
+	if err != nil {
 		return nil, err
 	}
 
+	f := &Formatter{
+		pool:        newPool(config, engineConfig, e),
+		cache:       newResultCache(config.cacheSize, bundle),
+		fingerprint: behaviorFingerprint(bundle),
+	}
+	f.self = f
+	if n := max(config.prewarm, config.minPoolSize); n > 0 {
+		if err := f.pool.prewarm(n); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	trackLeak(f)
+
 	return f, nil
 }
 
-// initialize sets up the JavaScript environment.
-func (f *Formatter) initialize() error {
-	// Evaluate the embedded JavaScript code
-	_, err := f.ctx.Eval(string(jsCode), nil)
-	if err != nil {
-		return fmt.Errorf("evaluating sql-formatter.min.js: %w", err)
+// Warmup formats a trivial query with each JavaScript context that is not in use, so that later
+// calls do not pay for lazy initialization. Contexts created afterwards are not warmed up;
+// use WithPrewarm to create them ahead of time.
+func (f *Formatter) Warmup() error {
+	if err := f.check("Warmup"); err != nil {
+		return err
 	}
+	return f.pool.warmup()
+}
 
-	// Set up the formatting function
-	setupCode := `
-		function formatSql(sql, optionsJson) {
-			const options = JSON.parse(optionsJson);
-			return sqlFormatter.format(sql, options);
-		}
-	`
-	_, err = f.ctx.Eval(setupCode, nil)
-	if err != nil {
-		return fmt.Errorf("setting up formatSql function: %w", err)
+// Prewarm creates JavaScript contexts until f has n of them (at most the pool size) and warms
+// them up, like WithPrewarm does in NewFormatter. It lets a server start quickly and report
+// itself ready once Prewarm returns.
+func (f *Formatter) Prewarm(n int) error {
+	if err := f.check("Prewarm"); err != nil {
+		return err
 	}
+	return f.pool.prewarm(n)
+}
 
-	return nil
+// ContextStats describes the memory use of one of the JavaScript contexts of a Formatter.
+type ContextStats struct {
+	// MemoryUsed is the number of bytes allocated by the QuickJS runtime of the context.
+	MemoryUsed int64
+	// Objects is the number of live JavaScript objects.
+	Objects int64
+}
+
+// ContextStats returns the memory use of the JavaScript contexts of f, in order of creation.
+// Measuring a context walks its heap, which takes in the order of 100µs, so only the contexts
+// that are not in use are measured; the others report their previous measurement, or zeros.
+func (f *Formatter) ContextStats() ([]ContextStats, error) {
+	if err := f.check("ContextStats"); err != nil {
+		return nil, err
+	}
+	return f.pool.stats()
+}
+
+// Clone returns a formatter sharing the JavaScript contexts of f, which is much cheaper than
+// NewFormatter since no context has to be created. The clone must be closed independently;
+// the contexts are released when the last formatter sharing them is closed. Since the pool is
+// shared, clones do not raise the number of calls that can run in parallel.
+func (f *Formatter) Clone() (*Formatter, error) {
+	if err := f.check("Clone"); err != nil {
+		return nil, err
+	}
+	if !f.pool.acquire() {
+		return nil, ErrFormatterClosed
+	}
+	clone := &Formatter{pool: f.pool, cache: f.cache, fingerprint: f.fingerprint}
+	clone.self = clone
+	trackLeak(clone)
+	return clone, nil
 }
 
 // Format formats a SQL query string according to the provided formatting options.
+//
+// The output depends only on sql and options (and the catalogs they reference): it does not vary
+// with time, map iteration order or the context used. A panic while formatting is returned as a
+// *PanicError rather than crashing the program.
 func (f *Formatter) Format(sql string, options FormatOptions) (string, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	formatted, _, err := f.format(sql, options)
+	return formatted, err
+}
+
+// format implements Format, also returning the warnings raised while formatting.
+func (f *Formatter) format(sql string, options FormatOptions) (string, []Warning, error) {
+	formatted, warnings, _, err := f.formatCached(sql, options)
+	return formatted, warnings, err
+}
+
+// formatCached implements format, returning the result from the cache set with WithResultCache if
+// it holds it, as reported by hit.
+func (f *Formatter) formatCached(sql string, options FormatOptions) (formatted string, warnings []Warning, hit bool, err error) {
+	if err := f.check("Format"); err != nil {
+		return "", nil, false, err
+	}
+	var (
+		key        [sha256.Size]byte
+		generation uint64
+		cached     = f.cache != nil
+	)
+	if cached {
+		key, cached = f.cache.key(sql, options)
+	}
+	if cached {
+		var entry *cacheEntry
+		if entry, generation, hit = f.cache.get(key); hit {
+			return entry.formatted, entry.warnings, true, nil
+		}
+	}
+
+	e, err := f.pool.get()
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer f.pool.put(e)
+
+	formatted, warnings, err = f.formatWith(e, sql, options, nil)
+	if err == nil && cached {
+		f.cache.put(key, generation, formatted, warnings)
+	}
+	return formatted, warnings, false, err
+}
+
+// InvalidateCache drops the results kept by the cache set with WithResultCache, which f shares
+// with its clones, including those of calls still in progress. The cache is keyed on the options
+// and the sql-formatter bundle, so this is only needed when something else changes the output,
+// such as the content of a Catalog, whose results are not cached anyway, or a deliberate reset.
+func (f *Formatter) InvalidateCache() {
+	if f.check("InvalidateCache") == nil && f.cache != nil {
+		f.cache.invalidate()
+	}
+}
+
+// formatWith implements format using the engine e. trace, if not nil, records the passes applied.
+func (f *Formatter) formatWith(e *engine, sql string, options FormatOptions, trace *passTrace) (formatted string, warnings []Warning, err error) {
+	if options.VerifyIdempotent {
+		return f.formatIdempotent(e, sql, options, trace)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			formatted, warnings, err = "", nil, panicError(e, r)
+		}
+	}()
+
+	// Pass the parts of a dump that are not SQL through
+	dump := dumpOptions(options)
+	if parts := splitDump(sql, dump); parts != nil {
+		return f.formatDump(e, parts, dump, trace)
+	}
+
+	// Format the parts of the input governed by directive comments separately
+	chunks, err := splitDirectives(sql, options)
+	if err != nil {
+		return "", nil, err
+	}
+	if chunks != nil {
+		trace.note("directives", fmt.Sprintf("%d parts with their own options", len(chunks)))
+		return f.formatChunks(e, chunks, options, trace)
+	}
 
-	if f.closed {
-		return "", ErrFormatterClosed
+	// Replace option values sql-formatter does not know by their defaults
+	options, warnings = checkOptions(options)
+	for _, w := range warnings {
+		trace.note("checkOptions", w.Message)
 	}
 
-	// Marshal options to JSON
-	optionsJSON, err := json.Marshal(options)
+	// Rewrite the query before layout
+	input := sql
+	sql, err = applyTransforms(sql, options)
 	if err != nil {
-		return "", fmt.Errorf("marshaling options: %w", err)
+		return "", nil, err
 	}
+	trace.record("transforms", input, sql)
 
-	// Call the JavaScript function
-	res, err := f.ctx.CallFunc("formatSql", sql, string(optionsJSON))
+	// Keep sqlx binds and optimizer hints out of sql-formatter's way; they are put back below
+	protected, binds := protectBinds(sql, options)
+	unhinted, hints := extractHints(protected, options.Language)
+	formatted, err = e.formatSQL(unhinted, options)
 	if err != nil {
-		return "", fmt.Errorf("calling formatSql: %w", err)
+		return "", nil, f.formatError(e, err, sql, options.Language)
+	}
+	trace.record("sql-formatter", unhinted, formatted)
+
+	// Remove spaces between names and their (
+	fixed, rejoined := joinCalls(unhinted, formatted, options.Language)
+	trace.record("spaceBeforeParen", formatted, fixed)
+	if rejoined {
+		warnings = append(warnings, Warning{
+			Code:    WarningWorkaround,
+			Message: "removed spaces before parentheses added by sql-formatter (sql-formatter issue 444)",
+		})
+	}
+	formatted = fixed
+
+	if hints != nil {
+		before := formatted
+		restored, ok := hints.restore(formatted, options.Language)
+		if !ok {
+			// sql-formatter changed the tokens, so let it place the hints itself
+			restored, err = e.formatSQL(protected, options)
+			if err != nil {
+				return "", nil, f.formatError(e, err, sql, options.Language)
+			}
+			restored, _ = joinCalls(protected, restored, options.Language)
+		}
+		formatted = restored
+		trace.record("hints", before, formatted)
+	}
+	if binds != nil {
+		before := formatted
+		formatted = binds.restore(formatted, options.Language)
+		trace.record("sqlxBinds", before, formatted)
+	}
+	if lexiconFor(options.Language).executableComments && options.ExecutableComments != ExecutableCommentsVerbatim {
+		before := formatted
+		formatted = formatExecutableComments(formatted, options)
+		trace.record("executableComments", before, formatted)
+	}
+	if options.PredicateChainWidth > 0 {
+		before := formatted
+		formatted = collapsePredicateChains(formatted, options.Language, options.PredicateChainWidth)
+		trace.record("predicateChainWidth", before, formatted)
 	}
 
-	// Convert result to string
-	formatted, ok := res.(string)
-	if !ok {
-		return "", fmt.Errorf("unexpected result type: %T", res)
+	if options.DenseOperators && options.DenseOperatorExceptions != "" {
+		before := formatted
+		formatted = spaceOperators(formatted, options)
+		trace.record("denseOperatorExceptions", before, formatted)
+	}
+	if formatsNumbers(options) {
+		before := formatted
+		formatted = formatNumbers(formatted, options)
+		trace.record("numbers", before, formatted)
 	}
 
-	// Remove spaces before ( except at the start of lines
-	formatted = spaceBeforeParenRegex.ReplaceAllString(formatted, "$1(")
+	if options.PreserveLineBreaks {
+		before := formatted
+		formatted = preserveLineBreaks(sql, formatted, options, options.MaxConsecutiveBlankLines)
+		trace.record("preserveLineBreaks", before, formatted)
+	} else if options.MaxConsecutiveBlankLines > 0 {
+		before := formatted
+		formatted = keepBlankLines(sql, formatted, options.Language, options.MaxConsecutiveBlankLines)
+		trace.record("maxConsecutiveBlankLines", before, formatted)
+	}
 
-	return formatted, nil
+	if options.AlignOperators {
+		before := formatted
+		formatted = alignOperators(formatted, options.Language)
+		trace.record("alignOperators", before, formatted)
+	}
+	if options.AlignAliases {
+		before := formatted
+		formatted = alignAliases(formatted, options.Language)
+		trace.record("alignAliases", before, formatted)
+	}
+
+	if options.VerifyTokens {
+		if err := verifyTokens(sql, formatted, options); err != nil {
+			return "", nil, err
+		}
+		trace.note("verifyTokens", "output has the same tokens as the input")
+	}
+
+	return formatted, warnings, nil
 }
 
-// Close releases the resources associated with the formatter.
-// Note: The QuickJS library doesn't provide explicit close for contexts,
-// but this method ensures the formatter is marked as closed.
+// Close releases the resources associated with the formatter. Calls in progress are allowed to
+// finish; later calls fail with ErrFormatterClosed. Contexts shared with clones are released when
+// the last of them is closed, which frees their QuickJS runtimes and heaps.
 func (f *Formatter) Close() error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	if f.closed {
+	if misuseGuard {
+		if err := f.check("Close"); err != nil && !errors.Is(err, ErrFormatterClosed) {
+			return err
+		}
+	}
+	if f.closed.Swap(true) {
 		return nil
 	}
-
-	f.closed = true
+	f.recordClose()
+	runtime.SetFinalizer(f, nil)
+	f.pool.release()
 	return nil
 }
 
+// shared is the formatter behind the package-level functions, created on first use and never
+// closed.
+var shared struct {
+	f  atomic.Pointer[Formatter]
+	mu sync.Mutex // held while creating f
+}
+
+// sharedFormatter returns the formatter behind the package-level functions, or the error that
+// prevented its creation. A failed creation is not kept: the next call tries again.
+func sharedFormatter() (*Formatter, error) {
+	if f := shared.f.Load(); f != nil {
+		return f, nil
+	}
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if f := shared.f.Load(); f != nil {
+		return f, nil
+	}
+	f, err := NewFormatter()
+	if err != nil {
+		return nil, err
+	}
+	shared.f.Store(f)
+	return f, nil
+}
+
 // Format formats a SQL query string according to the provided formatting options.
-// It creates a new formatter instance for each call, which may be inefficient for
-// multiple formatting operations. Consider using NewFormatter for better performance.
+// It uses a formatter shared by all callers in the program, created on first use, so it is safe
+// for concurrent use: concurrent calls run in parallel on up to runtime.GOMAXPROCS(0) JavaScript
+// contexts and wait for one beyond that. Programs that need a different pool size or other
+// FormatterOptions should use NewFormatter.
 //
 // Parameters:
 //   - sql: The SQL query string to format
@@ -256,13 +788,19 @@ func (f *Formatter) Close() error {
 //	}
 //	fmt.Println(formatted)
 func Format(sql string, options FormatOptions) (string, error) {
-	f, err := NewFormatter()
+	f, err := sharedFormatter()
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		_ = f.Close() // Error is intentionally ignored as cleanup is best-effort
-	}()
-
 	return f.Format(sql, options)
 }
+
+// FormatMany formats a batch of queries in parallel with the formatter shared by Format, like
+// Formatter.FormatMany.
+func FormatMany(sqls []string, options FormatOptions) ([]string, error) {
+	f, err := sharedFormatter()
+	if err != nil {
+		return nil, err
+	}
+	return f.FormatMany(sqls, options)
+}
@@ -0,0 +1,207 @@
+// Package sqlfmttest runs golden tests of SQL formatting, so that projects depending on sqlfmt can
+// pin the output they expect and notice when a new version of sqlfmt or of the bundled
+// sql-formatter changes it.
+//
+// Each test case is a directory holding an input.sql file and the expected.sql file it must
+// format to:
+//
+//	testdata/golden/
+//	    joins/
+//	        input.sql
+//	        expected.sql
+//	    lowercase/
+//	        input.sql
+//	        expected.sql
+//
+// The options of a case are those passed to Run, overridden by a header of directive comments at
+// the top of input.sql, written like the directives accepted within files:
+//
+//	-- sqlfmt: keywordCase=lower tabWidth=4
+//	select id from users;
+//
+// The header is not part of the input that is formatted. Running the tests with -update writes
+// the output to expected.sql instead of comparing it, creating the file if needed:
+//
+//	go test ./... -run TestGolden -update
+//
+// sqlfmttest registers the -update flag, so packages that import it must not define their own.
+package sqlfmttest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+// InputFile and ExpectedFile are the names of the files of a test case.
+const (
+	InputFile    = "input.sql"
+	ExpectedFile = "expected.sql"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected output of sqlfmt golden tests")
+
+// Case is a golden test case.
+type Case struct {
+	// Name is the path of the directory of the case, relative to the directory passed to Load.
+	Name string
+	// Dir is the directory of the case.
+	Dir string
+	// Input is the SQL to format, without the options header.
+	Input string
+	// Options are the options to format Input with.
+	Options sqlfmt.FormatOptions
+	// Expected is the content of the expected file, empty if it does not exist.
+	Expected string
+}
+
+// Load returns the test cases under dir: the directories holding an input.sql file, at any
+// depth, in lexical order. base holds the options of the cases without a header.
+func Load(dir string, base sqlfmt.FormatOptions) ([]Case, error) {
+	var cases []Case
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != InputFile {
+			return err
+		}
+		caseDir := filepath.Dir(path)
+		name, err := filepath.Rel(dir, caseDir)
+		if err != nil {
+			return err
+		}
+		c, err := loadCase(caseDir, base)
+		if err != nil {
+			return err
+		}
+		c.Name = filepath.ToSlash(name)
+		cases = append(cases, c)
+		return nil
+	})
+	return cases, err
+}
+
+// loadCase reads the case in dir.
+func loadCase(dir string, base sqlfmt.FormatOptions) (Case, error) {
+	input, err := os.ReadFile(filepath.Join(dir, InputFile))
+	if err != nil {
+		return Case{}, err
+	}
+	options, body, err := ParseHeader(string(input), base)
+	if err != nil {
+		return Case{}, fmt.Errorf("%s: %w", filepath.Join(dir, InputFile), err)
+	}
+	expected, err := os.ReadFile(filepath.Join(dir, ExpectedFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Case{}, err
+	}
+	return Case{Dir: dir, Input: body, Options: options, Expected: string(expected)}, nil
+}
+
+// ParseHeader splits the options header off input. The header is made of the "-- sqlfmt:"
+// comment lines at the top of input, applied in order over base with sqlfmt.ApplyDirective, each
+// holding key=value pairs named like the keys of the configuration file. Blank lines between the
+// header and the SQL are dropped.
+func ParseHeader(input string, base sqlfmt.FormatOptions) (sqlfmt.FormatOptions, string, error) {
+	options, rest := base, input
+	found := false
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		applied, ok, err := sqlfmt.ApplyDirective(line, base, options)
+		if !ok {
+			break
+		}
+		if err != nil {
+			return base, input, fmt.Errorf("options header: %w", err)
+		}
+		options, rest, found = applied, next, true
+	}
+	if !found {
+		return base, input, nil
+	}
+	return options, strings.TrimLeft(rest, " \t\r\n"), nil
+}
+
+// Run runs the golden test cases under dir as subtests of t, named after their directories, with
+// a Formatter created for the purpose. base holds the options of the cases without a header.
+func Run(t *testing.T, dir string, base sqlfmt.FormatOptions) {
+	t.Helper()
+	f, err := sqlfmt.NewFormatter()
+	if err != nil {
+		t.Fatalf("creating formatter: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	RunFormatter(t, f, dir, base)
+}
+
+// RunFormatter is like Run but formats with f.
+func RunFormatter(t *testing.T, f *sqlfmt.Formatter, dir string, base sqlfmt.FormatOptions) {
+	t.Helper()
+	cases, err := Load(dir, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no %s found under %s", InputFile, dir)
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := f.Format(c.Input, c.Options)
+			if err != nil {
+				t.Fatalf("formatting %s: %v", filepath.Join(c.Dir, InputFile), err)
+			}
+			Check(t, c, got+"\n")
+		})
+	}
+}
+
+// Check compares got, the output of formatting the case c, with its expected file, or with
+// -update writes got to that file.
+func Check(t *testing.T, c Case, got string) {
+	t.Helper()
+	path := filepath.Join(c.Dir, ExpectedFile)
+	if *update {
+		if got != c.Expected {
+			if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("updated %s", path)
+		}
+		return
+	}
+	if c.Expected == "" {
+		t.Fatalf("%s is missing; run the tests with -update to create it", path)
+	}
+	if got != c.Expected {
+		line, gotLine, wantLine := firstDifference(got, c.Expected)
+		t.Errorf("output differs from %s at line %d:\n got: %q\nwant: %q\n\nfull output:\n%s\n(run the tests with -update to accept it)",
+			path, line, gotLine, wantLine, got)
+	}
+}
+
+// firstDifference returns the number of the first line (1-based) that differs between got and
+// want, and that line in each.
+func firstDifference(got, want string) (int, string, string) {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; ; i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w || i >= len(gotLines) || i >= len(wantLines) {
+			return i + 1, g, w
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package sqlfmttest
+
+import (
+	"testing"
+
+	"github.com/0x6b/sqlfmt"
+)
+
+func TestParseHeader(t *testing.T) {
+	if sqlfmt.Backend == "native" {
+		t.Skip("the native backend does not apply directives")
+	}
+	input := "-- sqlfmt: keywordCase=lower tabWidth=2\n-- sqlfmt: generatedMarker=NaN\n\nselect 1;\n"
+	options, body, err := ParseHeader(input, sqlfmt.DefaultFormatOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.KeywordCase != sqlfmt.CaseOptionLower || options.TabWidth != 2 || options.GeneratedMarker != "NaN" {
+		t.Errorf("got options %+v", options)
+	}
+	if body != "select 1;\n" {
+		t.Errorf("got body %q, want %q", body, "select 1;\n")
+	}
+
+	for _, input := range []string{"select 1;", "-- a comment\nselect 1;"} {
+		options, body, err := ParseHeader(input, sqlfmt.DefaultFormatOptions)
+		if err != nil || body != input || options != sqlfmt.DefaultFormatOptions {
+			t.Errorf("ParseHeader(%q) = %+v, %q, %v; want the input unchanged", input, options, body, err)
+		}
+	}
+
+	if _, _, err := ParseHeader("-- sqlfmt: tabWidth=Inf\nselect 1;", sqlfmt.DefaultFormatOptions); err == nil {
+		t.Error("tabWidth=Inf: got no error")
+	}
+}
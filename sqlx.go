@@ -0,0 +1,100 @@
+package sqlfmt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// bindSet holds the sqlx binds taken out of a query by protectBinds.
+type bindSet struct {
+	prefix string   // prefix of the words standing for the binds, followed by their index
+	binds  []string // the binds, as written
+}
+
+// sqlxLexicon returns the lexicon of lang extended with the binds of sqlx: ? for sqlx.In and
+// :name, where the name may hold dots, for sqlx.Named. Like sqlx, it takes :: and := for
+// operators.
+func sqlxLexicon(lang LanguageOption) lexicon {
+	lx := lexiconFor(lang)
+	lx.positionalParams = true
+	if !strings.Contains(lx.namedParams, ":") {
+		lx.namedParams += ":"
+	}
+	return lx
+}
+
+// protectBinds replaces the sqlx binds of sql with words, which every dialect accepts where a
+// value is expected and sql-formatter leaves whole, since it may reject binds the dialect does not
+// have, such as :name in MySQL, or split them from their operator. It returns sql unchanged and
+// nil if options.SQLXBinds is not set or there is no bind.
+func protectBinds(sql string, options FormatOptions) (string, *bindSet) {
+	if !options.SQLXBinds {
+		return sql, nil
+	}
+	set := bindSet{prefix: "sqlxbind"}
+	for strings.Contains(strings.ToLower(sql), set.prefix) {
+		set.prefix += "_"
+	}
+
+	var (
+		b       strings.Builder
+		pending string // a :name bind, which may continue with .name
+		dot     bool   // pending is followed by a dot
+	)
+	flush := func() {
+		if pending == "" {
+			return
+		}
+		b.WriteString(set.prefix + strconv.Itoa(len(set.binds)))
+		set.binds = append(set.binds, pending)
+		if dot {
+			b.WriteByte('.')
+		}
+		pending, dot = "", false
+	}
+	sqlxLexicon(options.Language).scan(sql, func(t token) bool {
+		switch {
+		case pending != "" && !dot && t.text == ".":
+			dot = true
+			return true
+		case dot && t.kind == tokenWord:
+			pending += "." + t.text
+			dot = false
+			return true
+		}
+		flush()
+		switch {
+		case t.kind == tokenParam && t.text == "?":
+			b.WriteString(set.prefix + strconv.Itoa(len(set.binds)))
+			set.binds = append(set.binds, t.text)
+		case t.kind == tokenParam && strings.HasPrefix(t.text, ":"):
+			pending = t.text
+		default:
+			b.WriteString(t.text)
+		}
+		return true
+	})
+	flush()
+	if set.binds == nil {
+		return sql, nil
+	}
+	return b.String(), &set
+}
+
+// restore puts the binds back into formatted, the formatted query, in place of the words standing
+// for them, whatever case sql-formatter gave these.
+func (set *bindSet) restore(formatted string, lang LanguageOption) string {
+	var b strings.Builder
+	b.Grow(len(formatted))
+	scanTokens(formatted, lang, func(t token) bool {
+		if t.kind == tokenWord && len(t.text) > len(set.prefix) && strings.EqualFold(t.text[:len(set.prefix)], set.prefix) {
+			if i, err := strconv.Atoi(t.text[len(set.prefix):]); err == nil && i < len(set.binds) {
+				b.WriteString(set.binds[i])
+				return true
+			}
+		}
+		b.WriteString(t.text)
+		return true
+	})
+	return b.String()
+}
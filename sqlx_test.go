@@ -0,0 +1,84 @@
+package sqlfmt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSQLXBinds(t *testing.T) {
+	tests := []struct {
+		name string
+		lang LanguageOption
+		sql  string
+		want string
+	}{
+		{
+			name: "named binds in a dialect without them",
+			lang: LanguageMySQL,
+			sql:  "select * from users where id = :id and name = :user.name",
+			want: "SELECT\n    *\nFROM\n    users\nWHERE\n    id = :id AND\n    name = :user.name",
+		},
+		{
+			name: "casts next to binds",
+			lang: LanguagePostgreSQL,
+			sql:  "select a::text from t where id in (?) and b = :b::int",
+			want: "SELECT\n    a::TEXT\nFROM\n    t\nWHERE\n    id IN (?) AND\n    b = :b::INT",
+		},
+		{
+			name: "in expansion",
+			lang: LanguageSQL,
+			sql:  "update t set a = :a, b = :b where id in (?)",
+			want: "UPDATE t\nSET\n    a = :a,\n    b = :b\nWHERE\n    id IN (?)",
+		},
+		{
+			name: "values",
+			lang: LanguageMySQL,
+			sql:  "insert into t (a, b) values (:a, :b)",
+			want: "INSERT INTO\n    t(a, b)\nVALUES\n    (:a, :b)",
+		},
+		{
+			name: "query holding the placeholder prefix",
+			lang: LanguagePostgreSQL,
+			sql:  "select :x, sqlxbind0 from t where a in (?)",
+			want: "SELECT\n    :x,\n    sqlxbind0\nFROM\n    t\nWHERE\n    a IN (?)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultFormatOptions.WithLanguage(tt.lang)
+			options.SQLXBinds = true
+			got, err := Format(tt.sql, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectBinds(t *testing.T) {
+	tests := []struct {
+		sql   string
+		binds []string
+	}{
+		{"select :a, :b.c from t", []string{":a", ":b.c"}},
+		{"select a::text, b := 1 from t where c in (?, ?)", []string{"?", "?"}},
+		{"select ':a', \"?\" from t -- :b ?", nil},
+		{"select 1", nil},
+	}
+	for _, tt := range tests {
+		protected, set := protectBinds(tt.sql, FormatOptions{Language: LanguagePostgreSQL, SQLXBinds: true})
+		var binds []string
+		if set != nil {
+			binds = set.binds
+			if restored := set.restore(protected, LanguagePostgreSQL); restored != tt.sql {
+				t.Errorf("restore(%q) = %q, want %q", protected, restored, tt.sql)
+			}
+		}
+		if !slices.Equal(binds, tt.binds) {
+			t.Errorf("protectBinds(%q) found binds %q, want %q", tt.sql, binds, tt.binds)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package sqlfmt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// StatementResult is the outcome of formatting one statement of a script with FormatStatements.
+type StatementResult struct {
+	// Start and End are the byte offsets of the statement in the input, including its leading
+	// comments and its terminating semicolon, if any.
+	Start, End int
+	// Kind is the upper-cased keyword that determines the kind of statement, such as SELECT,
+	// INSERT or CREATE. For WITH queries it is the keyword of the main statement.
+	Kind string
+	// Formatted is the formatted statement, or "" if Err is set.
+	Formatted string
+	// Err is the error formatting the statement, if any.
+	Err error
+	// Verbatim reports that FormatStream passed the text through unformatted, because the
+	// statement is larger than the limit set with SkipLargerThan or because it is a part of a dump
+	// that is not SQL (see FormatOptions.DumpFormat). Formatted then holds the text as written.
+	Verbatim bool
+}
+
+// FormatStatements splits sql into statements and formats each of them separately, so that an
+// error in one statement does not prevent formatting the others. The returned error is only set
+// when the formatter cannot be used at all, for instance because it is closed.
+func (f *Formatter) FormatStatements(sql string, options FormatOptions) ([]StatementResult, error) {
+	if err := f.check("FormatStatements"); err != nil {
+		return nil, err
+	}
+	var results []StatementResult
+	for _, stmt := range splitStatements(sql, options.Language) {
+		formatted, err := f.Format(sql[stmt.start:stmt.end], options)
+		if errors.Is(err, ErrFormatterClosed) {
+			return nil, err
+		}
+		results = append(results, StatementResult{
+			Start:     stmt.start,
+			End:       stmt.end,
+			Kind:      stmt.kind,
+			Formatted: formatted,
+			Err:       err,
+		})
+	}
+	return results, nil
+}
+
+// StreamOption configures a call to FormatStream.
+type StreamOption func(*streamConfig)
+
+// streamConfig holds the settings applied by StreamOption values.
+type streamConfig struct {
+	skipLargerThan int
+}
+
+// skips reports whether the statement sql is over the limit set with SkipLargerThan.
+func (c streamConfig) skips(sql string) bool {
+	return c.skipLargerThan > 0 && len(sql) > c.skipLargerThan
+}
+
+// SkipLargerThan makes FormatStream pass statements larger than n bytes through unformatted, as
+// Verbatim results. Formatting takes time and memory in proportion to the size of a statement,
+// which for the bulk INSERT statements of dumps can be hundreds of megabytes. Outside dumps, a
+// statement is not held once it is over the limit: it is reported in chunks as it is read, as
+// Verbatim results without a Kind that concatenate to it, like the verbatim parts of dumps. Only
+// a single token, such as a string literal, is held whole. Values below 1 set no limit, the
+// default.
+func SkipLargerThan(n int) StreamOption {
+	return func(c *streamConfig) {
+		c.skipLargerThan = n
+	}
+}
+
+// FormatStream reads a script from r and formats each statement as soon as it is complete, that
+// is, as soon as its terminating semicolon has been read, calling fn with the result. Offsets are
+// relative to the whole input. The text after the last semicolon is formatted once r is exhausted.
+// Like FormatStatements, errors formatting a statement are reported in its result; FormatStream
+// itself stops at the first error returned by r or fn.
+//
+// FormatStream holds one statement in memory at a time, whatever the size of the input, and
+// tokenizes the input once as it arrives. With options.DumpFormat set, the input is read line by
+// line and the parts of the dump that are not SQL are reported as Verbatim results with their
+// surrounding whitespace, so that consecutive Verbatim results concatenate to the input; the data
+// of COPY statements is reported in chunks.
+// Statements are formatted one at a time, so OrderByDependencies and SortSchema have no effect.
+func (f *Formatter) FormatStream(r io.Reader, options FormatOptions, fn func(StatementResult) error, opts ...StreamOption) error {
+	var config streamConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if isDumpFormat(options.DumpFormat) {
+		return f.formatDumpStream(r, dumpOptions(options), config, fn)
+	}
+
+	var (
+		pending  strings.Builder // input from base on
+		base     int             // offset of pending in the input
+		done     int             // length of the part of pending already reported
+		chunk    = make([]byte, 32*1024)
+		s        = newStatementSplitter(options.Language)
+		skipping bool // whether the current statement is over the limit and reported in chunks
+	)
+	for {
+		n, readErr := r.Read(chunk)
+		pending.Write(chunk[:n])
+		eof := readErr == io.EOF
+		if readErr != nil && !eof {
+			return readErr
+		}
+
+		sql := pending.String()
+		report := func(result StatementResult, start, end int) error {
+			result.Start, result.End = base+start, base+end
+			done = end
+			return fn(result)
+		}
+		for _, stmt := range s.split(sql, eof) {
+			var (
+				result StatementResult
+				err    error
+			)
+			if skipping {
+				// The last chunk of the statement, reported like the others.
+				stmt.start = done
+				result, skipping = StatementResult{Formatted: sql[stmt.start:stmt.end], Verbatim: true}, false
+			} else if result, err = f.streamStatement(sql[stmt.start:stmt.end], options, config); err != nil {
+				return err
+			} else {
+				result.Kind = stmt.kind
+			}
+			if err := report(result, stmt.start, stmt.end); err != nil {
+				return err
+			}
+		}
+		// Report the text scanned so far of a statement over the limit rather than hold it.
+		if start := max(s.start, done); s.start >= 0 && s.scanned > start && (skipping || config.skips(sql[start:])) {
+			skipping = true
+			if err := report(StatementResult{Formatted: sql[start:s.scanned], Verbatim: true}, start, s.scanned); err != nil {
+				return err
+			}
+		}
+		// Drop the reported text once it is most of pending, so that the text of a token or
+		// statement left open is not copied again on every read.
+		if done > len(sql)/2 {
+			pending.Reset()
+			pending.WriteString(sql[done:])
+			s.drop(done)
+			base += done
+			done = 0
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// streamStatement formats the statement sql for FormatStream, unless it is larger than the limit
+// of config. It only fails if the formatter is closed.
+func (f *Formatter) streamStatement(sql string, options FormatOptions, config streamConfig) (StatementResult, error) {
+	if config.skips(sql) {
+		return StatementResult{Formatted: sql, Verbatim: true}, nil
+	}
+	formatted, err := f.Format(sql, options)
+	if errors.Is(err, ErrFormatterClosed) {
+		return StatementResult{}, err
+	}
+	return StatementResult{Formatted: formatted, Err: err}, nil
+}
+
+// formatDumpStream is FormatStream for dumps: the input is fed line by line to a dumpSplitter,
+// and each part is reported as soon as it is complete.
+func (f *Formatter) formatDumpStream(r io.Reader, options FormatOptions, config streamConfig, fn func(StatementResult) error) error {
+	inner := options
+	inner.DumpFormat = ""
+	var (
+		base int // offset of the next part in the input
+		err  error
+	)
+	s := &dumpSplitter{options: options, emit: func(part dumpPart) {
+		start := base
+		base += len(part.sql)
+		if err != nil {
+			return
+		}
+		if part.kind == dumpVerbatim {
+			err = fn(StatementResult{Start: start, End: base, Formatted: part.sql, Verbatim: true})
+			return
+		}
+		// Report the statement without the whitespace around it, like splitStatements.
+		text := strings.TrimSpace(part.sql)
+		start += strings.Index(part.sql, text)
+		var result StatementResult
+		if part.kind == dumpInserts && !config.skips(text) {
+			result = StatementResult{Formatted: compactInserts(text, inner)}
+		} else if result, err = f.streamStatement(text, inner, config); err != nil {
+			return
+		}
+		result.Start, result.End = start, start+len(text)
+		if stmts := splitStatements(text, options.Language); len(stmts) > 0 {
+			result.Kind = stmts[0].kind
+		}
+		err = fn(result)
+	}}
+
+	br := bufio.NewReaderSize(r, dumpChunkSize)
+	for err == nil {
+		line, readErr := br.ReadString('\n')
+		if line != "" {
+			s.line(line)
+		}
+		if readErr == io.EOF {
+			s.close()
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return err
+}
+
+// statement is a statement found by splitStatements.
+type statement struct {
+	start, end int
+	kind       string
+	terminated bool // ends with a semicolon
+}
+
+// splitStatements splits sql at the semicolons outside parentheses. Statements without any
+// significant token, such as comments after the last statement, are skipped.
+func splitStatements(sql string, lang LanguageOption) []statement {
+	s := newStatementSplitter(lang)
+	return s.split(sql, true)
+}
+
+// statementSplitter is splitStatements for a script read in pieces, such as by FormatStream: the
+// text is tokenized from where the previous call stopped, so that a long statement is tokenized
+// once rather than once per piece.
+type statementSplitter struct {
+	lang     LanguageOption
+	scanned  int         // offset of the first token not scanned yet
+	closer   string      // text closing the token at scanned, a string or comment left open so far
+	searched int         // offset up to which closer was looked for
+	kind     kindScanner // kind of the current statement
+	start    int         // offset of the first non-space token of the current statement, or -1
+	depth    int         // depth of parentheses
+}
+
+func newStatementSplitter(lang LanguageOption) *statementSplitter {
+	return &statementSplitter{lang: lang, start: -1}
+}
+
+// split returns the statements of text completed since the previous call, text being the text of
+// that call with more appended, less what drop discarded. Unless eof, the text after the last
+// semicolon is held back, and so is the last token, which may go on in the next piece.
+func (s *statementSplitter) split(text string, eof bool) []statement {
+	if s.closer != "" && !eof {
+		from := max(s.searched-len(s.closer)+1, s.scanned)
+		s.searched = len(text)
+		if !strings.Contains(text[from:], s.closer) {
+			return nil // the token left open goes on
+		}
+	}
+	var stmts []statement
+	flush := func(end int, terminated bool) {
+		if !s.kind.empty() {
+			stmts = append(stmts, statement{start: s.start, end: end, kind: s.kind.kind(), terminated: terminated})
+		}
+		s.kind, s.start = kindScanner{}, -1
+	}
+	offset := s.scanned
+	scanTokens(text[offset:], s.lang, func(t token) bool {
+		t.start, t.end = t.start+offset, t.end+offset
+		if !eof && t.end == len(text) && t.kind != tokenSpace && t.text != ";" {
+			s.closer, s.searched = closerOf(t), len(text)
+			return false
+		}
+		s.scanned, s.closer = t.end, ""
+		if t.kind == tokenSpace {
+			return true
+		}
+		if s.start < 0 {
+			s.start = t.start
+		}
+		if t.kind == tokenComment {
+			return true
+		}
+		switch t.text {
+		case "(":
+			s.depth++
+		case ")":
+			s.depth = max(s.depth-1, 0)
+		case ";":
+			if s.depth == 0 {
+				flush(t.end, true)
+				return true
+			}
+		}
+		s.kind.add(t)
+		return true
+	})
+	if eof && s.start >= 0 {
+		end := len(strings.TrimRight(text, " \t\r\n"))
+		flush(end, false)
+	}
+	return stmts
+}
+
+// drop tells s that the first n bytes of the text, which must have been scanned, are discarded.
+func (s *statementSplitter) drop(n int) {
+	s.scanned -= n
+	s.searched = max(s.searched-n, 0)
+	if s.start >= 0 {
+		s.start = max(s.start-n, 0)
+	}
+}
+
+// statementKinds are the keywords that can follow the common table expressions of a WITH query.
+var statementKinds = wordSet(`SELECT INSERT UPDATE DELETE MERGE VALUES TABLE`)
+
+// statementKind returns the upper-cased keyword determining the kind of the statement made of sig.
+func statementKind(sig []token) string {
+	var k kindScanner
+	for _, t := range sig {
+		if !k.add(t) {
+			break
+		}
+	}
+	return k.kind()
+}
+
+// kindScanner determines the kind of a statement from its significant tokens, fed in order, without
+// holding them: the first keyword, or for a WITH query the keyword after its common table
+// expressions, or for a parenthesized query such as (SELECT ...) UNION (SELECT ...) its first word.
+type kindScanner struct {
+	first string // upper-cased first token
+	found string // kind, once known
+	depth int    // parentheses open after WITH
+}
+
+// add feeds the next significant token, and reports whether the kind is still unknown.
+func (k *kindScanner) add(t token) bool {
+	switch {
+	case k.found != "":
+	case k.first == "":
+		k.first = strings.ToUpper(t.text)
+		if k.first != "WITH" && k.first != "(" {
+			k.found = k.first
+		}
+	case k.first == "(":
+		if t.kind == tokenWord {
+			k.found = strings.ToUpper(t.text)
+		}
+	case t.text == "(":
+		k.depth++
+	case t.text == ")":
+		k.depth--
+	case k.depth == 0 && t.kind == tokenWord && statementKinds[strings.ToUpper(t.text)]:
+		k.found = strings.ToUpper(t.text)
+	}
+	return k.found == ""
+}
+
+// empty reports whether no token was fed.
+func (k *kindScanner) empty() bool {
+	return k.first == ""
+}
+
+// kind returns the kind of the statement, which is its first token if nothing more specific was
+// found.
+func (k *kindScanner) kind() string {
+	if k.found != "" {
+		return k.found
+	}
+	return k.first
+}
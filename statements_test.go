@@ -0,0 +1,186 @@
+package sqlfmt
+
+import (
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const streamScript = `-- leading comment
+select 'a;b', "c;d" from t;
+/* block; comment */ insert into t values (1, ';');
+create function f() returns int as $body$ begin return 1; end; $body$ language plpgsql;
+with x as (select 1) select * from x
+;select 2 -- no semicolon`
+
+func TestStatementSplitterPieces(t *testing.T) {
+	want := splitStatements(streamScript, LanguagePostgreSQL)
+	for _, size := range []int{1, 2, 3, 7, 64} {
+		s := newStatementSplitter(LanguagePostgreSQL)
+		var (
+			got  []statement
+			text string
+			base int
+		)
+		for i := 0; i < len(streamScript); i += size {
+			text += streamScript[i:min(i+size, len(streamScript))]
+			eof := i+size >= len(streamScript)
+			for _, stmt := range s.split(text, eof) {
+				stmt.start, stmt.end = base+stmt.start, base+stmt.end
+				got = append(got, stmt)
+			}
+			if n := len(got); n > 0 && got[n-1].end > base {
+				done := got[n-1].end - base
+				text, base = text[done:], base+done
+				s.drop(done)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("pieces of %d bytes: got %+v, want %+v", size, got, want)
+		}
+	}
+}
+
+func TestFormatStreamPieces(t *testing.T) {
+	options := DefaultFormatOptions
+	options.Language = LanguagePostgreSQL
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := f.FormatStatements(streamScript, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []StatementResult
+	err = f.FormatStream(iotest.OneByteReader(strings.NewReader(streamScript)), options, func(result StatementResult) error {
+		got = append(got, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Start != w.Start || g.End != w.End || g.Kind != w.Kind || g.Formatted != w.Formatted {
+			t.Errorf("result %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestFormatStreamSkipLargerThan(t *testing.T) {
+	large := "insert into t values " + strings.Repeat("(1, 'a;b'), ", 100_000) + "(2, 'c');"
+	script := "select 1;\n" + large + "\nselect 2;"
+	r := &countingReader{r: strings.NewReader(script)}
+
+	var (
+		results  []StatementResult
+		firstAt  = -1 // bytes read when the first chunk of the large statement was reported
+		verbatim strings.Builder
+	)
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = f.FormatStream(r, DefaultFormatOptions, func(result StatementResult) error {
+		results = append(results, result)
+		if result.Verbatim {
+			if firstAt < 0 {
+				firstAt = r.n
+			}
+			verbatim.WriteString(result.Formatted)
+		}
+		return nil
+	}, SkipLargerThan(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if firstAt < 0 || firstAt >= len(large)/2 {
+		t.Errorf("first chunk reported after reading %d of %d bytes, want it reported early", firstAt, len(script))
+	}
+	if verbatim.String() != large {
+		t.Errorf("the Verbatim results do not concatenate to the large statement")
+	}
+	first, last := results[0], results[len(results)-1]
+	if first.Kind != "SELECT" || first.Verbatim || last.Kind != "SELECT" || last.Verbatim || last.Start != strings.LastIndex(script, "select") {
+		t.Errorf("got results %+v ... %+v around the large statement", first, last)
+	}
+	for _, result := range results[1 : len(results)-1] {
+		if !result.Verbatim || result.Kind != "" || script[result.Start:result.End] != result.Formatted {
+			t.Fatalf("got chunk %+v, want a Verbatim result without Kind holding the input", result)
+		}
+	}
+}
+
+// longToken is a script made of a string literal of n bytes, which FormatStream holds whole.
+func longToken(n int) string {
+	return "select '" + strings.Repeat("a", n) + "';"
+}
+
+func TestFormatStreamLongToken(t *testing.T) {
+	script := longToken(8 << 20)
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var got strings.Builder
+	err = f.FormatStream(strings.NewReader(script), DefaultFormatOptions, func(result StatementResult) error {
+		got.WriteString(result.Formatted)
+		return nil
+	}, SkipLargerThan(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if got.String() != script {
+		t.Errorf("the results do not concatenate to the input")
+	}
+	// Copying the open literal on every read of 32 KiB allocates gigabytes.
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8*uint64(len(script)) {
+		t.Errorf("streaming %d bytes allocated %d bytes", len(script), allocated)
+	}
+}
+
+func BenchmarkFormatStreamLongToken(b *testing.B) {
+	script := longToken(32 << 20)
+	f, err := NewFormatter()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	b.SetBytes(int64(len(script)))
+	for b.Loop() {
+		err := f.FormatStream(strings.NewReader(script), DefaultFormatOptions, func(StatementResult) error {
+			return nil
+		}, SkipLargerThan(1<<20))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
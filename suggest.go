@@ -0,0 +1,163 @@
+package sqlfmt
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of keywords suggested for a single word.
+const maxSuggestions = 3
+
+// dialectKeywords returns the set of upper-cased keywords sql-formatter knows for lang.
+// Keyword lists missing from the cache are retrieved through e.
+func (f *Formatter) dialectKeywords(e *engine, lang LanguageOption) map[string]bool {
+	if lang == "" {
+		lang = LanguageSQL
+	}
+	p := f.pool
+	p.keywordsMu.Lock()
+	defer p.keywordsMu.Unlock()
+	if kw, ok := p.keywords[lang]; ok {
+		return kw
+	}
+
+	name := lang
+	if name == LanguageTSQL {
+		name = LanguageTransactSQL
+	}
+	words, err := e.keywordList(name)
+	if err != nil {
+		// Fall back to the generic keyword list; suggestions are best-effort.
+		return keywords
+	}
+
+	kw := wordSet(strings.ToUpper(words))
+	if p.keywords == nil {
+		p.keywords = make(map[LanguageOption]map[string]bool)
+	}
+	p.keywords[lang] = kw
+	return kw
+}
+
+// suggestKeywords returns did-you-mean suggestions for unknown words in keyword position within
+// the statement of sql that contains offset. A word is in keyword position when it starts the
+// statement, is at offset, or directly follows a complete operand (where an operator or clause
+// keyword is expected).
+func suggestKeywords(sql string, lang LanguageOption, kw map[string]bool, offset int) []Suggestion {
+	sig := significant(tokenize(sql, lang))
+
+	first, last := 0, len(sig)
+	for i, t := range sig {
+		if t.text != ";" {
+			continue
+		}
+		if t.start < offset {
+			first = i + 1
+		} else {
+			last = i
+			break
+		}
+	}
+
+	var suggestions []Suggestion
+	for i := first; i < last; i++ {
+		t := sig[i]
+		upper := strings.ToUpper(t.text)
+		if t.kind != tokenWord || len(t.text) < 2 || kw[upper] || keywords[upper] || tokenAt(sig, i+1).text == "(" {
+			continue
+		}
+		if i != first && !(t.start <= offset && offset < t.end) && !endsOperand(tokenAt(sig, i-1)) {
+			continue
+		}
+		if candidates := closestKeywords(upper, kw); len(candidates) > 0 {
+			line, column := position(sql, t.start)
+			suggestions = append(suggestions, Suggestion{Word: t.text, Line: line, Column: column, Keywords: candidates})
+		}
+	}
+	return suggestions
+}
+
+// endsOperand reports whether t can be the last token of an operand.
+func endsOperand(t token) bool {
+	switch t.kind {
+	case tokenIdent, tokenString, tokenNumber, tokenParam:
+		return true
+	case tokenWord:
+		return !isKeyword(t.text)
+	case tokenPunct:
+		return t.text == ")"
+	}
+	return false
+}
+
+// closestKeywords returns the keywords in kw closest to word, if any is close enough to be a likely typo.
+func closestKeywords(word string, kw map[string]bool) []string {
+	limit := 1
+	if len(word) > 4 {
+		limit = 2
+	}
+
+	type candidate struct {
+		keyword  string
+		distance int
+	}
+	var candidates []candidate
+	for k := range kw {
+		if d := editDistance(word, k); d <= limit {
+			candidates = append(candidates, candidate{k, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		// Prefer keywords of the same length, which explains typos as swapped or mistyped letters.
+		if li, lj := abs(len(candidates[i].keyword)-len(word)), abs(len(candidates[j].keyword)-len(word)); li != lj {
+			return li < lj
+		}
+		return candidates[i].keyword < candidates[j].keyword
+	})
+
+	var out []string
+	for _, c := range candidates {
+		if len(out) == maxSuggestions {
+			break
+		}
+		out = append(out, c.keyword)
+	}
+	return out
+}
+
+// editDistance returns the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions and adjacent transpositions needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,302 @@
+package sqlfmt
+
+import "strings"
+
+// applyTransforms rewrites sql according to the options implemented by this package
+// before it is handed to sql-formatter for layout.
+func applyTransforms(sql string, options FormatOptions) (string, error) {
+	if options.OrderByDependencies || options.SortSchema {
+		sql = orderByDependencies(sql, options.Language, options.SortSchema)
+	}
+	if options.QualifyTables {
+		if options.Catalog == nil {
+			return "", ErrNoCatalog
+		}
+		sql = qualifyTables(sql, options.Language, options.Catalog)
+	}
+	if options.ExpandStar {
+		if options.ColumnCatalog == nil {
+			return "", ErrNoCatalog
+		}
+		sql = expandStars(sql, options.Language, options.ColumnCatalog)
+	}
+	return sql, nil
+}
+
+// qualifyTables prefixes unqualified table references in sql with the schema reported by catalog.
+// References to common table expressions and tables unknown to catalog are left alone.
+func qualifyTables(sql string, lang LanguageOption, catalog Catalog) string {
+	tokens := tokenize(sql, lang)
+	sig := significant(tokens)
+	refs := scanNames(sig)
+
+	ctes := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.role == roleCTE {
+			ctes[strings.ToLower(ref.name)] = true
+		}
+	}
+
+	prefixes := make(map[int]string) // token start offset -> schema prefix
+	for _, ref := range refs {
+		if ref.role != roleTable || ref.qualified || ctes[strings.ToLower(ref.name)] {
+			continue
+		}
+		if !isTablePosition(sig, ref.index) {
+			// A column qualifier, which refers to the table by its unqualified name.
+			continue
+		}
+		schema, ok := catalog.SchemaOf(ref.name)
+		if !ok || schema == "" {
+			continue
+		}
+		t := sig[ref.index]
+		if t.kind == tokenIdent {
+			prefixes[t.start] = quoteIdent(schema, lang) + "."
+		} else {
+			prefixes[t.start] = quoteName(schema, lang) + "."
+		}
+	}
+
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(prefixes[t.start])
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// span is a byte range of the input to be replaced by text.
+type span struct {
+	start, end int
+	text       string
+}
+
+// replaceSpans returns sql with each span replaced. Spans must be sorted and must not overlap.
+func replaceSpans(sql string, spans []span) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(sql[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// fromItem is a table reference in a FROM clause.
+type fromItem struct {
+	name  string // the table name, schema-qualified if the query qualifies it
+	alias string // the alias, or the unqualified table name if there is none
+	known bool   // whether the item is a plain table reference (not a subquery or function)
+}
+
+// expandStars replaces * and t.* in select lists with the columns reported by catalog.
+// A bare * is only expanded if every item of the FROM clause is a table known to catalog;
+// with more than one item the columns are qualified with the item's alias.
+func expandStars(sql string, lang LanguageOption, catalog ColumnCatalog) string {
+	sig := significant(tokenize(sql, lang))
+	depths := nestingDepths(sig)
+	refs := scanNames(sig)
+
+	var spans []span
+	for i, t := range sig {
+		if t.kind != tokenWord || !strings.EqualFold(t.text, "SELECT") {
+			continue
+		}
+		from, end := selectBounds(sig, depths, i)
+		if from < 0 {
+			continue
+		}
+		items := fromItems(sig, depths, refs, from, end)
+
+		for j := i + 1; j < from; j++ {
+			if sig[j].text != "*" || depths[j] != depths[i] {
+				continue
+			}
+			prev := tokenAt(sig, j-1)
+			if prev.text == "." {
+				// t.*
+				q := j - 2
+				if q < i || !isName(sig[q]) {
+					continue
+				}
+				qualifier := unquoteName(sig[q])
+				for _, item := range items {
+					if !item.known || !strings.EqualFold(item.alias, qualifier) {
+						continue
+					}
+					if columns, ok := catalog.ColumnsOf(item.name); ok && len(columns) > 0 {
+						spans = append(spans, span{sig[q].start, sig[j].end, columnList(columns, sig[q].text, lang)})
+					}
+					break
+				}
+				continue
+			}
+			if j != i+1 && prev.text != "," && !isKeyword(prev.text) {
+				continue
+			}
+			if list, ok := expandBareStar(items, catalog, lang); ok {
+				spans = append(spans, span{sig[j].start, sig[j].end, list})
+			}
+		}
+	}
+	return replaceSpans(sql, spans)
+}
+
+// nestingDepths returns the parenthesis nesting depth of each token in sig.
+// Parentheses themselves have the depth of the tokens around them.
+func nestingDepths(sig []token) []int {
+	depths := make([]int, len(sig))
+	depth := 0
+	for i, t := range sig {
+		if t.text == ")" {
+			depth--
+		}
+		depths[i] = depth
+		if t.text == "(" {
+			depth++
+		}
+	}
+	return depths
+}
+
+// selectItems splits the select list of the SELECT at sig[i], which ends before sig[end],
+// into items given as [start, end) index ranges.
+func selectItems(sig []token, depths []int, i, end int) [][2]int {
+	var items [][2]int
+	start := i + 1
+	for start < end && sig[start].kind == tokenWord && (strings.EqualFold(sig[start].text, "DISTINCT") || strings.EqualFold(sig[start].text, "ALL")) {
+		start++
+	}
+	for j := start; j <= end; j++ {
+		if j == end || (sig[j].text == "," && depths[j] == depths[i]) {
+			if j > start {
+				items = append(items, [2]int{start, j})
+			}
+			start = j + 1
+		}
+	}
+	return items
+}
+
+// selectBounds returns the index of the FROM keyword belonging to the SELECT at sig[i] and the index
+// just past its FROM clause, or -1 if the SELECT has no FROM clause.
+func selectBounds(sig []token, depths []int, i int) (from, end int) {
+	from = -1
+	for j := i + 1; j < len(sig); j++ {
+		if depths[j] < depths[i] || sig[j].text == ";" {
+			return -1, j
+		}
+		if depths[j] != depths[i] || sig[j].kind != tokenWord {
+			continue
+		}
+		switch strings.ToUpper(sig[j].text) {
+		case "FROM":
+			from = j
+		case "SELECT", "UNION", "INTERSECT", "EXCEPT", "MINUS", "WHERE", "GROUP", "ORDER", "HAVING", "LIMIT":
+			return -1, j
+		}
+		if from >= 0 {
+			break
+		}
+	}
+	if from < 0 {
+		return -1, len(sig)
+	}
+	for end = from + 1; end < len(sig); end++ {
+		if depths[end] < depths[i] || sig[end].text == ";" {
+			break
+		}
+		if depths[end] == depths[i] && sig[end].kind == tokenWord && clauseKeywords[strings.ToUpper(sig[end].text)] &&
+			!strings.EqualFold(sig[end].text, "JOIN") && !strings.EqualFold(sig[end].text, "ON") &&
+			!strings.EqualFold(sig[end].text, "USING") {
+			break
+		}
+	}
+	return from, end
+}
+
+// fromItems lists the items of the FROM clause spanning sig[from+1:end].
+func fromItems(sig []token, depths []int, refs []nameRef, from, end int) []fromItem {
+	level := depths[from]
+	var items []fromItem
+	for j := from + 1; j < end; j++ {
+		if depths[j] != level {
+			continue
+		}
+		if sig[j].text == "(" && isTablePosition(sig, j) {
+			// A derived table; its columns are unknown.
+			items = append(items, fromItem{})
+		}
+	}
+	for k, ref := range refs {
+		if ref.index <= from || ref.index >= end || depths[ref.index] != level {
+			continue
+		}
+		switch ref.role {
+		case roleFunction:
+			if isTablePosition(sig, ref.index) {
+				items = append(items, fromItem{})
+			}
+		case roleTable:
+			if !isTablePosition(sig, ref.index) && !(ref.qualified && isTablePosition(sig, qualifierStart(sig, ref.index))) {
+				continue
+			}
+			name := ref.name
+			for b := k - 1; b >= 0 && refs[b].role == roleSchema && refs[b].index == refs[b+1].index-2; b-- {
+				name = refs[b].name + "." + name
+			}
+			item := fromItem{name: name, alias: ref.name, known: true}
+			if k+1 < len(refs) && refs[k+1].role == roleTableAlias {
+				item.alias = refs[k+1].name
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// qualifierStart returns the index of the first token of the dotted name ending at sig[i].
+func qualifierStart(sig []token, i int) int {
+	for i >= 2 && sig[i-1].text == "." {
+		i -= 2
+	}
+	return i
+}
+
+// expandBareStar returns the column list replacing a bare * over items.
+func expandBareStar(items []fromItem, catalog ColumnCatalog, lang LanguageOption) (string, bool) {
+	if len(items) == 0 {
+		return "", false
+	}
+	var lists []string
+	for _, item := range items {
+		if !item.known {
+			return "", false
+		}
+		columns, ok := catalog.ColumnsOf(item.name)
+		if !ok || len(columns) == 0 {
+			return "", false
+		}
+		qualifier := ""
+		if len(items) > 1 {
+			qualifier = quoteName(item.alias, lang)
+		}
+		lists = append(lists, columnList(columns, qualifier, lang))
+	}
+	return strings.Join(lists, ", "), true
+}
+
+// columnList renders columns as a comma-separated list, each prefixed with qualifier if it is not empty.
+func columnList(columns []string, qualifier string, lang LanguageOption) string {
+	parts := make([]string, len(columns))
+	for i, c := range columns {
+		parts[i] = quoteName(c, lang)
+		if qualifier != "" {
+			parts[i] = qualifier + "." + parts[i]
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,99 @@
+package sqlfmt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsafeFormat is matched by *UnsafeFormatError.
+var ErrUnsafeFormat = errors.New("formatting would change the meaning of the query")
+
+// UnsafeFormatError is returned when FormatOptions.VerifyTokens is set and the formatted output
+// does not consist of the same tokens as the input.
+type UnsafeFormatError struct {
+	// Input and Output are the first differing tokens; an empty string stands for the end of the text.
+	Input, Output string
+	// InputLine and InputColumn locate the differing token in the input (1-based).
+	InputLine, InputColumn int
+	// OutputLine and OutputColumn locate the differing token in the output (1-based).
+	OutputLine, OutputColumn int
+}
+
+// Error implements the error interface.
+func (e *UnsafeFormatError) Error() string {
+	return fmt.Sprintf("%v: %s at line %d column %d became %s at line %d column %d",
+		ErrUnsafeFormat, describeToken(e.Input), e.InputLine, e.InputColumn,
+		describeToken(e.Output), e.OutputLine, e.OutputColumn)
+}
+
+// Is reports whether target is ErrUnsafeFormat.
+func (e *UnsafeFormatError) Is(target error) bool {
+	return target == ErrUnsafeFormat
+}
+
+func describeToken(text string) string {
+	if text == "" {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", text)
+}
+
+// verifyTokens checks that input and output consist of the same significant tokens. Whitespace and
+// comments are ignored, except for MySQL executable comments, whose SQL is compared like the rest;
+// so is the case of bare words when options change the case of keywords, functions, data types or
+// identifiers.
+func verifyTokens(input, output string, options FormatOptions) error {
+	in := verifiable(input, options.Language)
+	out := verifiable(output, options.Language)
+	ignoreCase := changesCase(options)
+
+	for i := 0; i < max(len(in), len(out)); i++ {
+		a, b := tokenAt(in, i), tokenAt(out, i)
+		if a.text == b.text || (ignoreCase && a.kind == tokenWord && b.kind == tokenWord && strings.EqualFold(a.text, b.text)) {
+			continue
+		}
+		if a.kind == tokenNumber && b.kind == tokenNumber && formatNumber(a.text, options) == b.text {
+			continue
+		}
+		e := &UnsafeFormatError{Input: a.text, Output: b.text}
+		e.InputLine, e.InputColumn = position(input, startOr(a, len(input)))
+		e.OutputLine, e.OutputColumn = position(output, startOr(b, len(output)))
+		return e
+	}
+	return nil
+}
+
+// verifiable returns the tokens of sql compared by verifyTokens: its significant tokens, with the
+// executable comments of dialects that run them split by executableTokens.
+func verifiable(sql string, lang LanguageOption) []token {
+	executable := lexiconFor(lang).executableComments
+	var tokens []token
+	for _, t := range tokenize(sql, lang) {
+		switch {
+		case t.kind == tokenComment && executable && isExecutableComment(t.text):
+			tokens = append(tokens, executableTokens(t, lang)...)
+		case t.kind != tokenSpace && t.kind != tokenComment:
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// startOr returns the start of t, or fallback for the zero token.
+func startOr(t token, fallback int) int {
+	if t.text == "" {
+		return fallback
+	}
+	return t.start
+}
+
+// changesCase reports whether options may change the case of bare words.
+func changesCase(options FormatOptions) bool {
+	for _, c := range []CaseOption{options.KeywordCase, options.FunctionCase, options.DataTypeCase, options.IdentifierCase} {
+		if c != "" && c != CaseOptionPreserve {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package sqlfmt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyTokens(t *testing.T) {
+	upper := DefaultFormatOptions.WithLanguage(LanguageMySQL)
+	upper.KeywordCase = CaseOptionUpper
+	mysql := upper
+	mysql.KeywordCase, mysql.FunctionCase, mysql.DataTypeCase, mysql.IdentifierCase = CaseOptionPreserve, CaseOptionPreserve, CaseOptionPreserve, CaseOptionPreserve
+	tests := []struct {
+		name            string
+		input, output   string
+		options         FormatOptions
+		token, became   string // the first differing tokens, if any
+		line, column    int    // of token in input
+		outLine, outCol int    // of became in output
+	}{
+		{name: "layout", input: "select a,b from t -- x", output: "SELECT\n  a,\n  b\nFROM\n  t", options: upper},
+		{name: "case kept", input: "select a from t", output: "SELECT a FROM t", options: mysql, token: "select", became: "SELECT", line: 1, column: 1, outLine: 1, outCol: 1},
+		{name: "token lost", input: "select a, b from t", output: "select a from t", options: mysql, token: ",", became: "from", line: 1, column: 9, outLine: 1, outCol: 10},
+		{name: "end", input: "select a from t;", output: "select a from t", options: mysql, token: ";", became: "", line: 1, column: 16, outLine: 1, outCol: 16},
+		{name: "executable comment", input: "create table t (a int) /*!50100 engine=innodb */", output: "CREATE TABLE t (a int) /*!50100 ENGINE = innodb */", options: upper},
+		{name: "executable comment changed", input: "select 1 /*!50100 , 2 */", output: "select 1 /*!50100 , 3 */", options: mysql, token: "2", became: "3", line: 1, column: 21, outLine: 1, outCol: 21},
+		{name: "executable comment version", input: "select 1 /*!50100 , 2 */", output: "select 1 /*!50101 , 2 */", options: mysql, token: "/*!50100", became: "/*!50101", line: 1, column: 10, outLine: 1, outCol: 10},
+		{name: "comment in other dialects", input: "select 1 /*!50100 , 2 */", output: "select 1 /*!50100 , 3 */", options: DefaultFormatOptions},
+	}
+	for _, tt := range tests {
+		err := verifyTokens(tt.input, tt.output, tt.options)
+		if tt.token == "" && tt.became == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var ue *UnsafeFormatError
+		if !errors.As(err, &ue) || !errors.Is(err, ErrUnsafeFormat) {
+			t.Errorf("%s: got error %v, want an *UnsafeFormatError", tt.name, err)
+			continue
+		}
+		want := UnsafeFormatError{tt.token, tt.became, tt.line, tt.column, tt.outLine, tt.outCol}
+		if *ue != want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *ue, want)
+		}
+	}
+}
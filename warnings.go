@@ -0,0 +1,195 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WarningCode identifies the kind of a Warning.
+type WarningCode string
+
+const (
+	// WarningCommentDropped reports a comment of the input that is missing from the output.
+	WarningCommentDropped WarningCode = "comment-dropped"
+	// WarningCommentMoved reports a comment that no longer follows the same token as in the input.
+	WarningCommentMoved WarningCode = "comment-moved"
+	// WarningUnknownOption reports an option value sql-formatter does not know, which was replaced
+	// by the value from DefaultFormatOptions.
+	WarningUnknownOption WarningCode = "unknown-option"
+	// WarningWorkaround reports that the output was corrected to work around a sql-formatter bug.
+	WarningWorkaround WarningCode = "workaround"
+)
+
+// Warning is a non-fatal problem noticed while formatting. The output is still returned.
+type Warning struct {
+	// Code identifies the kind of problem.
+	Code WarningCode
+	// Message describes the problem.
+	Message string
+	// Text is the input text concerned, if any.
+	Text string
+	// Line and Column locate Text in the input (1-based), or are zero if the warning has no location.
+	Line, Column int
+}
+
+// String formats the warning as line:column: code: message.
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return fmt.Sprintf("%s: %s", w.Code, w.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", w.Line, w.Column, w.Code, w.Message)
+}
+
+// FormatWithWarnings formats sql like Format and additionally reports problems that do not prevent
+// formatting: option values that were ignored, corrections applied to the output of sql-formatter,
+// and comments of the input that were dropped or moved.
+func (f *Formatter) FormatWithWarnings(sql string, options FormatOptions) (string, []Warning, error) {
+	formatted, warnings, _, err := f.formatWithWarnings(sql, options)
+	return formatted, warnings, err
+}
+
+// formatWithWarnings implements FormatWithWarnings, also reporting whether the output came from
+// the result cache.
+func (f *Formatter) formatWithWarnings(sql string, options FormatOptions) (string, []Warning, bool, error) {
+	formatted, warnings, hit, err := f.formatCached(sql, options)
+	if err != nil {
+		return "", nil, false, err
+	}
+	return formatted, append(warnings, checkComments(sql, formatted, options.Language)...), hit, nil
+}
+
+// optionValues lists the values accepted for each enumerated option, by JSON name.
+var optionValues = map[string][]string{
+	"dataTypeCase":   caseValues,
+	"functionCase":   caseValues,
+	"identifierCase": caseValues,
+	"keywordCase":    caseValues,
+	"indentStyle": {
+		string(IndentStyleStandard), string(IndentStyleTabularLeft), string(IndentStyleTabularRight),
+	},
+	"language": {
+		string(LanguageSQL), string(LanguageBigQuery), string(LanguageDB2), string(LanguageDB2i),
+		string(LanguageDuckDB), string(LanguageHive), string(LanguageMariaDB), string(LanguageMySQL),
+		string(LanguageTiDB), string(LanguageN1QL), string(LanguagePLSQL), string(LanguagePostgreSQL),
+		string(LanguageRedshift), string(LanguageSingleStoreDB), string(LanguageSnowflake),
+		string(LanguageSpark), string(LanguageSQLite), string(LanguageTransactSQL), string(LanguageTSQL),
+		string(LanguageTrino),
+	},
+	"logicalOperatorNewline": {string(LogicalOperatorNewlineBefore), string(LogicalOperatorNewlineAfter)},
+	"dumpFormat":             {string(DumpFormatPgDump), string(DumpFormatMySQLDump)},
+	"dumpInserts":            {string(DumpInsertsFormat), string(DumpInsertsVerbatim), string(DumpInsertsCompact)},
+	"numberCase":             caseValues,
+	"leadingZero":            {string(LeadingZeroPreserve), string(LeadingZeroAlways), string(LeadingZeroNever)},
+	"executableComments":     {string(ExecutableCommentsFormat), string(ExecutableCommentsVerbatim)},
+}
+
+var caseValues = []string{string(CaseOptionPreserve), string(CaseOptionUpper), string(CaseOptionLower)}
+
+// checkOptions returns options with the enumerated values sql-formatter does not know replaced by
+// their defaults, since sql-formatter would otherwise produce garbage (an unknown keywordCase drops
+// the keywords), and a warning for each of them.
+func checkOptions(options FormatOptions) (FormatOptions, []Warning) {
+	var warnings []Warning
+	check := func(name string, value *string, def string) {
+		if *value == "" || slices.Contains(optionValues[name], *value) {
+			return
+		}
+		warnings = append(warnings, Warning{
+			Code:    WarningUnknownOption,
+			Message: fmt.Sprintf("unknown %s %q, using %q", name, *value, def),
+		})
+		*value = def
+	}
+	d := DefaultFormatOptions
+	check("dataTypeCase", (*string)(&options.DataTypeCase), string(d.DataTypeCase))
+	check("functionCase", (*string)(&options.FunctionCase), string(d.FunctionCase))
+	check("identifierCase", (*string)(&options.IdentifierCase), string(d.IdentifierCase))
+	check("keywordCase", (*string)(&options.KeywordCase), string(d.KeywordCase))
+	check("indentStyle", (*string)(&options.IndentStyle), string(d.IndentStyle))
+	check("logicalOperatorNewline", (*string)(&options.LogicalOperatorNewline), string(d.LogicalOperatorNewline))
+	check("dumpFormat", (*string)(&options.DumpFormat), "")
+	check("dumpInserts", (*string)(&options.DumpInserts), string(DumpInsertsFormat))
+	check("numberCase", (*string)(&options.NumberCase), string(CaseOptionPreserve))
+	check("leadingZero", (*string)(&options.LeadingZero), string(LeadingZeroPreserve))
+	check("executableComments", (*string)(&options.ExecutableComments), string(ExecutableCommentsFormat))
+	if _, unknown := operatorExceptions(options.DenseOperatorExceptions); len(unknown) > 0 {
+		warnings = append(warnings, Warning{
+			Code:    WarningUnknownOption,
+			Message: fmt.Sprintf("unknown denseOperatorExceptions %q, which are neither operators nor groups of operators", strings.Join(unknown, ",")),
+		})
+	}
+	return options, warnings
+}
+
+// checkComments returns a warning for every comment of input that does not appear in output, or
+// that follows a different token there. Comments are matched in order, ignoring differences in
+// whitespace, since sql-formatter re-indents multi-line comments.
+func checkComments(input, output string, lang LanguageOption) []Warning {
+	type comment struct {
+		text  string // whitespace-normalized
+		after string // upper-cased preceding significant token
+	}
+	comments := func(sql string) ([]comment, []token) {
+		var list []comment
+		var found []token
+		prev := ""
+		for _, t := range tokenize(sql, lang) {
+			switch t.kind {
+			case tokenSpace:
+			case tokenComment:
+				text := normalizeSpace(t.text)
+				if isExecutableComment(text) {
+					text = strings.ToUpper(text) // see formatExecutableComment
+				}
+				list = append(list, comment{text, prev})
+				found = append(found, t)
+			default:
+				prev = strings.ToUpper(t.text)
+			}
+		}
+		return list, found
+	}
+	in, tokens := comments(input)
+	out, _ := comments(output)
+
+	var warnings []Warning
+	next := 0
+	for i, c := range in {
+		t := tokens[i]
+		line, column := position(input, t.start)
+		match := -1
+		for j := next; j < len(out); j++ {
+			if out[j].text == c.text {
+				match = j
+				break
+			}
+		}
+		switch {
+		case match < 0:
+			warnings = append(warnings, Warning{
+				Code:    WarningCommentDropped,
+				Message: fmt.Sprintf("comment dropped by the formatter: %s", t.text),
+				Text:    t.text,
+				Line:    line,
+				Column:  column,
+			})
+			continue
+		case out[match].after != c.after:
+			warnings = append(warnings, Warning{
+				Code:    WarningCommentMoved,
+				Message: fmt.Sprintf("comment moved by the formatter: %s", t.text),
+				Text:    t.text,
+				Line:    line,
+				Column:  column,
+			})
+		}
+		next = match + 1
+	}
+	return warnings
+}
+
+// normalizeSpace collapses runs of whitespace in s into single spaces and trims the ends.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
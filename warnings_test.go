@@ -0,0 +1,62 @@
+package sqlfmt
+
+import "testing"
+
+func TestWorkaroundWarning(t *testing.T) {
+	if Backend == "native" {
+		t.Skip("the native backend does not run sql-formatter")
+	}
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"select count(*) from t", false},
+		{"select a from t where b in (1, 2) and exists(select 1)", false},
+		{"insert into t (a) values (1)", false},
+		{"select my_func(1) from t", true},
+		{"select x.y(2), count(*) from t", true},
+	}
+	f, err := NewFormatter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, tt := range tests {
+		res, err := f.FormatWithResult(tt.sql, DefaultFormatOptions)
+		if err != nil {
+			t.Errorf("%q: %v", tt.sql, err)
+			continue
+		}
+		got := false
+		for _, w := range res.Warnings {
+			got = got || w.Code == WarningWorkaround
+		}
+		if got != tt.want {
+			t.Errorf("%q: got workaround warning %v, want %v (warnings %v)", tt.sql, got, tt.want, res.Warnings)
+		}
+	}
+}
+
+func TestJoinCalls(t *testing.T) {
+	if Backend == "native" {
+		t.Skip("the native backend does not run sql-formatter")
+	}
+	tests := []struct{ sql, want string }{
+		{"select 'a (b)', my_func (1) from t", "SELECT\n    'a (b)',\n    my_func(1)\nFROM\n    t"},
+		{"select a -- see f (x)\nfrom t", "SELECT\n    a -- see f (x)\nFROM\n    t"},
+		{"select a from t where b in (1, 2)", "SELECT\n    a\nFROM\n    t\nWHERE\n    b IN (1, 2)"},
+		{"create table t (a varchar (10))", "CREATE TABLE t(a VARCHAR(10))"},
+	}
+	options := DefaultFormatOptions
+	options.VerifyTokens = true
+	for _, tt := range tests {
+		got, err := Format(tt.sql, options)
+		if err != nil {
+			t.Errorf("%q: %v", tt.sql, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.sql, got, tt.want)
+		}
+	}
+}